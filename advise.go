@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// A TextureFormat quantizes each channel to Bits, zero bits dropping the channel.
+type TextureFormat struct {
+	Name          string
+	BytesPerPixel int
+	Bits          [4]int
+}
+
+// Candidate formats, cheapest first.
+var textureFormats = []TextureFormat{
+	{Name: "RGB565", BytesPerPixel: 2, Bits: [4]int{5, 6, 5, 0}},
+	{Name: "RGBA5551", BytesPerPixel: 2, Bits: [4]int{5, 5, 5, 1}},
+	{Name: "RGBA4444", BytesPerPixel: 2, Bits: [4]int{4, 4, 4, 4}},
+	{Name: "RGB888", BytesPerPixel: 3, Bits: [4]int{8, 8, 8, 0}},
+	{Name: "RGBA8888", BytesPerPixel: 4, Bits: [4]int{8, 8, 8, 8}},
+}
+
+// Lossless formats report this PSNR, since JSON cannot hold infinity.
+const losslessPSNR = 100
+
+type SheetAdvice struct {
+	Image        string             `json:"image"`
+	Frames       int                `json:"frames"`
+	Pixels       int                `json:"pixels"`
+	Alpha        string             `json:"alpha"`
+	UniqueColors int                `json:"uniqueColors"`
+	Palette      bool               `json:"palette"`
+	Gradient     float64            `json:"gradient"`
+	PSNR         map[string]float64 `json:"psnr"`
+	Recommended  string             `json:"recommended"`
+	Reason       string             `json:"reason"`
+}
+
+func quantizeChannel(v uint8, bits int) uint8 {
+	if bits >= 8 {
+		return v
+	}
+	levels := float64(int(1)<<bits - 1)
+	return uint8(math.Round(math.Round(float64(v)*levels/255) * 255 / levels))
+}
+
+type sheetStats struct {
+	pixels   int
+	alphas   map[uint8]bool
+	colors   ColorHistogram
+	smooth   int
+	pairs    int
+	sqErrors map[string]float64
+}
+
+func (stats *sheetStats) add(img image.Image, region image.Rectangle) {
+	region = region.Intersect(img.Bounds())
+
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		for x := region.Min.X; x < region.Max.X; x++ {
+			px := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			stats.pixels++
+			stats.alphas[px.A] = true
+			if px.A == 0 {
+				stats.colors[color.NRGBA{}]++
+			} else {
+				stats.colors[px]++
+			}
+
+			channels := [4]uint8{px.R, px.G, px.B, px.A}
+			for _, format := range textureFormats {
+				for c, bits := range format.Bits {
+					if bits == 0 {
+						continue
+					}
+					// Color under a transparent pixel never shows, so only alpha counts there.
+					if px.A == 0 && c < 3 {
+						continue
+					}
+					diff := float64(channels[c]) - float64(quantizeChannel(channels[c], bits))
+					stats.sqErrors[format.Name] += diff * diff
+				}
+			}
+
+			if x+1 < region.Max.X {
+				stats.pairs++
+				next := color.NRGBAModel.Convert(img.At(x+1, y)).(color.NRGBA)
+				delta := max(absDiff(px.R, next.R), absDiff(px.G, next.G), absDiff(px.B, next.B))
+				if delta > 0 && delta <= 8 {
+					stats.smooth++
+				}
+			}
+		}
+	}
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func (stats *sheetStats) alphaKind() string {
+	switch {
+	case len(stats.alphas) == 1 && stats.alphas[255]:
+		return "none"
+	case len(stats.alphas) <= 2 && !hasPartialAlpha(stats.alphas):
+		return "binary"
+	}
+	return "partial"
+}
+
+func hasPartialAlpha(alphas map[uint8]bool) bool {
+	for a := range alphas {
+		if a != 0 && a != 255 {
+			return true
+		}
+	}
+	return false
+}
+
+func (stats *sheetStats) psnr(format TextureFormat) float64 {
+	channels := 0
+	for _, bits := range format.Bits {
+		if bits > 0 {
+			channels++
+		}
+	}
+
+	mse := stats.sqErrors[format.Name] / float64(max(1, stats.pixels*channels))
+	if mse == 0 {
+		return losslessPSNR
+	}
+	return min(losslessPSNR, 10*math.Log10(255*255/mse))
+}
+
+func adviseSheet(inputDir string, sheet Sheet, minPSNR float64) (SheetAdvice, error) {
+	img, err := decodeSheet(inputDir, sheet)
+	if err != nil {
+		return SheetAdvice{}, err
+	}
+
+	stats := &sheetStats{
+		alphas:   make(map[uint8]bool),
+		colors:   make(ColorHistogram),
+		sqErrors: make(map[string]float64),
+	}
+
+	for _, tex := range sheet.Textures {
+		stats.add(img, frameRegion(tex))
+	}
+
+	advice := SheetAdvice{
+		Image:        sheet.Image,
+		Frames:       len(sheet.Textures),
+		Pixels:       stats.pixels,
+		Alpha:        stats.alphaKind(),
+		UniqueColors: len(stats.colors),
+		Palette:      stats.colors.fits(256),
+		PSNR:         make(map[string]float64),
+	}
+	if stats.pairs > 0 {
+		advice.Gradient = float64(stats.smooth) / float64(stats.pairs)
+	}
+
+	for _, format := range textureFormats {
+		advice.PSNR[format.Name] = stats.psnr(format)
+	}
+
+	var recommended TextureFormat
+	for _, format := range textureFormats {
+		switch {
+		case format.Bits[3] == 0 && advice.Alpha != "none":
+			continue
+		case format.Bits[3] == 1 && advice.Alpha == "partial":
+			continue
+		case advice.PSNR[format.Name] < minPSNR:
+			continue
+		}
+
+		recommended = format
+		break
+	}
+
+	advice.Recommended = recommended.Name
+	switch {
+	case advice.Recommended == "RGBA8888":
+		advice.Reason = fmt.Sprintf("needs RGBA8888, no cheaper format stays above %.0f dB", minPSNR)
+	case advice.PSNR[advice.Recommended] == losslessPSNR:
+		advice.Reason = fmt.Sprintf("survives %s losslessly", advice.Recommended)
+	default:
+		advice.Reason = fmt.Sprintf("survives %s at %.1f dB", advice.Recommended, advice.PSNR[advice.Recommended])
+	}
+	if advice.Gradient > 0.25 && recommended.BytesPerPixel < 3 {
+		advice.Reason += fmt.Sprintf(", %.0f%% of neighbouring pixels form gradients that may band", advice.Gradient*100)
+	}
+
+	return advice, nil
+}
+
+func newAdviseCmd() *cobra.Command {
+	var minPSNR float64 = 40
+	var asJSON bool = false
+
+	var adviseCmd = &cobra.Command{
+		Use:   "advise <path>",
+		Short: "Recommend a GPU texture format for each sheet from its frame content",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var path = args[0]
+			format, _ := cmd.Flags().GetString("format")
+
+			pack, err := loadPack(path, format)
+			if err != nil {
+				return err
+			}
+
+			advice := make([]SheetAdvice, 0, len(pack.Sheets))
+			for _, sh := range pack.Sheets {
+				sheetAdvice, err := adviseSheet(filepath.Dir(path), sh, minPSNR)
+				if err != nil {
+					return err
+				}
+				advice = append(advice, sheetAdvice)
+			}
+
+			if asJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(struct {
+					MinPSNR float64       `json:"minPsnr"`
+					Sheets  []SheetAdvice `json:"sheets"`
+				}{minPSNR, advice})
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "SHEET\tALPHA\tCOLORS\tRECOMMENDED\tREASON")
+			for _, sheetAdvice := range advice {
+				fmt.Fprintf(writer, "%s\t%s\t%d\t%s\t%s\n", sheetAdvice.Image, sheetAdvice.Alpha, sheetAdvice.UniqueColors, sheetAdvice.Recommended, sheetAdvice.Reason)
+			}
+			writer.Flush()
+
+			return nil
+		},
+	}
+
+	adviseCmd.Flags().Float64VarP(&minPSNR, "min-psnr", "", minPSNR, "Lowest PSNR in dB a format may have to be recommended")
+	adviseCmd.Flags().BoolVarP(&asJSON, "json", "", asJSON, "Print the analysis as JSON")
+
+	return adviseCmd
+}
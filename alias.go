@@ -0,0 +1,29 @@
+package main
+
+type aliasKey struct {
+	Sheet            string
+	Frame            Frame
+	Rotated          bool
+	SourceSize       Size
+	SpriteSourceSize Frame
+}
+
+// findAliases maps every frame that cuts the same rect out of the same sheet
+// as an earlier frame, and so extracts to identical pixels, to that first frame.
+func findAliases(pack Pack) map[string]Texture {
+	aliases := make(map[string]Texture)
+	seen := make(map[aliasKey]Texture)
+
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			key := aliasKey{sh.Image, tex.Frame, tex.Rotated, tex.SourceSize, tex.SpriteSourceSize}
+			if canonical, ok := seen[key]; ok {
+				aliases[tex.FileName] = canonical
+			} else {
+				seen[key] = tex
+			}
+		}
+	}
+
+	return aliases
+}
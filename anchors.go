@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// FrameAnchor is what re-importing a sprite needs to line it up again: its
+// pivot, when the atlas has one, and where its trimmed pixels sit on the
+// source canvas.
+type FrameAnchor struct {
+	Pivot *Pivot     `json:"pivot,omitempty"`
+	Trim  TrimOffset `json:"trim"`
+}
+
+func writeAnchors(pack Pack, path string) error {
+	anchors := make(map[string]FrameAnchor)
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			anchors[tex.FileName] = FrameAnchor{
+				Pivot: tex.Pivot,
+				Trim: TrimOffset{
+					X:          tex.SpriteSourceSize.X,
+					Y:          tex.SpriteSourceSize.Y,
+					Width:      tex.SpriteSourceSize.Width,
+					Height:     tex.SpriteSourceSize.Height,
+					SourceSize: tex.SourceSize,
+				},
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(anchors, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode anchors: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write anchors: %w", err)
+	}
+	return nil
+}
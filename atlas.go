@@ -0,0 +1,135 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"image"
+	"sync"
+)
+
+// pageCacheSize caps how many decoded pages each Atlas keeps, so fetching
+// frames from a large multi-page pack does not hold every page in memory.
+var pageCacheSize = 8
+
+type cachedPage struct {
+	key   string
+	ready chan struct{}
+	img   image.Image
+	err   error
+}
+
+// A PageCache keeps the most recently used decoded pages. Concurrent
+// requests for a page that is still decoding wait for that one decode.
+type PageCache struct {
+	Capacity int
+	mu       sync.Mutex
+	order    *list.List
+	pages    map[string]*list.Element
+}
+
+func newPageCache(capacity int) *PageCache {
+	return &PageCache{Capacity: max(capacity, 1), order: list.New(), pages: make(map[string]*list.Element)}
+}
+
+func (cache *PageCache) get(ctx context.Context, key string, decode func() (image.Image, error)) (image.Image, error) {
+	cache.mu.Lock()
+	if elem, ok := cache.pages[key]; ok {
+		cache.order.MoveToFront(elem)
+		page := elem.Value.(*cachedPage)
+		cache.mu.Unlock()
+
+		select {
+		case <-page.ready:
+			return page.img, page.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		cache.mu.Unlock()
+		return nil, err
+	}
+
+	page := &cachedPage{key: key, ready: make(chan struct{})}
+	cache.pages[key] = cache.order.PushFront(page)
+	for cache.order.Len() > cache.Capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.pages, oldest.Value.(*cachedPage).key)
+	}
+	cache.mu.Unlock()
+
+	page.img, page.err = decode()
+	close(page.ready)
+
+	// A failed decode is not cached, so the next request tries again.
+	if page.err != nil {
+		cache.mu.Lock()
+		if elem, ok := cache.pages[key]; ok && elem.Value == page {
+			cache.order.Remove(elem)
+			delete(cache.pages, key)
+		}
+		cache.mu.Unlock()
+	}
+	return page.img, page.err
+}
+
+// An Atlas fetches single frames of a pack, decoding only the pages they sit
+// on, for commands that need a few frames rather than all of them.
+type Atlas struct {
+	Pack
+	InputDir string
+	frames   map[string]Texture
+	owners   map[string]Sheet
+	pages    *PageCache
+}
+
+func newAtlas(pack Pack, inputDir string) *Atlas {
+	atlas := &Atlas{
+		Pack:     pack,
+		InputDir: inputDir,
+		frames:   make(map[string]Texture),
+		owners:   make(map[string]Sheet),
+		pages:    newPageCache(pageCacheSize),
+	}
+
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			atlas.frames[tex.FileName] = tex
+			atlas.owners[tex.FileName] = sh
+		}
+	}
+
+	return atlas
+}
+
+type AtlasFrame struct {
+	atlas *Atlas
+	Name  string
+}
+
+// Frame looks nothing up until Image is called, so a missing frame is
+// reported there.
+func (atlas *Atlas) Frame(name string) AtlasFrame {
+	return AtlasFrame{atlas: atlas, Name: name}
+}
+
+// Image renders the frame at its source size, decoding its page unless it is
+// already cached.
+func (frame AtlasFrame) Image(ctx context.Context) (*image.NRGBA, error) {
+	texture, ok := frame.atlas.frames[frame.Name]
+	if !ok {
+		return nil, fmt.Errorf("frame %q not found in atlas", frame.Name)
+	}
+
+	sheet := frame.atlas.owners[frame.Name]
+	img, err := frame.atlas.pages.get(ctx, sheet.Image, func() (image.Image, error) {
+		return decodeSheet(frame.atlas.InputDir, sheet)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return renderTexture(texture, img), nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// An Attribution records who made a frame and under what terms, carried into
+// the manifest and the PNGs so redistributed sprites keep their credit.
+type Attribution struct {
+	Author  string `json:"author,omitempty"`
+	License string `json:"license,omitempty"`
+	Source  string `json:"source,omitempty"`
+}
+
+type attributionRule struct {
+	pattern string
+	glob    *regexp.Regexp
+	Attribution
+}
+
+// Attributions maps frame names to their attribution, either by exact name
+// or through globs like ui/** for whole folders.
+type Attributions struct {
+	exact map[string]Attribution
+	rules []attributionRule
+}
+
+func loadAttributions(path string) (Attributions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Attributions{}, fmt.Errorf("failed to read attribution file: %w", err)
+	}
+
+	var entries map[string]Attribution
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return Attributions{}, fmt.Errorf("invalid attribution file: %w", err)
+	}
+
+	attributions := Attributions{exact: make(map[string]Attribution)}
+	for key, attribution := range entries {
+		if !strings.ContainsAny(key, "*?[") {
+			attributions.exact[key] = attribution
+			continue
+		}
+		glob, err := compileGlob(key)
+		if err != nil {
+			return Attributions{}, err
+		}
+		attributions.rules = append(attributions.rules, attributionRule{pattern: key, glob: glob, Attribution: attribution})
+	}
+
+	// The longest glob is taken as the most specific, so ui/icons/** wins over **.
+	slices.SortFunc(attributions.rules, func(a, b attributionRule) int {
+		return cmp.Or(cmp.Compare(len(b.pattern), len(a.pattern)), strings.Compare(a.pattern, b.pattern))
+	})
+	return attributions, nil
+}
+
+// lookup returns a frame's own attribution, or that of the most specific glob
+// matching it.
+func (attributions Attributions) lookup(name string) (Attribution, bool) {
+	if attribution, ok := attributions.exact[name]; ok {
+		return attribution, true
+	}
+	for _, rule := range attributions.rules {
+		if rule.glob.MatchString(name) {
+			return rule.Attribution, true
+		}
+	}
+	return Attribution{}, false
+}
+
+// textChunks writes the attribution as PNG text under the standard Author,
+// Copyright, and Source keywords. tEXt only holds Latin-1, so anything
+// beyond ASCII goes in an uncompressed UTF-8 iTXt chunk instead.
+func (attribution Attribution) textChunks() []pngChunk {
+	var chunks []pngChunk
+	for _, field := range []struct{ keyword, text string }{
+		{"Author", attribution.Author},
+		{"Copyright", attribution.License},
+		{"Source", attribution.Source},
+	} {
+		if field.text == "" {
+			continue
+		}
+
+		ascii := !strings.ContainsFunc(field.text, func(r rune) bool { return r >= 0x80 })
+		if ascii {
+			chunks = append(chunks, pngChunk{"tEXt", []byte(field.keyword + "\x00" + field.text)})
+		} else {
+			chunks = append(chunks, pngChunk{"iTXt", []byte(field.keyword + "\x00\x00\x00\x00\x00" + field.text)})
+		}
+	}
+	return chunks
+}
+
+// stamped returns the profile with the attribution's text chunks added,
+// leaving the sheet's profile shared by other frames untouched.
+func (profile ColorProfile) stamped(attribution *Attribution) ColorProfile {
+	if attribution == nil {
+		return profile
+	}
+	return ColorProfile{Chunks: slices.Concat(profile.Chunks, attribution.textChunks())}
+}
+
+// attribution returns the frame's attribution, nil when it has none.
+func (unpacker Unpacker) attribution(texture Texture) *Attribution {
+	attribution, ok := unpacker.Attributions.lookup(texture.FileName)
+	if !ok {
+		return nil
+	}
+	return &attribution
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"math"
+	"math/rand/v2"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+type Augmenter struct {
+	Flip     bool
+	Rotate   bool
+	Hue      bool
+	Noise    bool
+	Variants int
+	Seed     uint64
+}
+
+type augmentParams struct {
+	Flip   bool
+	Angle  float64
+	Hue    float64
+	Jitter float64
+}
+
+func newAugmenter(augmentations []string, variants int, seed uint64) (Augmenter, error) {
+	augmenter := Augmenter{Seed: seed}
+
+	for _, aug := range augmentations {
+		switch aug {
+		case "flip":
+			augmenter.Flip = true
+		case "rotate":
+			augmenter.Rotate = true
+		case "hue":
+			augmenter.Hue = true
+		case "noise":
+			augmenter.Noise = true
+		default:
+			return Augmenter{}, fmt.Errorf("invalid augmentation %q, expected flip, rotate, hue, or noise", aug)
+		}
+	}
+
+	if len(augmentations) > 0 {
+		if variants <= 0 {
+			return Augmenter{}, fmt.Errorf("variants must be positive")
+		}
+		augmenter.Variants = variants
+	}
+
+	return augmenter, nil
+}
+
+// Parameters are derived from the frame name and variant index so every run,
+// regardless of worker scheduling, produces the same variants.
+func (augmenter Augmenter) params(texture Texture, variant int) augmentParams {
+	hash := fnv.New64a()
+	hash.Write([]byte(texture.FileName))
+	rng := rand.New(rand.NewPCG(augmenter.Seed, hash.Sum64()+uint64(variant)))
+
+	var params augmentParams
+	if augmenter.Flip {
+		params.Flip = rng.IntN(2) == 1
+	}
+	if augmenter.Rotate {
+		params.Angle = rng.Float64()*30 - 15
+	}
+	if augmenter.Hue {
+		params.Hue = rng.Float64()*36 - 18
+	}
+	if augmenter.Noise {
+		params.Jitter = rng.Float64()*8 + 2
+	}
+
+	return params
+}
+
+func (params augmentParams) String() string {
+	var parts []string
+
+	if params.Flip {
+		parts = append(parts, "flip")
+	}
+	if params.Angle != 0 {
+		parts = append(parts, fmt.Sprintf("rotate(%.1f)", params.Angle))
+	}
+	if params.Hue != 0 {
+		parts = append(parts, fmt.Sprintf("hue(%.1f)", params.Hue))
+	}
+	if params.Jitter != 0 {
+		parts = append(parts, fmt.Sprintf("noise(%.1f)", params.Jitter))
+	}
+
+	return strings.Join(parts, "+")
+}
+
+func (augmenter Augmenter) apply(sprite image.Image, params augmentParams) *image.RGBA {
+	bounds := sprite.Bounds()
+	variant := image.NewRGBA(bounds)
+
+	// Flip and rotate about the sprite center in a single affine transform.
+	cx, cy := float64(bounds.Dx())/2, float64(bounds.Dy())/2
+	sin, cos := math.Sincos(params.Angle * math.Pi / 180)
+	sx := 1.0
+	if params.Flip {
+		sx = -1
+	}
+
+	transform := f64.Aff3{
+		sx * cos, -sin, cx - sx*cos*cx + sin*cy,
+		sx * sin, cos, cy - sx*sin*cx - cos*cy,
+	}
+	draw.BiLinear.Transform(variant, transform, sprite, bounds, draw.Src, nil)
+
+	if params.Hue == 0 && params.Jitter == 0 {
+		return variant
+	}
+
+	rng := rand.New(rand.NewPCG(augmenter.Seed, math.Float64bits(params.Jitter)))
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			px := variant.RGBAAt(x, y)
+			if px.A == 0 {
+				continue
+			}
+
+			if params.Hue != 0 {
+				px = shiftHue(px, params.Hue)
+			}
+			if params.Jitter != 0 {
+				px.R = jitterChannel(px.R, px.A, rng.NormFloat64()*params.Jitter)
+				px.G = jitterChannel(px.G, px.A, rng.NormFloat64()*params.Jitter)
+				px.B = jitterChannel(px.B, px.A, rng.NormFloat64()*params.Jitter)
+			}
+
+			variant.SetRGBA(x, y, px)
+		}
+	}
+
+	return variant
+}
+
+// Hue is invariant under the uniform alpha scaling of premultiplied colors,
+// so the rotation can be applied without un-premultiplying first.
+func shiftHue(px color.RGBA, degrees float64) color.RGBA {
+	r, g, b := float64(px.R), float64(px.G), float64(px.B)
+	hi, lo := max(r, g, b), min(r, g, b)
+	if hi == lo {
+		return px
+	}
+
+	var hue float64
+	switch hi {
+	case r:
+		hue = math.Mod((g-b)/(hi-lo), 6)
+	case g:
+		hue = (b-r)/(hi-lo) + 2
+	default:
+		hue = (r-g)/(hi-lo) + 4
+	}
+	hue = math.Mod(hue*60+degrees+360, 360) / 60
+
+	chroma := hi - lo
+	mid := chroma * (1 - math.Abs(math.Mod(hue, 2)-1))
+
+	var nr, ng, nb float64
+	switch int(hue) {
+	case 0:
+		nr, ng, nb = chroma, mid, 0
+	case 1:
+		nr, ng, nb = mid, chroma, 0
+	case 2:
+		nr, ng, nb = 0, chroma, mid
+	case 3:
+		nr, ng, nb = 0, mid, chroma
+	case 4:
+		nr, ng, nb = mid, 0, chroma
+	default:
+		nr, ng, nb = chroma, 0, mid
+	}
+
+	return color.RGBA{uint8(nr + lo + 0.5), uint8(ng + lo + 0.5), uint8(nb + lo + 0.5), px.A}
+}
+
+func jitterChannel(c, alpha uint8, delta float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(float64(alpha), float64(c)+delta))))
+}
+
+func (unpacker Unpacker) augmentPath(texture Texture, variant int) string {
+	outputPath := unpacker.datasetPath(texture)
+	ext := filepath.Ext(outputPath)
+
+	return fmt.Sprintf("%s_aug%d%s", strings.TrimSuffix(outputPath, ext), variant, ext)
+}
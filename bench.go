@@ -0,0 +1,195 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// benchWorkerCounts doubles from one worker up to the default of --workers,
+// which it always ends with.
+func benchWorkerCounts() []int {
+	limit := min(2*runtime.NumCPU(), 32)
+	var counts []int
+	for n := 1; n < limit; n *= 2 {
+		counts = append(counts, n)
+	}
+	return append(counts, limit)
+}
+
+// discardSprites encodes sprites in full but writes none of the bytes, so a
+// benchmark measures decoding and encoding without the disk.
+type discardSprites struct {
+	SpriteEncoder
+}
+
+func (sprites discardSprites) Encode(w io.Writer, img image.Image) error {
+	return sprites.SpriteEncoder.Encode(io.Discard, img)
+}
+
+type BenchResult struct {
+	Workers int
+	Frames  int
+	Bytes   int64
+	Elapsed time.Duration
+}
+
+func (result BenchResult) framesPerSecond() float64 {
+	return float64(result.Frames) / result.Elapsed.Seconds()
+}
+
+// bench extracts the pack into the workspace once per run at each worker
+// count, keeping the fastest run of each.
+func bench(pack Pack, inputDir string, ws *Workspace, encoder SpriteEncoder, counts []int, runs int) ([]BenchResult, error) {
+	frames := 0
+	for _, sh := range pack.Sheets {
+		frames += len(sh.Textures)
+	}
+
+	// Every run would repeat the same messages, so only warnings are shown.
+	defer func(level int) { verbosity = level }(verbosity)
+	verbosity = min(verbosity, verbosityQuiet)
+
+	var results []BenchResult
+	for _, workers := range counts {
+		result := BenchResult{Workers: workers, Frames: frames}
+		for run := range runs {
+			outputDir := ws.path(fmt.Sprintf("out-%d-%d", workers, run))
+			unpacker := Unpacker{
+				Pack:      pack,
+				InputDir:  inputDir,
+				OutputDir: outputDir,
+				Workers:   workers,
+				Encoder:   encoder,
+				Summary:   "none",
+			}
+
+			start := time.Now()
+			if err := unpacker.unpack(true); err != nil {
+				return nil, fmt.Errorf("failed to extract with %d workers: %w", workers, err)
+			}
+			elapsed := time.Since(start)
+
+			if result.Elapsed == 0 || elapsed < result.Elapsed {
+				bytes, err := dirSize(outputDir)
+				if err != nil {
+					return nil, fmt.Errorf("failed to measure benchmark output: %w", err)
+				}
+				result.Elapsed, result.Bytes = elapsed, bytes
+			}
+			if err := os.RemoveAll(outputDir); err != nil {
+				return nil, fmt.Errorf("failed to clean up benchmark output: %w", err)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+func newBenchCmd() *cobra.Command {
+	var workers []int = benchWorkerCounts()
+	var runs int = 1
+	var outputFormat string = "png"
+	var discard bool = false
+	var keep bool = false
+
+	var benchCmd = &cobra.Command{
+		Use:   "bench <path>",
+		Short: "Time extracting an atlas at several worker counts, to choose --workers for this machine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var path = args[0]
+			format, _ := cmd.Flags().GetString("format")
+
+			if len(workers) == 0 {
+				return fmt.Errorf("no worker counts to time")
+			}
+			if runs < 1 {
+				return fmt.Errorf("invalid runs %d, must be at least 1", runs)
+			}
+			for _, n := range workers {
+				if n < 1 {
+					return fmt.Errorf("invalid worker count %d, must be at least 1", n)
+				}
+			}
+
+			encoder, err := newSpriteEncoder(SpriteEncoding{Format: outputFormat, PNGCompression: "default"})
+			if err != nil {
+				return err
+			}
+			if discard {
+				encoder = discardSprites{encoder}
+			}
+
+			pack, err := loadPack(path, format)
+			if err != nil {
+				return err
+			}
+
+			ws, err := openWorkspace("bench")
+			if err != nil {
+				return err
+			}
+			if keep {
+				ws.keep()
+				logf("[info] keeping work directory %s\n", ws.Dir)
+			}
+			defer ws.Close()
+
+			results, err := bench(pack, filepath.Dir(path), ws, encoder, workers, runs)
+			if err != nil {
+				return err
+			}
+
+			fastest := slices.MinFunc(results, func(a, b BenchResult) int {
+				return cmp.Compare(a.Elapsed, b.Elapsed)
+			})
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+			fmt.Fprintln(w, "WORKERS\tTIME\tFRAMES/S\tWRITTEN/S\tSPEEDUP\t")
+			for _, result := range results {
+				fmt.Fprintf(w, "%d\t%.2fs\t%.0f\t%s\t%.2fx\t\n",
+					result.Workers, result.Elapsed.Seconds(), result.framesPerSecond(),
+					formatBytes(int64(float64(result.Bytes)/result.Elapsed.Seconds())),
+					results[0].Elapsed.Seconds()/result.Elapsed.Seconds())
+			}
+			w.Flush()
+
+			logSuccess("[info] fastest with --workers %d, %.0f frames/s\n", fastest.Workers, fastest.framesPerSecond())
+			return nil
+		},
+	}
+
+	benchCmd.Flags().IntSliceVarP(&workers, "workers", "w", workers, "Worker counts to time, comma separated")
+	benchCmd.Flags().IntVarP(&runs, "runs", "", runs, "Runs per worker count, keeping the fastest")
+	benchCmd.Flags().StringVarP(&outputFormat, "output-format", "", outputFormat, "Sprite image format to encode")
+	benchCmd.Flags().BoolVarP(&discard, "discard", "", discard, "Encode sprites without writing their bytes, leaving the disk out of the timing")
+	benchCmd.Flags().BoolVarP(&keep, "keep", "", keep, "Keep the work directory for inspection")
+
+	return benchCmd
+}
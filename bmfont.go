@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Glyph struct {
+	ID       rune `xml:"id,attr"`
+	X        int  `xml:"x,attr"`
+	Y        int  `xml:"y,attr"`
+	Width    int  `xml:"width,attr"`
+	Height   int  `xml:"height,attr"`
+	XOffset  int  `xml:"xoffset,attr"`
+	YOffset  int  `xml:"yoffset,attr"`
+	XAdvance int  `xml:"xadvance,attr"`
+	Page     int  `xml:"page,attr"`
+}
+
+type kerningPair struct {
+	First  rune
+	Second rune
+}
+
+type BitmapFont struct {
+	Face       string
+	LineHeight int
+	Base       int
+	Pages      map[int]string
+	Glyphs     map[rune]Glyph
+	Kernings   map[kerningPair]int
+}
+
+type xmlBitmapFont struct {
+	Info struct {
+		Face string `xml:"face,attr"`
+	} `xml:"info"`
+	Common struct {
+		LineHeight int `xml:"lineHeight,attr"`
+		Base       int `xml:"base,attr"`
+	} `xml:"common"`
+	Pages []struct {
+		ID   int    `xml:"id,attr"`
+		File string `xml:"file,attr"`
+	} `xml:"pages>page"`
+	Chars    []Glyph `xml:"chars>char"`
+	Kernings []struct {
+		First  rune `xml:"first,attr"`
+		Second rune `xml:"second,attr"`
+		Amount int  `xml:"amount,attr"`
+	} `xml:"kernings>kerning"`
+}
+
+func newBitmapFont() BitmapFont {
+	return BitmapFont{
+		Pages:    make(map[int]string),
+		Glyphs:   make(map[rune]Glyph),
+		Kernings: make(map[kerningPair]int),
+	}
+}
+
+func parseBitmapFont(data []byte) (BitmapFont, error) {
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("BMF")):
+		return BitmapFont{}, fmt.Errorf("binary BMFont files are not supported, export as text or XML")
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return parseXMLBitmapFont(trimmed)
+	default:
+		return parseTextBitmapFont(trimmed)
+	}
+}
+
+func parseXMLBitmapFont(data []byte) (BitmapFont, error) {
+	var raw xmlBitmapFont
+	if err := xml.Unmarshal(data, &raw); err != nil {
+		return BitmapFont{}, fmt.Errorf("invalid font XML: %w", err)
+	}
+
+	font := newBitmapFont()
+	font.Face = raw.Info.Face
+	font.LineHeight = raw.Common.LineHeight
+	font.Base = raw.Common.Base
+
+	for _, page := range raw.Pages {
+		font.Pages[page.ID] = page.File
+	}
+	for _, glyph := range raw.Chars {
+		font.Glyphs[glyph.ID] = glyph
+	}
+	for _, kerning := range raw.Kernings {
+		font.Kernings[kerningPair{kerning.First, kerning.Second}] = kerning.Amount
+	}
+
+	return font, nil
+}
+
+// Text BMFont lines are a tag followed by key=value pairs, where values may
+// be quoted and contain spaces.
+func parseBitmapFontLine(line string) (string, map[string]string) {
+	tag, rest, _ := strings.Cut(line, " ")
+	attrs := make(map[string]string)
+
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				attrs[key], rest = value[1:], ""
+				continue
+			}
+			attrs[key], rest = value[1:end+1], value[end+2:]
+			continue
+		}
+
+		value, rest, _ = strings.Cut(value, " ")
+		attrs[key] = value
+	}
+
+	return tag, attrs
+}
+
+func parseTextBitmapFont(data []byte) (BitmapFont, error) {
+	font := newBitmapFont()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		tag, attrs := parseBitmapFontLine(strings.TrimSpace(scanner.Text()))
+
+		atoi := func(key string) int {
+			n, _ := strconv.Atoi(attrs[key])
+			return n
+		}
+
+		switch tag {
+		case "info":
+			font.Face = attrs["face"]
+		case "common":
+			font.LineHeight = atoi("lineHeight")
+			font.Base = atoi("base")
+		case "page":
+			font.Pages[atoi("id")] = attrs["file"]
+		case "char":
+			if _, ok := attrs["id"]; !ok {
+				return BitmapFont{}, fmt.Errorf("invalid font: line %d: char without id", lineNum)
+			}
+			font.Glyphs[rune(atoi("id"))] = Glyph{
+				ID:       rune(atoi("id")),
+				X:        atoi("x"),
+				Y:        atoi("y"),
+				Width:    atoi("width"),
+				Height:   atoi("height"),
+				XOffset:  atoi("xoffset"),
+				YOffset:  atoi("yoffset"),
+				XAdvance: atoi("xadvance"),
+				Page:     atoi("page"),
+			}
+		case "kerning":
+			font.Kernings[kerningPair{rune(atoi("first")), rune(atoi("second"))}] = atoi("amount")
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return BitmapFont{}, fmt.Errorf("failed to read font: %w", err)
+	}
+
+	if len(font.Glyphs) == 0 {
+		return BitmapFont{}, fmt.Errorf("invalid font: no chars found")
+	}
+
+	return font, nil
+}
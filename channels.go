@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var channelModes = []string{"rgba", "rgb", "alpha", "split"}
+
+// channelProfiles names common packings of data maps, in the same form as a
+// custom profile.
+var channelProfiles = map[string]string{
+	"orm":                   "R=occlusion,G=roughness,B=metallic",
+	"normal-roughness-mask": "RG=normal,B=roughness,A=mask",
+}
+
+var channelLabel = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// A ChannelOutput writes some of a sprite's channels to their own image,
+// named with Suffix after the frame name when it is set.
+type ChannelOutput struct {
+	Suffix   string
+	Channels string
+}
+
+// channelOutputs resolves a channel mode, a named profile, or a custom
+// profile like "RG=normal,B=roughness,A=mask" that writes each group of
+// channels to its own map labeled after the frame name.
+func channelOutputs(mode string) ([]ChannelOutput, error) {
+	switch mode {
+	case "rgba":
+		return []ChannelOutput{{Channels: "RGBA"}}, nil
+	case "rgb":
+		return []ChannelOutput{{Channels: "RGB"}}, nil
+	case "alpha":
+		return []ChannelOutput{{Channels: "A"}}, nil
+	case "split":
+		return []ChannelOutput{{"r", "R"}, {"g", "G"}, {"b", "B"}, {"a", "A"}}, nil
+	}
+
+	spec, named := channelProfiles[mode]
+	if !named {
+		spec = mode
+	}
+	if !strings.Contains(spec, "=") {
+		return nil, fmt.Errorf("invalid channels %q, expected %s, a profile (%s), or a mapping like RG=normal,B=roughness",
+			mode, strings.Join(channelModes, ", "), strings.Join(slices.Sorted(maps.Keys(channelProfiles)), ", "))
+	}
+
+	var outputs []ChannelOutput
+	labels := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		channels, label, _ := strings.Cut(part, "=")
+		channels, label = strings.ToUpper(strings.TrimSpace(channels)), strings.TrimSpace(label)
+
+		if !channelLabel.MatchString(label) {
+			return nil, fmt.Errorf("invalid channel label %q in %q, expected letters, digits, - or _", label, part)
+		}
+		if labels[label] {
+			return nil, fmt.Errorf("invalid channels %q, label %s is used twice", mode, label)
+		}
+		labels[label] = true
+
+		if channels == "" || len(channels) > 4 {
+			return nil, fmt.Errorf("invalid channels %q in %q, expected 1 to 4 of R, G, B, A", channels, part)
+		}
+		for i, ch := range channels {
+			if !strings.ContainsRune("RGBA", ch) || strings.ContainsRune(channels[i+1:], ch) {
+				return nil, fmt.Errorf("invalid channels %q in %q, expected 1 to 4 of R, G, B, A", channels, part)
+			}
+		}
+
+		outputs = append(outputs, ChannelOutput{Suffix: label, Channels: channels})
+	}
+	return outputs, nil
+}
+
+func (output ChannelOutput) path(outputPath string) string {
+	if output.Suffix == "" {
+		return outputPath
+	}
+	ext := filepath.Ext(outputPath)
+	return fmt.Sprintf("%s_%s%s", strings.TrimSuffix(outputPath, ext), output.Suffix, ext)
+}
+
+// selectChannels keeps the named channels of sprite in order. A single
+// channel comes out as a grayscale image, two or three as an opaque image
+// with the rest black, and four with the last as alpha. Colors are read
+// unpremultiplied, so a mask-packed channel keeps its values where alpha is
+// low, and 16-bit sprites stay 16-bit.
+func selectChannels(sprite image.Image, channels string) image.Image {
+	if channels == "RGBA" {
+		return sprite
+	}
+
+	bounds := sprite.Bounds()
+	deep := image.NewNRGBA64(bounds)
+	draw.Draw(deep, bounds, sprite, bounds.Min, draw.Src)
+
+	var out draw.Image
+	switch {
+	case len(channels) == 1 && isDeep(sprite):
+		out = image.NewGray16(bounds)
+	case len(channels) == 1:
+		out = image.NewGray(bounds)
+	case isDeep(sprite):
+		out = image.NewNRGBA64(bounds)
+	default:
+		out = image.NewNRGBA(bounds)
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := deep.NRGBA64At(x, y)
+			values := [4]uint16{c.R, c.G, c.B, c.A}
+			if len(channels) == 1 {
+				out.Set(x, y, color.Gray16{values[strings.IndexByte("RGBA", channels[0])]})
+				continue
+			}
+
+			picked := [4]uint16{3: 0xffff}
+			for i := range len(channels) {
+				picked[i] = values[strings.IndexByte("RGBA", channels[i])]
+			}
+			out.Set(x, y, color.NRGBA64{picked[0], picked[1], picked[2], picked[3]})
+		}
+	}
+	return out
+}
+
+func isDeep(sprite image.Image) bool {
+	switch sprite.(type) {
+	case *image.NRGBA64, *image.Gray16:
+		return true
+	}
+	return false
+}
+
+// writeChannels writes each channel output of sprite next to outputPath,
+// returning the bytes written.
+func (unpacker Unpacker) writeChannels(outputPath string, sprite image.Image) (int64, error) {
+	var written int64
+	outputs := unpacker.Channels
+	if len(outputs) == 0 {
+		outputs = []ChannelOutput{{Channels: "RGBA"}}
+	}
+
+	for _, output := range outputs {
+		n, err := unpacker.writeSprite(output.path(outputPath), selectChannels(sprite, output.Channels))
+		if err != nil {
+			return 0, err
+		}
+		written += n
+	}
+	return written, nil
+}
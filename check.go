@@ -0,0 +1,205 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+type FileCheck struct {
+	Path   string `json:"path"`
+	Frame  string `json:"frame,omitempty"`
+	Status string `json:"status"`
+}
+
+// pixelHash hashes decoded pixels rather than file bytes, so a sprite
+// re-encoded by another PNG writer still matches. Fully transparent pixels
+// hash alike whatever their color.
+func pixelHash(img image.Image) string {
+	nrgba := image.NewNRGBA(image.Rectangle{Max: img.Bounds().Size()})
+	draw.Draw(nrgba, nrgba.Rect, img, img.Bounds().Min, draw.Src)
+
+	for i := 0; i < len(nrgba.Pix); i += 4 {
+		if nrgba.Pix[i+3] == 0 {
+			clear(nrgba.Pix[i : i+4])
+		}
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%dx%d\n", nrgba.Rect.Dx(), nrgba.Rect.Dy())
+	hash.Write(nrgba.Pix)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// readManifest reads the manifest of an extracted directory, or nil when it
+// has none.
+func readManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest JSON: %w", err)
+	}
+	return &manifest, nil
+}
+
+// expectedPaths maps output paths to frame names, taken from the directory's
+// manifest when one was written so renamed layouts check too. It also returns
+// every frame accounted for, since aliases can share one path.
+func expectedPaths(unpacker Unpacker) (map[string]string, map[string]bool, error) {
+	dir := unpacker.OutputDir
+	paths := make(map[string]string)
+	listed := make(map[string]bool)
+
+	manifest, err := readManifest(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if manifest != nil {
+		for name, frame := range manifest.Frames {
+			paths[filepath.Join(dir, filepath.FromSlash(frame.Path))] = name
+			listed[name] = true
+		}
+		return paths, listed, nil
+	}
+
+	for _, sh := range unpacker.Sheets {
+		for _, tex := range sh.Textures {
+			paths[unpacker.outputPath(tex)] = tex.FileName
+			listed[tex.FileName] = true
+		}
+	}
+	return paths, listed, nil
+}
+
+func checkExtraction(pack Pack, inputDir, dir string) ([]FileCheck, error) {
+	paths, listed, err := expectedPaths(Unpacker{Pack: pack, OutputDir: dir})
+	if err != nil {
+		return nil, err
+	}
+
+	composer := newComposer(pack, inputDir)
+	var checks []FileCheck
+
+	for _, outputPath := range slices.Sorted(maps.Keys(paths)) {
+		name := paths[outputPath]
+		check := FileCheck{Path: outputPath, Frame: name, Status: "ok"}
+
+		if _, ok := composer.frames[name]; !ok {
+			check.Status = "extra"
+			checks = append(checks, check)
+			continue
+		}
+
+		if _, err := os.Stat(outputPath); err != nil {
+			check.Status = "missing"
+			checks = append(checks, check)
+			continue
+		}
+
+		sprite, err := composer.sprite(name)
+		if err != nil {
+			return nil, err
+		}
+
+		// A file that no longer decodes is as stale as one with other pixels.
+		onDisk, err := decodeImageFile(outputPath)
+		if err != nil || pixelHash(onDisk) != pixelHash(sprite) {
+			check.Status = "stale"
+		}
+		checks = append(checks, check)
+	}
+
+	// Frames the manifest does not know about are missing too.
+	unpacker := Unpacker{Pack: pack, OutputDir: dir}
+	for _, name := range frameNames(pack) {
+		if !listed[name] {
+			tex := composer.frames[name]
+			checks = append(checks, FileCheck{Path: unpacker.outputPath(tex), Frame: name, Status: "missing"})
+		}
+	}
+
+	extra, err := extraFiles(dir, paths)
+	if err != nil {
+		return nil, err
+	}
+	return append(checks, extra...), nil
+}
+
+// extraFiles lists the files under dir that no frame is expected at.
+func extraFiles(dir string, paths map[string]string) ([]FileCheck, error) {
+	var checks []FileCheck
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Sprites are never JSON, so .json files are the manifest and other metadata.
+		if entry.IsDir() || filepath.Ext(path) == ".json" {
+			return nil
+		}
+		if _, ok := paths[path]; !ok {
+			checks = append(checks, FileCheck{Path: path, Status: "extra"})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", dir, err)
+	}
+	return checks, nil
+}
+
+func newCheckCmd() *cobra.Command {
+	var checkCmd = &cobra.Command{
+		Use:   "check <atlas> <extracted-dir>",
+		Short: "Check an extracted directory against its atlas without writing anything",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var path, dir = args[0], args[1]
+			format, _ := cmd.Flags().GetString("format")
+
+			pack, err := loadPack(path, format)
+			if err != nil {
+				return err
+			}
+
+			checks, err := checkExtraction(pack, filepath.Dir(path), dir)
+			if err != nil {
+				return err
+			}
+
+			counts := make(map[string]int)
+			for _, check := range checks {
+				counts[check.Status]++
+				if check.Status != "ok" {
+					fmt.Printf("%-7s  %s\n", check.Status, check.Path)
+				}
+			}
+
+			logf("[info] %d ok, %d missing, %d stale, %d extra\n",
+				counts["ok"], counts["missing"], counts["stale"], counts["extra"])
+
+			if problems := len(checks) - counts["ok"]; problems > 0 {
+				return fmt.Errorf("%s is out of date with %s", dir, path)
+			}
+			return nil
+		},
+	}
+
+	return checkCmd
+}
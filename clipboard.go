@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// A ClipboardBackend copies a PNG file to the system clipboard by running a
+// platform tool, since Go has no portable clipboard API.
+type ClipboardBackend struct {
+	Tool string
+	Args func(pngPath string) []string
+	// Stdin tools read the PNG from standard input instead of the path.
+	Stdin bool
+	// Env names a variable that must be set for the tool to work, like
+	// WAYLAND_DISPLAY for wl-copy.
+	Env string
+}
+
+var clipboardBackends = map[string][]ClipboardBackend{
+	"linux": {
+		{Tool: "wl-copy", Args: func(string) []string { return []string{"--type", "image/png"} }, Stdin: true, Env: "WAYLAND_DISPLAY"},
+		{Tool: "xclip", Args: func(pngPath string) []string {
+			return []string{"-selection", "clipboard", "-t", "image/png", "-i", pngPath}
+		}},
+	},
+	"darwin": {
+		{Tool: "osascript", Args: func(pngPath string) []string {
+			quoted := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(pngPath)
+			return []string{"-e", fmt.Sprintf(`set the clipboard to (read (POSIX file "%s") as «class PNGf»)`, quoted)}
+		}},
+	},
+	"windows": {
+		{Tool: "powershell", Args: func(pngPath string) []string {
+			quoted := strings.ReplaceAll(pngPath, "'", "''")
+			return []string{"-NoProfile", "-Command", fmt.Sprintf(
+				"Add-Type -AssemblyName System.Windows.Forms, System.Drawing; [System.Windows.Forms.Clipboard]::SetImage([System.Drawing.Image]::FromFile('%s'))", quoted)}
+		}},
+	},
+}
+
+func findClipboardBackend() (ClipboardBackend, error) {
+	var tools []string
+	for _, backend := range clipboardBackends[runtime.GOOS] {
+		tools = append(tools, backend.Tool)
+		if backend.Env != "" && os.Getenv(backend.Env) == "" {
+			continue
+		}
+		if _, err := exec.LookPath(backend.Tool); err == nil {
+			return backend, nil
+		}
+	}
+
+	if len(tools) == 0 {
+		return ClipboardBackend{}, fmt.Errorf("copying images to the clipboard is not supported on %s", runtime.GOOS)
+	}
+	return ClipboardBackend{}, fmt.Errorf("copying images to the clipboard needs %s on PATH", strings.Join(tools, " or "))
+}
+
+func copyToClipboard(sprite image.Image) error {
+	backend, err := findClipboardBackend()
+	if err != nil {
+		return err
+	}
+
+	ws, err := openWorkspace("clipboard")
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	tmp, err := os.Create(ws.path("sprite.png"))
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	if err := png.Encode(tmp, sprite); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode sprite: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	cmd := exec.Command(backend.Tool, backend.Args(tmp.Name())...)
+	if backend.Stdin {
+		input, err := os.Open(tmp.Name())
+		if err != nil {
+			return fmt.Errorf("failed to open temporary file: %w", err)
+		}
+		defer input.Close()
+		cmd.Stdin = input
+	}
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to copy to the clipboard with %s: %w\n%s", backend.Tool, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func newExtractCmd() *cobra.Command {
+	var outputPath string
+	var clipboard bool = false
+
+	var extractCmd = &cobra.Command{
+		Use:   "extract <atlas> <frame>",
+		Short: "Extract a single frame to a file or the clipboard",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var atlasPath, frame = args[0], args[1]
+			format, _ := cmd.Flags().GetString("format")
+
+			pack, err := loadPack(atlasPath, format)
+			if err != nil {
+				return err
+			}
+
+			sprite, err := newComposer(pack, filepath.Dir(atlasPath)).sprite(frame)
+			if err != nil {
+				return err
+			}
+
+			if clipboard {
+				if err := copyToClipboard(sprite); err != nil {
+					return err
+				}
+				logf("[info] copied %s to the clipboard\n", frame)
+				if outputPath == "" {
+					return nil
+				}
+			}
+
+			if outputPath == "" {
+				outputPath = path.Base(frame) + ".png"
+			}
+
+			if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+
+			unpacker := Unpacker{OutputDir: filepath.Dir(outputPath)}
+			if _, err := unpacker.writeSprite(outputPath, sprite); err != nil {
+				return err
+			}
+			logf("[info] wrote %s to %s\n", frame, outputPath)
+
+			return nil
+		},
+	}
+
+	extractCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output file, the frame name with .png in the current directory by default")
+	extractCmd.Flags().BoolVarP(&clipboard, "clipboard", "", clipboard, "Copy the frame to the system clipboard instead of writing a file")
+
+	return extractCmd
+}
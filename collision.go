@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var collisionModes = []string{"error", "skip", "suffix"}
+
+// textureKey tells frames apart even when a pack repeats a name across
+// sheets, which a name alone cannot.
+type textureKey struct {
+	Name  string
+	Frame Frame
+}
+
+func keyOf(tex Texture) textureKey {
+	return textureKey{Name: tex.FileName, Frame: tex.Frame}
+}
+
+// A Collision is a frame whose output path another frame took first.
+type Collision struct {
+	Frame string
+	With  string
+	Sheet string
+	Path  string
+	// Resolved is where the frame is written instead, empty when it is skipped.
+	Resolved string
+	key      textureKey
+}
+
+// pathKey folds case where the filesystem usually does, since Sprite.png and
+// sprite.png are one file there.
+func pathKey(path string) string {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return strings.ToLower(path)
+	}
+	return path
+}
+
+// findCollisions walks the frames in write order and resolves every output
+// path an earlier frame already took: suffix moves the later frame to the
+// first free _2, _3, ... path, and skip and error leave it out.
+func (unpacker Unpacker) findCollisions(mode string) []Collision {
+	taken := make(map[string]string)
+	var collisions []Collision
+
+	for _, sh := range unpacker.Sheets {
+		for _, tex := range sh.Textures {
+			if _, alias := unpacker.aliases[tex.FileName]; alias && unpacker.SkipAliases {
+				continue
+			}
+
+			path := unpacker.outputPath(tex)
+			first, ok := taken[pathKey(path)]
+			if !ok {
+				taken[pathKey(path)] = tex.FileName
+				continue
+			}
+
+			collision := Collision{Frame: tex.FileName, With: first, Sheet: sh.Image, Path: path, key: keyOf(tex)}
+			if mode == "suffix" {
+				ext := filepath.Ext(path)
+				for n := 2; ; n++ {
+					candidate := fmt.Sprintf("%s_%d%s", strings.TrimSuffix(path, ext), n, ext)
+					if _, ok := taken[pathKey(candidate)]; !ok {
+						collision.Resolved = candidate
+						taken[pathKey(candidate)] = tex.FileName
+						break
+					}
+				}
+			}
+			collisions = append(collisions, collision)
+		}
+	}
+
+	return collisions
+}
+
+// resolveCollisions applies the collision mode, failing on the first
+// collision with error and warning about each one otherwise. It returns
+// where each colliding frame goes, empty for skipped frames.
+func (unpacker Unpacker) resolveCollisions() (map[textureKey]string, error) {
+	collisions := unpacker.findCollisions(unpacker.OnCollision)
+	if len(collisions) == 0 {
+		return nil, nil
+	}
+
+	if unpacker.OnCollision == "error" {
+		first := collisions[0]
+		return nil, fmt.Errorf(tr("frames %q and %q would both be written to %s (%d collisions), pass --on-collision skip or suffix to keep going"), first.With, first.Frame, first.Path, len(collisions))
+	}
+
+	resolved := make(map[textureKey]string)
+	for _, collision := range collisions {
+		resolved[collision.key] = collision.Resolved
+		if collision.Resolved == "" {
+			logf(tr("[warn] skipping %q, its output %s is taken by %q\n"), collision.Frame, collision.Path, collision.With)
+		} else {
+			logf(tr("[warn] writing %q to %s, its output %s is taken by %q\n"), collision.Frame, collision.Resolved, collision.Path, collision.With)
+		}
+	}
+	return resolved, nil
+}
+
+// collided reports whether tex lost its output path to another frame and
+// is skipped.
+func (unpacker Unpacker) collided(tex Texture) bool {
+	resolved, ok := unpacker.collisions[keyOf(tex)]
+	return ok && resolved == ""
+}
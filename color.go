@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+var colorModes = []string{"auto", "always", "never"}
+
+const (
+	colorRed    = "31"
+	colorGreen  = "32"
+	colorYellow = "33"
+)
+
+// colorize is whether status lines are colored, set by --color.
+var colorize = false
+
+// useColor decides whether to color output. Auto colors only a terminal,
+// and only when NO_COLOR is unset or empty, as https://no-color.org asks;
+// always overrides NO_COLOR, being asked for explicitly.
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && isTTY()
+}
+
+// paint colors text, leaving a trailing newline after the reset so the
+// color never bleeds into the next line.
+func paint(color, text string) string {
+	if !colorize || color == "" {
+		return text
+	}
+	text, newline := strings.CutSuffix(text, "\n")
+	painted := "\x1b[" + color + "m" + text + "\x1b[0m"
+	if newline {
+		painted += "\n"
+	}
+	return painted
+}
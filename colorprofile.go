@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"os"
+)
+
+type pngChunk struct {
+	Type string
+	Data []byte
+}
+
+// ColorProfile holds a sheet's color chunks in PNG form, ready to be written
+// into every sprite cut from it. Go's decoders drop them, and without them
+// color-managed tools show sprites from wide-gamut or gamma-tagged sheets
+// washed out.
+type ColorProfile struct {
+	Chunks []pngChunk
+}
+
+var colorChunks = map[string]bool{"iCCP": true, "sRGB": true, "gAMA": true, "cHRM": true}
+
+func (profile ColorProfile) chunk(kind string) ([]byte, bool) {
+	for _, chunk := range profile.Chunks {
+		if chunk.Type == kind {
+			return chunk.Data, true
+		}
+	}
+	return nil, false
+}
+
+// readColorProfile reads the color chunks of a PNG or WebP sheet. Other
+// formats carry none the decoders understand, so they have an empty profile.
+func readColorProfile(path string) (ColorProfile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ColorProfile{}, fmt.Errorf("failed to open texture sheet: %w", err)
+	}
+	defer file.Close()
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return ColorProfile{}, nil
+	}
+
+	switch {
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return readPNGProfile(io.MultiReader(bytes.NewReader(header[8:]), file))
+	case string(header[:4]) == "RIFF" && string(header[8:]) == "WEBP":
+		return readWebPProfile(file)
+	}
+	return ColorProfile{}, nil
+}
+
+// readPNGProfile copies the color chunks as they are, stopping at the image
+// data they must precede.
+func readPNGProfile(r io.Reader) (ColorProfile, error) {
+	var profile ColorProfile
+
+	for {
+		var head struct {
+			Length uint32
+			Type   [4]byte
+		}
+		if err := binary.Read(r, binary.BigEndian, &head); err != nil {
+			return profile, nil
+		}
+
+		kind := string(head.Type[:])
+		if kind == "IDAT" || kind == "IEND" {
+			return profile, nil
+		}
+
+		if !colorChunks[kind] {
+			if _, err := io.CopyN(io.Discard, r, int64(head.Length)+4); err != nil {
+				return profile, nil
+			}
+			continue
+		}
+
+		data := make([]byte, head.Length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return ColorProfile{}, fmt.Errorf("invalid PNG %s chunk: %w", kind, err)
+		}
+		if _, err := io.CopyN(io.Discard, r, 4); err != nil {
+			return ColorProfile{}, fmt.Errorf("invalid PNG %s chunk: %w", kind, err)
+		}
+		profile.Chunks = append(profile.Chunks, pngChunk{kind, data})
+	}
+}
+
+// readWebPProfile turns an extended WebP's ICCP chunk into a PNG iCCP chunk.
+func readWebPProfile(r io.Reader) (ColorProfile, error) {
+	for {
+		var head struct {
+			Type   [4]byte
+			Length uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &head); err != nil {
+			return ColorProfile{}, nil
+		}
+
+		padded := int64(head.Length) + int64(head.Length&1)
+		if string(head.Type[:]) != "ICCP" {
+			if _, err := io.CopyN(io.Discard, r, padded); err != nil {
+				return ColorProfile{}, nil
+			}
+			continue
+		}
+
+		icc := make([]byte, head.Length)
+		if _, err := io.ReadFull(r, icc); err != nil {
+			return ColorProfile{}, fmt.Errorf("invalid WebP ICCP chunk: %w", err)
+		}
+
+		var data bytes.Buffer
+		data.WriteString("ICC profile\x00\x00")
+		compressor := zlib.NewWriter(&data)
+		compressor.Write(icc)
+		compressor.Close()
+		return ColorProfile{Chunks: []pngChunk{{"iCCP", data.Bytes()}}}, nil
+	}
+}
+
+// icc returns the uncompressed ICC profile from an iCCP chunk.
+func (profile ColorProfile) icc() ([]byte, error) {
+	data, ok := profile.chunk("iCCP")
+	if !ok {
+		return nil, nil
+	}
+
+	// The profile name ends in a NUL, followed by the compression method.
+	name := bytes.IndexByte(data, 0)
+	if name < 0 || name+2 > len(data) {
+		return nil, fmt.Errorf("invalid iCCP chunk")
+	}
+	decompressor, err := zlib.NewReader(bytes.NewReader(data[name+2:]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid iCCP chunk: %w", err)
+	}
+	defer decompressor.Close()
+	return io.ReadAll(decompressor)
+}
+
+// writePNGChunks inserts the profile's chunks right after IHDR, ahead of any
+// palette or image data as PNG requires.
+func (profile ColorProfile) writePNGChunks(w io.Writer, encoded []byte) error {
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+
+	if _, err := w.Write(encoded[:ihdrEnd]); err != nil {
+		return err
+	}
+	for _, chunk := range profile.Chunks {
+		crc := crc32.NewIEEE()
+		crc.Write([]byte(chunk.Type))
+		crc.Write(chunk.Data)
+
+		var buf bytes.Buffer
+		binary.Write(&buf, binary.BigEndian, uint32(len(chunk.Data)))
+		buf.WriteString(chunk.Type)
+		buf.Write(chunk.Data)
+		binary.Write(&buf, binary.BigEndian, crc.Sum32())
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(encoded[ihdrEnd:])
+	return err
+}
+
+var srgbProfile = ColorProfile{Chunks: []pngChunk{{"sRGB", []byte{0}}}}
+
+// toSRGB converts a gamma-tagged sheet to sRGB values and tags it sRGB. An
+// ICC profile would need a color management engine to convert, so only
+// profiles that already describe sRGB are accepted.
+func toSRGB(img image.Image, profile ColorProfile) (image.Image, ColorProfile, error) {
+	if _, ok := profile.chunk("sRGB"); ok {
+		return img, srgbProfile, nil
+	}
+
+	icc, err := profile.icc()
+	if err != nil {
+		return nil, ColorProfile{}, err
+	}
+	if icc != nil {
+		if !bytes.Contains(icc, []byte("sRGB")) {
+			return nil, ColorProfile{}, fmt.Errorf("cannot convert an embedded ICC profile to sRGB, drop --srgb to carry it through instead")
+		}
+		return img, srgbProfile, nil
+	}
+
+	data, ok := profile.chunk("gAMA")
+	if !ok || len(data) != 4 || binary.BigEndian.Uint32(data) == 0 {
+		return img, srgbProfile, nil
+	}
+
+	// gAMA holds the encoding exponent times 100000, so decoding raises to its inverse.
+	exponent := 100000 / float64(binary.BigEndian.Uint32(data))
+	convert := func(v float64) float64 {
+		linear := math.Pow(v, exponent)
+		if linear <= 0.0031308 {
+			return linear * 12.92
+		}
+		return 1.055*math.Pow(linear, 1/2.4) - 0.055
+	}
+
+	var lut8 [256]uint8
+	for i := range lut8 {
+		lut8[i] = uint8(math.Round(convert(float64(i)/255) * 255))
+	}
+	lut16 := func(v uint16) uint16 {
+		return uint16(math.Round(convert(float64(v)/65535) * 65535))
+	}
+
+	bounds := img.Bounds()
+	switch src := img.(type) {
+	case *image.Gray:
+		gray := image.NewGray(bounds)
+		for i, v := range src.Pix {
+			gray.Pix[i] = lut8[v]
+		}
+		return gray, srgbProfile, nil
+	case *image.Gray16:
+		gray := image.NewGray16(bounds)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				gray.SetGray16(x, y, color.Gray16{lut16(src.Gray16At(x, y).Y)})
+			}
+		}
+		return gray, srgbProfile, nil
+	case *image.NRGBA64, *image.RGBA64:
+		deep := image.NewNRGBA64(bounds)
+		draw.Draw(deep, bounds, img, bounds.Min, draw.Src)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := deep.NRGBA64At(x, y)
+				deep.SetNRGBA64(x, y, color.NRGBA64{lut16(c.R), lut16(c.G), lut16(c.B), c.A})
+			}
+		}
+		return deep, srgbProfile, nil
+	}
+
+	nrgba := image.NewNRGBA(bounds)
+	draw.Draw(nrgba, bounds, img, bounds.Min, draw.Src)
+	for i := 0; i < len(nrgba.Pix); i += 4 {
+		nrgba.Pix[i], nrgba.Pix[i+1], nrgba.Pix[i+2] = lut8[nrgba.Pix[i]], lut8[nrgba.Pix[i+1]], lut8[nrgba.Pix[i+2]]
+	}
+	return nrgba, srgbProfile, nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+type SceneSprite struct {
+	Frame    string   `json:"frame"`
+	X        float64  `json:"x"`
+	Y        float64  `json:"y"`
+	Scale    *float64 `json:"scale"`
+	ScaleX   *float64 `json:"scaleX"`
+	ScaleY   *float64 `json:"scaleY"`
+	Rotation float64  `json:"rotation"`
+	OriginX  *float64 `json:"originX"`
+	OriginY  *float64 `json:"originY"`
+	FlipX    bool     `json:"flipX"`
+	FlipY    bool     `json:"flipY"`
+	Alpha    *float64 `json:"alpha"`
+}
+
+type Scene struct {
+	Atlas      string        `json:"atlas"`
+	Width      int           `json:"width"`
+	Height     int           `json:"height"`
+	Background string        `json:"background"`
+	PixelArt   bool          `json:"pixelArt"`
+	Sprites    []SceneSprite `json:"sprites"`
+}
+
+type Composer struct {
+	*Atlas
+}
+
+func parseColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	var c color.NRGBA
+	if len(hex) != 8 {
+		return c, fmt.Errorf("invalid color %q, expected #rrggbb or #rrggbbaa", s)
+	}
+	if _, err := fmt.Sscanf(hex, "%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A); err != nil {
+		return c, fmt.Errorf("invalid color %q, expected #rrggbb or #rrggbbaa", s)
+	}
+
+	return c, nil
+}
+
+func optional(value *float64, fallback float64) float64 {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
+
+func loadScene(path string) (Scene, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scene{}, fmt.Errorf("failed to read scene: %w", err)
+	}
+
+	var scene Scene
+	if err := json.Unmarshal(data, &scene); err != nil {
+		return Scene{}, fmt.Errorf("invalid scene JSON: %w", err)
+	}
+
+	if scene.Width <= 0 || scene.Height <= 0 {
+		return Scene{}, fmt.Errorf("invalid scene: width and height must be positive")
+	}
+
+	return scene, nil
+}
+
+func newComposer(pack Pack, inputDir string) *Composer {
+	return &Composer{Atlas: newAtlas(pack, inputDir)}
+}
+
+func (composer *Composer) sprite(name string) (*image.NRGBA, error) {
+	return composer.Frame(name).Image(context.Background())
+}
+
+func (composer *Composer) compose(scene Scene) (*image.RGBA, error) {
+	canvas, err := newCanvas(scene.Width, scene.Height)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scene: %w", err)
+	}
+
+	if scene.Background != "" {
+		background, err := parseColor(scene.Background)
+		if err != nil {
+			return nil, err
+		}
+		draw.Draw(canvas, canvas.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	}
+
+	var interpolator draw.Interpolator = draw.BiLinear
+	if scene.PixelArt {
+		interpolator = draw.NearestNeighbor
+	}
+
+	for _, placed := range scene.Sprites {
+		sprite, err := composer.sprite(placed.Frame)
+		if err != nil {
+			return nil, err
+		}
+
+		bounds := sprite.Bounds()
+		scale := optional(placed.Scale, 1)
+		sx, sy := optional(placed.ScaleX, scale), optional(placed.ScaleY, scale)
+		if placed.FlipX {
+			sx = -sx
+		}
+		if placed.FlipY {
+			sy = -sy
+		}
+
+		// Origins default to the sprite center, matching Phaser game objects.
+		ox := optional(placed.OriginX, 0.5) * float64(bounds.Dx())
+		oy := optional(placed.OriginY, 0.5) * float64(bounds.Dy())
+		sin, cos := math.Sincos(placed.Rotation * math.Pi / 180)
+
+		a, b := cos*sx, -sin*sy
+		d, e := sin*sx, cos*sy
+		transform := f64.Aff3{
+			a, b, placed.X - (a*ox + b*oy),
+			d, e, placed.Y - (d*ox + e*oy),
+		}
+
+		var opts *draw.Options
+		if placed.Alpha != nil {
+			alpha := uint16(math.Max(0, math.Min(1, *placed.Alpha)) * 0xffff)
+			opts = &draw.Options{SrcMask: image.NewUniform(color.Alpha16{A: alpha})}
+		}
+
+		interpolator.Transform(canvas, transform, sprite, bounds, draw.Over, opts)
+	}
+
+	return canvas, nil
+}
+
+func newComposeCmd() *cobra.Command {
+	var outputPath string
+	var atlasPath string
+
+	var composeCmd = &cobra.Command{
+		Use:   "compose <scene>",
+		Short: "Compose an image from atlas frames described by a scene file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var scenePath = args[0]
+			format, _ := cmd.Flags().GetString("format")
+
+			scene, err := loadScene(scenePath)
+			if err != nil {
+				return err
+			}
+
+			if atlasPath == "" {
+				if scene.Atlas == "" {
+					return fmt.Errorf("scene does not name an atlas, pass one with --atlas")
+				}
+				atlasPath = filepath.Join(filepath.Dir(scenePath), scene.Atlas)
+			}
+
+			pack, err := loadPack(atlasPath, format)
+			if err != nil {
+				return err
+			}
+
+			if outputPath == "" {
+				outputPath = strings.TrimSuffix(scenePath, filepath.Ext(scenePath)) + ".png"
+			}
+
+			canvas, err := newComposer(pack, filepath.Dir(atlasPath)).compose(scene)
+			if err != nil {
+				return err
+			}
+
+			outputFile, err := os.Create(outputPath)
+			if err != nil {
+				return fmt.Errorf("failed to open output file: %w", err)
+			}
+
+			if err = png.Encode(outputFile, canvas); err != nil {
+				outputFile.Close()
+				return fmt.Errorf("failed to encode scene as png: %w", err)
+			}
+
+			if err = outputFile.Close(); err != nil {
+				return fmt.Errorf("failed to write output file: %w", err)
+			}
+
+			logf("[info] composed %d sprites into %s\n", len(scene.Sprites), outputPath)
+
+			return nil
+		},
+	}
+
+	composeCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output image")
+	composeCmd.Flags().StringVarP(&atlasPath, "atlas", "a", "", "Atlas to draw frames from, overriding the scene's atlas")
+
+	return composeCmd
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"maps"
+	"os"
+	"path"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+type ReservedRegion struct {
+	Page   int `yaml:"page"`
+	X      int `yaml:"x"`
+	Y      int `yaml:"y"`
+	Width  int `yaml:"w"`
+	Height int `yaml:"h"`
+}
+
+func (region ReservedRegion) Rect() image.Rectangle {
+	return image.Rect(region.X, region.Y, region.X+region.Width, region.Y+region.Height)
+}
+
+// PackConstraints are keyed by frame name patterns as understood by path.Match.
+// NoRotate always holds, since the packer never rotates frames, so its
+// patterns are only checked.
+type PackConstraints struct {
+	Pages    map[string]int   `yaml:"pages"`
+	Padding  map[string]int   `yaml:"padding"`
+	NoRotate []string         `yaml:"noRotate"`
+	Reserved []ReservedRegion `yaml:"reserved"`
+}
+
+func loadPackConstraints(constraintsPath string) (PackConstraints, error) {
+	data, err := os.ReadFile(constraintsPath)
+	if err != nil {
+		return PackConstraints{}, fmt.Errorf("failed to read constraints: %w", err)
+	}
+
+	var constraints PackConstraints
+	if err := yaml.Unmarshal(data, &constraints); err != nil {
+		return PackConstraints{}, fmt.Errorf("invalid constraints: %w", err)
+	}
+
+	for _, region := range constraints.Reserved {
+		if region.Page < 0 || region.Width <= 0 || region.Height <= 0 {
+			return PackConstraints{}, fmt.Errorf("invalid reserved region %+v, page must not be negative and size must be positive", region)
+		}
+	}
+
+	return constraints, nil
+}
+
+// matchConstraint returns the value of the first pattern matching name, in
+// sorted pattern order so overlapping patterns resolve the same way every run.
+func matchConstraint(patterns map[string]int, name string) (int, bool, error) {
+	for _, pattern := range slices.Sorted(maps.Keys(patterns)) {
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid frame pattern %q: %w", pattern, err)
+		}
+		if matched {
+			return patterns[pattern], true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// apply pins and pads sprites, warning about patterns that match nothing.
+func (constraints PackConstraints) apply(sprites []PackSprite) ([]PackSprite, error) {
+	constrained := make([]PackSprite, len(sprites))
+
+	for i, sprite := range sprites {
+		page, ok, err := matchConstraint(constraints.Pages, sprite.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if page < 0 {
+				return nil, fmt.Errorf("invalid page %d for frame %q", page, sprite.Name)
+			}
+			sprite.Page = &page
+		}
+
+		padding, ok, err := matchConstraint(constraints.Padding, sprite.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			sprite.Padding = padding
+		}
+
+		constrained[i] = sprite
+	}
+
+	patterns := slices.Concat(slices.Sorted(maps.Keys(constraints.Pages)), slices.Sorted(maps.Keys(constraints.Padding)), constraints.NoRotate)
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid frame pattern %q: %w", pattern, err)
+		}
+
+		matched := slices.ContainsFunc(sprites, func(sprite PackSprite) bool {
+			ok, _ := path.Match(pattern, sprite.Name)
+			return ok
+		})
+		if !matched {
+			logf("[warn] constraint pattern %q matches no frames\n", pattern)
+		}
+	}
+
+	return constrained, nil
+}
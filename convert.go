@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func findEncoder(name string) (AtlasFormat, error) {
+	var names []string
+	for _, format := range atlasFormats {
+		if format.Encode == nil {
+			continue
+		}
+		if format.Name == name {
+			return format, nil
+		}
+		names = append(names, format.Name)
+	}
+
+	return AtlasFormat{}, fmt.Errorf("cannot convert to %q, expected one of: %s", name, strings.Join(names, ", "))
+}
+
+// convertPack encodes pack as format, splitting it into one file per sheet
+// when the format holds a single image. Split JSON files list each other in
+// related_multi_packs so they still load as one pack.
+func convertPack(pack Pack, format AtlasFormat, outputPath string) (map[string][]byte, error) {
+	if format.Name == "multiatlas" || len(pack.Sheets) == 1 {
+		data, err := format.Encode(pack)
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{outputPath: data}, nil
+	}
+
+	base := strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+	paths := make([]string, len(pack.Sheets))
+	for i := range pack.Sheets {
+		paths[i] = fmt.Sprintf("%s-%d%s", base, i, filepath.Ext(outputPath))
+	}
+
+	files := make(map[string][]byte, len(pack.Sheets))
+	for i, sh := range pack.Sheets {
+		page := Pack{Meta: pack.Meta, Sheets: []Sheet{sh}}
+		for j, related := range paths {
+			if j != i {
+				page.Related = append(page.Related, filepath.Base(related))
+			}
+		}
+
+		data, err := format.Encode(page)
+		if err != nil {
+			return nil, err
+		}
+		files[paths[i]] = data
+	}
+
+	return files, nil
+}
+
+func newConvertCmd() *cobra.Command {
+	var outputPath string
+	var to string
+	var autoPivot string = autoPivotModes[0]
+
+	var convertCmd = &cobra.Command{
+		Use:   "convert <path>",
+		Short: "Rewrite atlas metadata in another format, leaving sheet images untouched",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var path = args[0]
+			format, _ := cmd.Flags().GetString("format")
+
+			target, err := findEncoder(to)
+			if err != nil {
+				return err
+			}
+
+			if !slices.Contains(autoPivotModes, autoPivot) {
+				return fmt.Errorf("invalid auto-pivot %q, expected none, center, bottom-center, or opaque-centroid", autoPivot)
+			}
+
+			pack, err := loadPack(path, format)
+			if err != nil {
+				return err
+			}
+
+			if autoPivot != "none" {
+				pivoted, computed, err := autoPivots(pack, filepath.Dir(path), autoPivot)
+				if err != nil {
+					return err
+				}
+				logf("[info] computed %d pivots from sprite pixels\n", computed)
+				pack = pivoted
+			}
+
+			if outputPath == "" {
+				outputPath = strings.TrimSuffix(path, filepath.Ext(path)) + "-" + target.Name + target.Extension
+			}
+
+			// Sheet images stay where they are, so point at them from the output.
+			relDir, err := filepath.Rel(filepath.Dir(outputPath), filepath.Dir(path))
+			if err != nil {
+				return fmt.Errorf("failed to resolve sheet images: %w", err)
+			}
+			for i := range pack.Sheets {
+				pack.Sheets[i].Image = filepath.ToSlash(filepath.Join(relDir, pack.Sheets[i].Image))
+			}
+
+			files, err := convertPack(pack, target, outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+
+			for _, filePath := range slices.Sorted(maps.Keys(files)) {
+				if err := os.WriteFile(filePath, files[filePath], 0o644); err != nil {
+					return fmt.Errorf("failed to write atlas: %w", err)
+				}
+				logf("[info] wrote %s\n", filePath)
+			}
+
+			return nil
+		},
+	}
+
+	convertCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output atlas file")
+	convertCmd.Flags().StringVarP(&to, "to", "t", "", "Target format: multiatlas, hash, array, or xml")
+	convertCmd.Flags().StringVarP(&autoPivot, "auto-pivot", "", autoPivot, "Pivot for frames without one, from their opaque pixels: none, center, bottom-center, or opaque-centroid")
+	convertCmd.MarkFlagRequired("to")
+
+	return convertCmd
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func loadLabelMap(mapPath string) (map[string]string, error) {
+	data, err := os.ReadFile(mapPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read label map: %w", err)
+	}
+
+	var labels map[string]string
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("invalid label map: %w", err)
+	}
+
+	return labels, nil
+}
+
+func (unpacker Unpacker) datasetLabel(texture Texture) string {
+	if label, ok := unpacker.Labels[texture.FileName]; ok {
+		return label
+	}
+
+	if label := animationName(texture.FileName); label != "" {
+		return label
+	}
+
+	return "unlabeled"
+}
+
+// animationName returns the folder of a frame, or without one the animation
+// name, e.g. walk_03 -> walk.
+func animationName(name string) string {
+	if dir := path.Dir(name); dir != "." {
+		return dir
+	}
+
+	name = strings.TrimRight(name, "0123456789")
+	return strings.TrimRight(name, "_-. ")
+}
+
+func (unpacker Unpacker) datasetPath(texture Texture) string {
+	name := strings.ReplaceAll(unpacker.outputName(texture), "/", "_") + unpacker.encoder().Extension()
+	label := filepath.FromSlash(unpacker.datasetLabel(texture))
+
+	return filepath.Join(unpacker.OutputDir, label, name)
+}
+
+func (unpacker Unpacker) writeDatasetIndex() error {
+	indexPath := filepath.Join(unpacker.OutputDir, "index.csv")
+	if err := unpacker.checkOutput(indexPath); err != nil {
+		return err
+	}
+
+	indexFile, err := os.Create(indexPath)
+	if err != nil {
+		return fmt.Errorf("failed to open dataset index: %w", err)
+	}
+
+	writer := csv.NewWriter(indexFile)
+	writer.Write([]string{"path", "label", "frame", "sheet", "width", "height", "augmentation"})
+
+	writeRow := func(sh Sheet, tex Texture, outputPath, augmentation string) error {
+		relPath, err := filepath.Rel(unpacker.OutputDir, outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve dataset path: %w", err)
+		}
+
+		return writer.Write([]string{
+			filepath.ToSlash(relPath),
+			unpacker.datasetLabel(tex),
+			tex.FileName,
+			sh.Image,
+			strconv.Itoa(tex.SourceSize.Width),
+			strconv.Itoa(tex.SourceSize.Height),
+			augmentation,
+		})
+	}
+
+	// Only frames on disk get rows, leaving out skipped and failed ones.
+	for _, sh := range unpacker.Sheets {
+		for _, tex := range sh.Textures {
+			if _, ok := unpacker.datasetFrames.Load(keyOf(tex)); !ok {
+				continue
+			}
+			if err := writeRow(sh, tex, unpacker.datasetPath(tex), ""); err != nil {
+				indexFile.Close()
+				return err
+			}
+
+			for i := range unpacker.Augment.Variants {
+				params := unpacker.Augment.params(tex, i)
+				if err := writeRow(sh, tex, unpacker.augmentPath(tex, i), params.String()); err != nil {
+					indexFile.Close()
+					return err
+				}
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		indexFile.Close()
+		return fmt.Errorf("failed to encode dataset index: %w", err)
+	}
+
+	if err := indexFile.Close(); err != nil {
+		return fmt.Errorf("failed to write dataset index: %w", err)
+	}
+
+	return nil
+}
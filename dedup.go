@@ -0,0 +1,225 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+var dedupModes = []string{"none", "hardlink", "symlink", "copy"}
+
+type dedupEntry struct {
+	path  string
+	ready chan struct{}
+	size  int64
+	ok    bool
+	links []string
+}
+
+// A Deduper writes each distinct sprite once and links or copies later
+// sprites with the same pixels to that file, so packs that repeat frames
+// under many names do not store them many times.
+type Deduper struct {
+	Mode       string
+	mu         sync.Mutex
+	entries    map[string]*dedupEntry
+	duplicates int
+	saved      int64
+}
+
+func newDeduper(mode string) *Deduper {
+	return &Deduper{Mode: mode, entries: make(map[string]*dedupEntry)}
+}
+
+// dedupKey identifies a sprite by its pixels at full depth and the color
+// profile it is written with, since either changes the file.
+func dedupKey(sprite image.Image, profile ColorProfile) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%T\n", sprite)
+	for _, chunk := range profile.Chunks {
+		fmt.Fprintf(hash, "%s %d\n", chunk.Type, len(chunk.Data))
+		hash.Write(chunk.Data)
+	}
+
+	bounds := sprite.Bounds()
+	switch img := sprite.(type) {
+	case *FloatImage:
+		fmt.Fprintf(hash, "%dx%d\n", bounds.Dx(), bounds.Dy())
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				binary.Write(hash, binary.LittleEndian, img.FloatAt(x, y))
+			}
+		}
+	case *image.NRGBA64, *image.Gray16:
+		fmt.Fprintf(hash, "%dx%d\n", bounds.Dx(), bounds.Dy())
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r, g, b, a := img.At(x, y).RGBA()
+				binary.Write(hash, binary.LittleEndian, [4]uint16{uint16(r), uint16(g), uint16(b), uint16(a)})
+			}
+		}
+	default:
+		hash.Write([]byte(pixelHash(sprite)))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// claim returns the entry for key and whether the caller is the first to
+// claim it, and so has to write the file the others link to.
+func (dedup *Deduper) claim(key, path string) (*dedupEntry, bool) {
+	dedup.mu.Lock()
+	defer dedup.mu.Unlock()
+
+	if entry, ok := dedup.entries[key]; ok {
+		return entry, false
+	}
+	entry := &dedupEntry{path: path, ready: make(chan struct{})}
+	dedup.entries[key] = entry
+	return entry, true
+}
+
+// write writes sprite to path through create, unless a sprite with the same
+// pixels was already written, in which case path becomes a link to or copy
+// of that file.
+func (dedup *Deduper) write(key, path string, create func() (int64, error)) (int64, error) {
+	entry, first := dedup.claim(key, path)
+	if first {
+		n, err := create()
+		entry.size, entry.ok = n, err == nil
+		close(entry.ready)
+		return n, err
+	}
+
+	<-entry.ready
+	// When the first write failed, which it has reported, or was to this very
+	// path, there is nothing to link to.
+	if !entry.ok || entry.path == path {
+		return create()
+	}
+
+	var written int64
+	switch dedup.Mode {
+	case "hardlink":
+		if err := os.Link(entry.path, path); err != nil {
+			return 0, fmt.Errorf("failed to hardlink %s, try --dedup copy: %w", path, err)
+		}
+	case "symlink":
+		target, err := filepath.Rel(filepath.Dir(path), entry.path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to link %s: %w", path, err)
+		}
+		if err := os.Symlink(target, path); err != nil {
+			return 0, fmt.Errorf("failed to symlink %s, try --dedup copy: %w", path, err)
+		}
+	case "copy":
+		n, err := copyFile(entry.path, path)
+		if err != nil {
+			return 0, err
+		}
+		written = n
+	}
+
+	dedup.mu.Lock()
+	dedup.duplicates++
+	dedup.saved += entry.size - written
+	entry.links = append(entry.links, path)
+	dedup.mu.Unlock()
+	return written, nil
+}
+
+// settle makes the first path of each sprite in sort order the file its
+// symlinks point to, rather than whichever path a worker happened to write
+// first, so every run leaves the same links. Hardlinks and copies come out
+// the same either way.
+func (dedup *Deduper) settle() error {
+	if dedup.Mode != "symlink" {
+		return nil
+	}
+
+	for _, entry := range dedup.entries {
+		if !entry.ok || len(entry.links) == 0 {
+			continue
+		}
+		canonical := slices.Min(entry.links)
+		if canonical > entry.path {
+			continue
+		}
+
+		// canonical is a link to the file, so the file replaces it.
+		if err := os.Rename(entry.path, canonical); err != nil {
+			return withExitCode(exitOutput, fmt.Errorf("failed to move %s: %w", entry.path, err))
+		}
+		links := append(slices.DeleteFunc(entry.links, func(link string) bool { return link == canonical }), entry.path)
+		for _, link := range links {
+			if err := os.Remove(link); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return withExitCode(exitOutput, fmt.Errorf("failed to relink %s: %w", link, err))
+			}
+			target, err := filepath.Rel(filepath.Dir(link), canonical)
+			if err != nil {
+				return fmt.Errorf("failed to relink %s: %w", link, err)
+			}
+			if err := os.Symlink(target, link); err != nil {
+				return withExitCode(exitOutput, fmt.Errorf("failed to relink %s: %w", link, err))
+			}
+		}
+		entry.path, entry.links = canonical, links
+	}
+	return nil
+}
+
+func copyFile(src, dst string) (int64, error) {
+	input, err := os.Open(src)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer input.Close()
+
+	output, err := os.Create(dst)
+	if err != nil {
+		return 0, withExitCode(exitOutput, fmt.Errorf("failed to open output file: %w", err))
+	}
+	n, err := io.Copy(output, input)
+	if err != nil {
+		output.Close()
+		return 0, fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	if err := output.Close(); err != nil {
+		return 0, withExitCode(exitOutput, fmt.Errorf("failed to write output file: %w", err))
+	}
+	return n, nil
+}
+
+// unlink removes what an earlier run left at path before a deduplicated
+// write, so a link from that run is replaced rather than written through.
+func (unpacker Unpacker) unlink(path string) error {
+	if _, err := os.Lstat(path); err != nil {
+		return nil
+	}
+	if err := unpacker.checkOutput(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+func (dedup *Deduper) report() {
+	if dedup.duplicates == 0 {
+		return
+	}
+	if dedup.Mode == "copy" {
+		logf("[info] copied %d duplicate sprites instead of encoding them again\n", dedup.duplicates)
+		return
+	}
+	logf("[info] %sed %d duplicate sprites, saving %s\n", dedup.Mode, dedup.duplicates, formatBytes(dedup.saved))
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+type FrameDiff struct {
+	Frame  string
+	Status string
+	Reason string
+}
+
+func frameNames(pack Pack) []string {
+	var names []string
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			names = append(names, tex.FileName)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// pixelDelta is the largest channel difference between two pixels, with
+// fully transparent pixels equal regardless of their color.
+func pixelDelta(a, b color.NRGBA) uint8 {
+	if a.A == 0 && b.A == 0 {
+		return 0
+	}
+
+	delta := uint8(0)
+	for _, pair := range [][2]uint8{{a.R, b.R}, {a.G, b.G}, {a.B, b.B}, {a.A, b.A}} {
+		delta = max(delta, max(pair[0], pair[1])-min(pair[0], pair[1]))
+	}
+	return delta
+}
+
+// heatmap draws the new frame dimmed to grayscale with every difference in
+// red, amplified so one-step color changes and one-pixel shifts stand out.
+// Frames that changed size are compared over both areas.
+func heatmap(before, after *image.NRGBA, amplify int) *image.NRGBA {
+	size := before.Bounds().Size()
+	size.X = max(size.X, after.Bounds().Dx())
+	size.Y = max(size.Y, after.Bounds().Dy())
+
+	heat := image.NewNRGBA(image.Rect(0, 0, size.X, size.Y))
+	for y := range size.Y {
+		for x := range size.X {
+			a := before.NRGBAAt(before.Rect.Min.X+x, before.Rect.Min.Y+y)
+			b := after.NRGBAAt(after.Rect.Min.X+x, after.Rect.Min.Y+y)
+
+			// The background stays below 64 so any difference reads as red.
+			gray := uint8((int(b.R)*299 + int(b.G)*587 + int(b.B)*114) / 1000 * int(b.A) / 255 / 4)
+			if delta := int(pixelDelta(a, b)); delta > 0 {
+				heat.SetNRGBA(x, y, color.NRGBA{uint8(min(255, 64+delta*amplify)), 0, 0, 255})
+			} else {
+				heat.SetNRGBA(x, y, color.NRGBA{gray, gray, gray, 255})
+			}
+		}
+	}
+
+	return heat
+}
+
+func diffPacks(before, after Pack, beforeDir, afterDir, heatmapDir string, amplify int) ([]FrameDiff, error) {
+	oldFrames, newFrames := newComposer(before, beforeDir), newComposer(after, afterDir)
+	heatmaps := Unpacker{OutputDir: heatmapDir}
+
+	var diffs []FrameDiff
+	for _, name := range frameNames(before) {
+		if _, ok := newFrames.frames[name]; !ok {
+			diffs = append(diffs, FrameDiff{Frame: name, Status: "removed"})
+		}
+	}
+
+	for _, name := range frameNames(after) {
+		if _, ok := oldFrames.frames[name]; !ok {
+			diffs = append(diffs, FrameDiff{Frame: name, Status: "added"})
+			continue
+		}
+
+		a, err := oldFrames.sprite(name)
+		if err != nil {
+			return nil, err
+		}
+		b, err := newFrames.sprite(name)
+		if err != nil {
+			return nil, err
+		}
+
+		_, reason := diffImages(a, b)
+		if reason == "" {
+			diffs = append(diffs, FrameDiff{Frame: name, Status: "same"})
+			continue
+		}
+		diffs = append(diffs, FrameDiff{Frame: name, Status: "changed", Reason: reason})
+
+		if heatmapDir != "" {
+			outputPath := filepath.Join(heatmapDir, filepath.FromSlash(name)+".png")
+			if _, err := heatmaps.writeSprite(outputPath, heatmap(a, b, amplify)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return diffs, nil
+}
+
+func newDiffCmd() *cobra.Command {
+	var heatmapDir string
+	var amplify int = 16
+
+	var diffCmd = &cobra.Command{
+		Use:   "diff <old> <new>",
+		Short: "Compare two versions of an atlas frame by frame",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+
+			if amplify < 1 {
+				return fmt.Errorf("invalid amplify %d, must be at least 1", amplify)
+			}
+
+			before, err := loadPack(args[0], format)
+			if err != nil {
+				return err
+			}
+			after, err := loadPack(args[1], format)
+			if err != nil {
+				return err
+			}
+
+			if heatmapDir != "" {
+				logf("[info] writing heatmaps to %s\n", heatmapDir)
+			}
+
+			diffs, err := diffPacks(before, after, filepath.Dir(args[0]), filepath.Dir(args[1]), heatmapDir, amplify)
+			if err != nil {
+				return err
+			}
+
+			counts := make(map[string]int)
+			for _, diff := range diffs {
+				counts[diff.Status]++
+				if porcelainOut != nil {
+					if diff.Status != "same" {
+						porcelainOut.record("diff", diff.Status, diff.Frame, diff.Reason)
+					}
+					continue
+				}
+				switch diff.Status {
+				case "changed":
+					fmt.Printf("changed  %s: %s\n", diff.Frame, diff.Reason)
+				case "added", "removed":
+					fmt.Printf("%-7s  %s\n", diff.Status, diff.Frame)
+				}
+			}
+
+			logf("[info] %d changed, %d added, %d removed, %d unchanged\n",
+				counts["changed"], counts["added"], counts["removed"], counts["same"])
+
+			return nil
+		},
+	}
+
+	diffCmd.Flags().StringVarP(&heatmapDir, "heatmap", "", "", "Write a difference heatmap per changed frame to this directory")
+	diffCmd.Flags().IntVarP(&amplify, "amplify", "", amplify, "Heatmap amplification of each channel difference")
+
+	return diffCmd
+}
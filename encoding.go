@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/gen2brain/avif"
+	_ "github.com/xfmoulet/qoi"
+	"golang.org/x/image/bmp"
+)
+
+// A SpriteEncoder writes extracted frames in one image format.
+type SpriteEncoder interface {
+	Extension() string
+	Encode(w io.Writer, img image.Image) error
+}
+
+// SpriteEncoding selects a SpriteEncoder and its settings, which each format
+// reads as it needs.
+type SpriteEncoding struct {
+	Format         string
+	Quality        int
+	Speed          int
+	PNGCompression string
+	PNGBufferPool  bool
+	Colors         int
+}
+
+var pngCompressions = map[string]png.CompressionLevel{
+	"default": png.DefaultCompression,
+	"none":    png.NoCompression,
+	"fast":    png.BestSpeed,
+	"best":    png.BestCompression,
+}
+
+var spriteFormats = []string{"png", "webp", "jpeg", "avif", "bmp", "tga", "qoi", "exr", "pfm"}
+
+// quality returns the requested quality, or the format's own default when none was.
+func (encoding SpriteEncoding) quality(fallback int) (int, error) {
+	if encoding.Quality == 0 {
+		return fallback, nil
+	}
+	if encoding.Quality < 1 || encoding.Quality > 100 {
+		return 0, fmt.Errorf("invalid quality %d, expected 1 to 100", encoding.Quality)
+	}
+	return encoding.Quality, nil
+}
+
+func newSpriteEncoder(encoding SpriteEncoding) (SpriteEncoder, error) {
+	if encoding.Colors != 0 {
+		if encoding.Format != "png" {
+			return nil, fmt.Errorf("--quantize needs png output, not %s", encoding.Format)
+		}
+		if encoding.Colors < 2 || encoding.Colors > 256 {
+			return nil, fmt.Errorf("invalid quantize %d, expected 2 to 256 colors", encoding.Colors)
+		}
+	}
+
+	switch encoding.Format {
+	case "png":
+		level, ok := pngCompressions[encoding.PNGCompression]
+		if !ok {
+			return nil, fmt.Errorf("invalid png compression %q, expected default, none, fast, or best", encoding.PNGCompression)
+		}
+		sprites := pngSprites{Compression: level, Colors: encoding.Colors}
+		if encoding.PNGBufferPool {
+			sprites.BufferPool = &pngBufferPool{}
+		}
+		return sprites, nil
+	case "webp":
+		return webpSprites{}, nil
+	case "jpeg", "jpg":
+		quality, err := encoding.quality(90)
+		if err != nil {
+			return nil, err
+		}
+		return jpegSprites{quality}, nil
+	case "avif":
+		quality, err := encoding.quality(60)
+		if err != nil {
+			return nil, err
+		}
+		if encoding.Speed < 0 || encoding.Speed > 10 {
+			return nil, fmt.Errorf("invalid speed %d, expected 0 to 10", encoding.Speed)
+		}
+		return avifSprites{quality, encoding.Speed}, nil
+	case "bmp":
+		return bmpSprites{}, nil
+	case "tga":
+		return tgaSprites{}, nil
+	case "qoi":
+		return qoiSprites{}, nil
+	case "exr":
+		return exrSprites{}, nil
+	case "pfm":
+		return pfmSprites{}, nil
+	}
+
+	return nil, fmt.Errorf("invalid output format %q, expected %s", encoding.Format, strings.Join(spriteFormats, ", "))
+}
+
+// A profileEncoder embeds a sheet's color profile in the sprites it writes.
+type profileEncoder interface {
+	EncodeProfile(w io.Writer, img image.Image, profile ColorProfile) error
+}
+
+// pngSprites writes an indexed PNG of at most Colors colors when it is set.
+type pngSprites struct {
+	Compression png.CompressionLevel
+	BufferPool  png.EncoderBufferPool
+	Colors      int
+}
+
+func (pngSprites) Extension() string { return ".png" }
+
+func (sprites pngSprites) Encode(w io.Writer, img image.Image) error {
+	if sprites.Colors > 0 {
+		img = quantize(img, sprites.Colors)
+	}
+	encoder := png.Encoder{CompressionLevel: sprites.Compression, BufferPool: sprites.BufferPool}
+	return encoder.Encode(w, img)
+}
+
+func (sprites pngSprites) EncodeProfile(w io.Writer, img image.Image, profile ColorProfile) error {
+	var encoded bytes.Buffer
+	if err := sprites.Encode(&encoded, img); err != nil {
+		return err
+	}
+	return profile.writePNGChunks(w, encoded.Bytes())
+}
+
+// pngBufferPool lets every worker reuse the encoder's zlib writer and row
+// buffers instead of allocating them for each frame.
+type pngBufferPool struct {
+	pool sync.Pool
+}
+
+func (pool *pngBufferPool) Get() *png.EncoderBuffer {
+	buffer, _ := pool.pool.Get().(*png.EncoderBuffer)
+	return buffer
+}
+
+func (pool *pngBufferPool) Put(buffer *png.EncoderBuffer) {
+	pool.pool.Put(buffer)
+}
+
+// webpSprites writes lossless WebP, which has no quality setting.
+type webpSprites struct{}
+
+func (webpSprites) Extension() string { return ".webp" }
+
+func (webpSprites) Encode(w io.Writer, img image.Image) error {
+	return nativewebp.Encode(w, img, nil)
+}
+
+// jpegSprites drops transparency, so it suits opaque frames only.
+type jpegSprites struct {
+	Quality int
+}
+
+func (jpegSprites) Extension() string { return ".jpg" }
+
+func (sprites jpegSprites) Encode(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: sprites.Quality})
+}
+
+// avifSprites encodes lossy AVIF through libavif compiled to WebAssembly, so
+// no C toolchain or system library is needed.
+type avifSprites struct {
+	Quality int
+	Speed   int
+}
+
+func (avifSprites) Extension() string { return ".avif" }
+
+func (sprites avifSprites) Encode(w io.Writer, img image.Image) error {
+	return avif.Encode(w, img, avif.Options{
+		Quality:           sprites.Quality,
+		QualityAlpha:      sprites.Quality,
+		Speed:             sprites.Speed,
+		ChromaSubsampling: image.YCbCrSubsampleRatio420,
+	})
+}
+
+// bmpSprites keeps alpha in 32-bit pixels, though many BMP readers ignore it.
+type bmpSprites struct{}
+
+func (bmpSprites) Extension() string { return ".bmp" }
+
+func (bmpSprites) Encode(w io.Writer, img image.Image) error {
+	return bmp.Encode(w, img)
+}
+
+// qoiSprites encodes QOI by hand, since the qoi package writes premultiplied
+// colors and would corrupt semi-transparent pixels. Its decoder is still used.
+type qoiSprites struct{}
+
+func (qoiSprites) Extension() string { return ".qoi" }
+
+func (qoiSprites) Encode(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+
+	out := bufio.NewWriter(w)
+	out.WriteString("qoif")
+	binary.Write(out, binary.BigEndian, [2]uint32{uint32(bounds.Dx()), uint32(bounds.Dy())})
+	out.Write([]byte{4, 0}) // RGBA, sRGB
+
+	var index [64]color.NRGBA
+	prev := color.NRGBA{A: 255}
+	run := 0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			px := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+
+			if px == prev {
+				run++
+				if run == 62 {
+					out.WriteByte(0xc0 | byte(run-1))
+					run = 0
+				}
+				continue
+			}
+			if run > 0 {
+				out.WriteByte(0xc0 | byte(run-1))
+				run = 0
+			}
+
+			hash := (int(px.R)*3 + int(px.G)*5 + int(px.B)*7 + int(px.A)*11) % 64
+			switch {
+			case index[hash] == px:
+				out.WriteByte(byte(hash))
+			case px.A != prev.A:
+				index[hash] = px
+				out.Write([]byte{0xff, px.R, px.G, px.B, px.A})
+			default:
+				index[hash] = px
+				dr, dg, db := int8(px.R-prev.R), int8(px.G-prev.G), int8(px.B-prev.B)
+				drdg, dbdg := dr-dg, db-dg
+
+				switch {
+				case dr >= -2 && dr <= 1 && dg >= -2 && dg <= 1 && db >= -2 && db <= 1:
+					out.WriteByte(0x40 | byte(dr+2)<<4 | byte(dg+2)<<2 | byte(db+2))
+				case dg >= -32 && dg <= 31 && drdg >= -8 && drdg <= 7 && dbdg >= -8 && dbdg <= 7:
+					out.Write([]byte{0x80 | byte(dg+32), byte(drdg+8)<<4 | byte(dbdg+8)})
+				default:
+					out.Write([]byte{0xfe, px.R, px.G, px.B})
+				}
+			}
+			prev = px
+		}
+	}
+	if run > 0 {
+		out.WriteByte(0xc0 | byte(run-1))
+	}
+
+	out.Write([]byte{0, 0, 0, 0, 0, 0, 0, 1})
+	return out.Flush()
+}
+
+// tgaSprites writes uncompressed 32-bit TGA with straight alpha, the variant
+// every engine reads.
+type tgaSprites struct{}
+
+func (tgaSprites) Extension() string { return ".tga" }
+
+func (tgaSprites) Encode(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	if bounds.Dx() > 0xffff || bounds.Dy() > 0xffff {
+		return fmt.Errorf("image size %dx%d is too large for TGA", bounds.Dx(), bounds.Dy())
+	}
+
+	header := make([]byte, 18)
+	header[2] = 2 // uncompressed true color
+	header[12], header[13] = byte(bounds.Dx()), byte(bounds.Dx()>>8)
+	header[14], header[15] = byte(bounds.Dy()), byte(bounds.Dy()>>8)
+	header[16] = 32
+	header[17] = 8 | 0x20 // 8 alpha bits, rows stored top to bottom
+
+	out := bufio.NewWriter(w)
+	out.Write(header)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			out.Write([]byte{c.B, c.G, c.R, c.A})
+		}
+	}
+	return out.Flush()
+}
+
+func (unpacker Unpacker) encoder() SpriteEncoder {
+	if unpacker.Encoder == nil {
+		return pngSprites{}
+	}
+	return unpacker.Encoder
+}
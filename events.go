@@ -0,0 +1,51 @@
+package main
+
+type EventKind string
+
+const (
+	EventSheet EventKind = "sheet"
+	EventWrite EventKind = "write"
+	EventFail  EventKind = "fail"
+)
+
+// Event reports one step of an extraction to the log and porcelain output.
+// Sheet comes as each sheet starts, write for each file written, and fail for
+// each frame that failed, or the whole sheet when Frame is empty.
+type Event struct {
+	Kind   EventKind
+	Sheet  string
+	Frame  string
+	Path   string
+	Frames int
+	Bytes  int64
+	Err    error
+}
+
+func (unpacker Unpacker) emit(event Event) {
+	if porcelainOut != nil && event.Kind == EventWrite {
+		porcelainOut.record("write", event.Frame, event.Path, event.Bytes)
+	}
+	if jsonLog == nil {
+		switch event.Kind {
+		case EventSheet:
+			logAt(verbositySheets, tr("[info] extracting %d frames from %s\n"), event.Frames, event.Sheet)
+		case EventWrite:
+			logAt(verbosityFrames, tr("[info] wrote %s to %s\n"), event.Frame, event.Path)
+		case EventFail:
+			if event.Frame == "" {
+				logAt(verbositySheets, tr("[warn] failed to read %s: %v\n"), event.Sheet, event.Err)
+			} else {
+				logAt(verbositySheets, tr("[warn] failed to extract %q from %s: %v\n"), event.Frame, event.Sheet, event.Err)
+			}
+		}
+	} else {
+		switch event.Kind {
+		case EventSheet:
+			jsonLog.write(LogRecord{Event: "sheet", Sheet: event.Sheet, Frames: event.Frames})
+		case EventWrite:
+			jsonLog.write(LogRecord{Event: "write", Frame: event.Frame, Path: event.Path, Bytes: event.Bytes})
+		case EventFail:
+			jsonLog.write(LogRecord{Event: "fail", Sheet: event.Sheet, Frame: event.Frame, Error: event.Err.Error()})
+		}
+	}
+}
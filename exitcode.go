@@ -0,0 +1,37 @@
+package main
+
+import "errors"
+
+// Exit codes let scripts tell failures apart without reading the output.
+// Anything not classified below exits with exitFailure.
+const (
+	exitFailure      = 1
+	exitBadInput     = 2
+	exitMissingSheet = 3
+	exitPartial      = 4
+	exitOutput       = 5
+)
+
+// An ExitError carries the exit code of the failure it wraps. Wrapping it
+// again with %w keeps the code.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (err *ExitError) Error() string { return err.Err.Error() }
+func (err *ExitError) Unwrap() error { return err.Err }
+
+func withExitCode(code int, err error) error {
+	return &ExitError{Code: code, Err: err}
+}
+
+// exitCode returns the code err was classified with, however often it was
+// wrapped since.
+func exitCode(err error) int {
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		return exit.Code
+	}
+	return exitFailure
+}
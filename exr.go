@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"slices"
+)
+
+func init() {
+	image.RegisterFormat("exr", "\x76\x2f\x31\x01", decodeEXR, decodeEXRConfig)
+}
+
+const (
+	exrUint  = 0
+	exrHalf  = 1
+	exrFloat = 2
+)
+
+const (
+	exrNoCompression   = 0
+	exrRLECompression  = 1
+	exrZIPSCompression = 2
+	exrZIPCompression  = 3
+)
+
+type exrChannel struct {
+	Name      string
+	PixelType int32
+}
+
+func (channel exrChannel) size() int {
+	if channel.PixelType == exrHalf {
+		return 2
+	}
+	return 4
+}
+
+// exrHeader holds the attributes needed to read a single-part scanline
+// image. Tiled, deep, and multi-part files, and the wavelet and lossy
+// compressions, are not supported.
+type exrHeader struct {
+	Channels    []exrChannel
+	Compression uint8
+	DataWindow  image.Rectangle
+}
+
+func (header exrHeader) linesPerChunk() int {
+	if header.Compression == exrZIPCompression {
+		return 16
+	}
+	return 1
+}
+
+func readCString(r *bufio.Reader) (string, error) {
+	s, err := r.ReadString(0)
+	if err != nil {
+		return "", err
+	}
+	return s[:len(s)-1], nil
+}
+
+func readEXRHeader(r *bufio.Reader) (exrHeader, error) {
+	var start struct {
+		Magic   uint32
+		Version uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &start); err != nil {
+		return exrHeader{}, fmt.Errorf("invalid EXR header: %w", err)
+	}
+	if start.Version&0xff != 2 {
+		return exrHeader{}, fmt.Errorf("unsupported EXR version %d", start.Version&0xff)
+	}
+	if start.Version&0x1a00 != 0 {
+		return exrHeader{}, fmt.Errorf("unsupported EXR layout, only single-part scanline images can be read")
+	}
+
+	var header exrHeader
+	windowSet := false
+	for {
+		name, err := readCString(r)
+		if err != nil {
+			return exrHeader{}, fmt.Errorf("invalid EXR header: %w", err)
+		}
+		if name == "" {
+			break
+		}
+		if _, err := readCString(r); err != nil {
+			return exrHeader{}, fmt.Errorf("invalid EXR attribute %s: %w", name, err)
+		}
+		var size int32
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil || size < 0 {
+			return exrHeader{}, fmt.Errorf("invalid EXR attribute %s", name)
+		}
+		value := make([]byte, size)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return exrHeader{}, fmt.Errorf("invalid EXR attribute %s: %w", name, err)
+		}
+
+		switch name {
+		case "channels":
+			if header.Channels, err = parseEXRChannels(value); err != nil {
+				return exrHeader{}, err
+			}
+		case "compression":
+			if len(value) != 1 {
+				return exrHeader{}, fmt.Errorf("invalid EXR compression attribute")
+			}
+			header.Compression = value[0]
+		case "dataWindow":
+			var box [4]int32
+			if err := binary.Read(bytes.NewReader(value), binary.LittleEndian, &box); err != nil {
+				return exrHeader{}, fmt.Errorf("invalid EXR data window: %w", err)
+			}
+			header.DataWindow = image.Rect(int(box[0]), int(box[1]), int(box[2])+1, int(box[3])+1)
+			windowSet = true
+		}
+	}
+
+	if !windowSet || header.DataWindow.Empty() || len(header.Channels) == 0 {
+		return exrHeader{}, fmt.Errorf("invalid EXR header, missing channels or data window")
+	}
+	if header.Compression > exrZIPCompression {
+		return exrHeader{}, fmt.Errorf("unsupported EXR compression %d, expected none, RLE, ZIPS, or ZIP", header.Compression)
+	}
+	return header, nil
+}
+
+func parseEXRChannels(value []byte) ([]exrChannel, error) {
+	r := bufio.NewReader(bytes.NewReader(value))
+	var channels []exrChannel
+	for {
+		name, err := readCString(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EXR channel list: %w", err)
+		}
+		if name == "" {
+			return channels, nil
+		}
+		var fields struct {
+			PixelType int32
+			Linear    uint8
+			Reserved  [3]uint8
+			XSampling int32
+			YSampling int32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &fields); err != nil {
+			return nil, fmt.Errorf("invalid EXR channel %s: %w", name, err)
+		}
+		if fields.PixelType < exrUint || fields.PixelType > exrFloat {
+			return nil, fmt.Errorf("invalid EXR channel %s pixel type %d", name, fields.PixelType)
+		}
+		if fields.XSampling != 1 || fields.YSampling != 1 {
+			return nil, fmt.Errorf("unsupported EXR channel %s, subsampled channels cannot be read", name)
+		}
+		channels = append(channels, exrChannel{name, fields.PixelType})
+	}
+}
+
+func decodeEXRConfig(r io.Reader) (image.Config, error) {
+	header, err := readEXRHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: FloatModel, Width: header.DataWindow.Dx(), Height: header.DataWindow.Dy()}, nil
+}
+
+// exrUnpredict undoes the delta predictor and byte interleaving RLE and ZIP
+// apply before compressing.
+func exrUnpredict(data []byte) []byte {
+	for i := 1; i < len(data); i++ {
+		data[i] = byte(int(data[i-1]) + int(data[i]) - 128)
+	}
+	out := make([]byte, len(data))
+	half := (len(data) + 1) / 2
+	for i := range out {
+		if i%2 == 0 {
+			out[i] = data[i/2]
+		} else {
+			out[i] = data[half+i/2]
+		}
+	}
+	return out
+}
+
+func exrPredict(data []byte) []byte {
+	out := make([]byte, len(data))
+	half := (len(data) + 1) / 2
+	for i, b := range data {
+		if i%2 == 0 {
+			out[i/2] = b
+		} else {
+			out[half+i/2] = b
+		}
+	}
+	prev := out[0]
+	for i := 1; i < len(out); i++ {
+		prev, out[i] = out[i], byte(int(out[i])-int(prev)+128)
+	}
+	return out
+}
+
+func exrDecompress(compression uint8, data []byte, size int) ([]byte, error) {
+	if len(data) == size {
+		return data, nil
+	}
+
+	var raw []byte
+	switch compression {
+	case exrRLECompression:
+		for i := 0; i < len(data); {
+			count := int(int8(data[i]))
+			i++
+			if count < 0 {
+				if i-count > len(data) {
+					return nil, fmt.Errorf("invalid EXR RLE run")
+				}
+				raw = append(raw, data[i:i-count]...)
+				i -= count
+			} else {
+				if i >= len(data) {
+					return nil, fmt.Errorf("invalid EXR RLE run")
+				}
+				for range count + 1 {
+					raw = append(raw, data[i])
+				}
+				i++
+			}
+		}
+	case exrZIPSCompression, exrZIPCompression:
+		decompressor, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid EXR ZIP chunk: %w", err)
+		}
+		if raw, err = io.ReadAll(decompressor); err != nil {
+			return nil, fmt.Errorf("invalid EXR ZIP chunk: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid EXR chunk size %d, expected %d", len(data), size)
+	}
+
+	if len(raw) != size {
+		return nil, fmt.Errorf("invalid EXR chunk, decompressed to %d bytes instead of %d", len(raw), size)
+	}
+	return exrUnpredict(raw), nil
+}
+
+// decodeEXR reads an OpenEXR image into float pixels. R, G, B, and A are
+// read by name, a lone Y channel as gray, and other channels are skipped.
+func decodeEXR(r io.Reader) (image.Image, error) {
+	reader := bufio.NewReader(r)
+	header, err := readEXRHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	window := header.DataWindow
+	width, height := window.Dx(), window.Dy()
+	lines := header.linesPerChunk()
+	chunks := (height + lines - 1) / lines
+	if _, err := reader.Discard(chunks * 8); err != nil {
+		return nil, fmt.Errorf("invalid EXR offset table: %w", err)
+	}
+
+	lineSize := 0
+	for _, channel := range header.Channels {
+		lineSize += channel.size() * width
+	}
+
+	img := NewFloatImage(image.Rect(0, 0, width, height))
+	alpha := slices.ContainsFunc(header.Channels, func(channel exrChannel) bool { return channel.Name == "A" })
+	if !alpha {
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 1
+		}
+	}
+
+	for range chunks {
+		var chunk struct {
+			Y    int32
+			Size int32
+		}
+		if err := binary.Read(reader, binary.LittleEndian, &chunk); err != nil {
+			return nil, fmt.Errorf("invalid EXR chunk: %w", err)
+		}
+		first := int(chunk.Y) - window.Min.Y
+		if first < 0 || first >= height || first%lines != 0 || chunk.Size < 0 {
+			return nil, fmt.Errorf("invalid EXR chunk at line %d", chunk.Y)
+		}
+		count := min(lines, height-first)
+
+		data := make([]byte, chunk.Size)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, fmt.Errorf("invalid EXR chunk: %w", err)
+		}
+		if data, err = exrDecompress(header.Compression, data, count*lineSize); err != nil {
+			return nil, err
+		}
+
+		for line := range count {
+			y := first + line
+			offset := line * lineSize
+			for _, channel := range header.Channels {
+				size := channel.size()
+				for x := range width {
+					raw := data[offset+x*size : offset+(x+1)*size]
+					var v float32
+					switch channel.PixelType {
+					case exrHalf:
+						v = halfToFloat(binary.LittleEndian.Uint16(raw))
+					case exrFloat:
+						v = math.Float32frombits(binary.LittleEndian.Uint32(raw))
+					default:
+						v = float32(binary.LittleEndian.Uint32(raw))
+					}
+
+					i := img.PixOffset(x, y)
+					switch channel.Name {
+					case "R":
+						img.Pix[i] = v
+					case "G":
+						img.Pix[i+1] = v
+					case "B":
+						img.Pix[i+2] = v
+					case "A":
+						img.Pix[i+3] = v
+					case "Y":
+						img.Pix[i], img.Pix[i+1], img.Pix[i+2] = v, v, v
+					}
+				}
+				offset += size * width
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// exrSprites writes ZIP-compressed OpenEXR with 32-bit float channels, so
+// data textures keep every value they were sliced with.
+type exrSprites struct{}
+
+func (exrSprites) Extension() string { return ".exr" }
+
+func (exrSprites) Encode(w io.Writer, img image.Image) error {
+	float := toFloatImage(img)
+	bounds := float.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	var header bytes.Buffer
+	attribute := func(name, kind string, value any) {
+		var data bytes.Buffer
+		binary.Write(&data, binary.LittleEndian, value)
+		header.WriteString(name + "\x00" + kind + "\x00")
+		binary.Write(&header, binary.LittleEndian, int32(data.Len()))
+		header.Write(data.Bytes())
+	}
+
+	binary.Write(&header, binary.LittleEndian, [2]uint32{0x01312f76, 2})
+	var channels bytes.Buffer
+	for _, name := range []string{"A", "B", "G", "R"} {
+		channels.WriteString(name + "\x00")
+		binary.Write(&channels, binary.LittleEndian, struct {
+			PixelType int32
+			Linear    [4]uint8
+			Sampling  [2]int32
+		}{exrFloat, [4]uint8{}, [2]int32{1, 1}})
+	}
+	channels.WriteByte(0)
+	attribute("channels", "chlist", channels.Bytes())
+	attribute("compression", "compression", uint8(exrZIPCompression))
+	window := [4]int32{0, 0, int32(width - 1), int32(height - 1)}
+	attribute("dataWindow", "box2i", window)
+	attribute("displayWindow", "box2i", window)
+	attribute("lineOrder", "lineOrder", uint8(0))
+	attribute("pixelAspectRatio", "float", float32(1))
+	attribute("screenWindowCenter", "v2f", [2]float32{0, 0})
+	attribute("screenWindowWidth", "float", float32(1))
+	header.WriteByte(0)
+
+	lines := exrHeader{Compression: exrZIPCompression}.linesPerChunk()
+	row := make([]float32, width)
+	var chunks [][]byte
+	for first := 0; first < height; first += lines {
+		var raw bytes.Buffer
+		for y := first; y < min(first+lines, height); y++ {
+			// Channels are stored alphabetically, so alpha comes first.
+			for channel := 3; channel >= 0; channel-- {
+				for x := range width {
+					row[x] = float.Pix[float.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)+channel]
+				}
+				binary.Write(&raw, binary.LittleEndian, row)
+			}
+		}
+
+		var compressed bytes.Buffer
+		compressor := zlib.NewWriter(&compressed)
+		compressor.Write(exrPredict(raw.Bytes()))
+		compressor.Close()
+
+		data := compressed.Bytes()
+		if len(data) >= raw.Len() {
+			data = raw.Bytes()
+		}
+		var chunk bytes.Buffer
+		binary.Write(&chunk, binary.LittleEndian, [2]int32{int32(first), int32(len(data))})
+		chunk.Write(data)
+		chunks = append(chunks, chunk.Bytes())
+	}
+
+	out := bufio.NewWriter(w)
+	out.Write(header.Bytes())
+	offset := uint64(header.Len() + 8*len(chunks))
+	for _, chunk := range chunks {
+		binary.Write(out, binary.LittleEndian, offset)
+		offset += uint64(len(chunk))
+	}
+	for _, chunk := range chunks {
+		out.Write(chunk)
+	}
+	return out.Flush()
+}
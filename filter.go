@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type FrameFilter struct {
+	Names   map[string]bool
+	Locale  string
+	Include []*regexp.Regexp
+	Exclude []*regexp.Regexp
+}
+
+func newFrameFilter(names []string, locale string, include, exclude []string) (FrameFilter, error) {
+	filter := FrameFilter{Locale: locale}
+	if len(names) > 0 {
+		filter.Names = make(map[string]bool, len(names))
+		for _, name := range names {
+			filter.Names[frameName(name)] = true
+		}
+	}
+
+	var err error
+	if filter.Include, err = compileGlobs(include); err != nil {
+		return FrameFilter{}, err
+	}
+	if filter.Exclude, err = compileGlobs(exclude); err != nil {
+		return FrameFilter{}, err
+	}
+
+	return filter, nil
+}
+
+// compileGlob turns a glob into a regexp where * and ? stay within one path
+// segment and ** spans any number. Like .gitignore, a pattern without a slash
+// also matches the last segment of a name at any depth.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	if !strings.Contains(pattern, "/") {
+		expr.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					expr.WriteString("(?:.*/)?")
+				} else {
+					expr.WriteString(".*")
+				}
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid glob %q: unterminated character class", pattern)
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	re, err := regexp.Compile("^" + expr.String() + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+func compileGlobs(patterns []string) ([]*regexp.Regexp, error) {
+	globs := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		glob, err := compileGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, glob)
+	}
+	return globs, nil
+}
+
+func matchAny(globs []*regexp.Regexp, name string) bool {
+	return slices.ContainsFunc(globs, func(glob *regexp.Regexp) bool {
+		return glob.MatchString(name)
+	})
+}
+
+func (filter FrameFilter) match(name string, locales LocaleIndex) bool {
+	if filter.Locale != "" {
+		if _, locale := locales.locale(name); locale != "" && locale != filter.Locale {
+			return false
+		}
+	}
+	if len(filter.Include) > 0 && !matchAny(filter.Include, name) {
+		return false
+	}
+	if matchAny(filter.Exclude, name) {
+		return false
+	}
+	return filter.Names == nil || filter.Names[name]
+}
+
+// apply drops unmatched frames, and with them any sheet left empty so its
+// image is never decoded.
+func (filter FrameFilter) apply(pack Pack, locales LocaleIndex) Pack {
+	sheets := make([]Sheet, 0, len(pack.Sheets))
+
+	for _, sh := range pack.Sheets {
+		textures := make([]Texture, 0, len(sh.Textures))
+		for _, tex := range sh.Textures {
+			if filter.match(tex.FileName, locales) {
+				textures = append(textures, tex)
+			}
+		}
+
+		if len(textures) > 0 {
+			sh.Textures = textures
+			sheets = append(sheets, sh)
+		}
+	}
+
+	pack.Sheets = sheets
+	return pack
+}
+
+// missing reports requested frame names that none of the packs contain.
+func (filter FrameFilter) missing(packs ...Pack) error {
+	found := make(map[string]bool)
+	for _, pack := range packs {
+		for _, sh := range pack.Sheets {
+			for _, tex := range sh.Textures {
+				found[tex.FileName] = true
+			}
+		}
+	}
+
+	var missing []string
+	for name := range filter.Names {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return fmt.Errorf("frames not found: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// FloatColor is a premultiplied color whose channels may run past 1, as HDR
+// lightmaps do, or hold data that is not a color at all, like a LUT.
+type FloatColor struct {
+	R, G, B, A float32
+}
+
+func (c FloatColor) RGBA() (r, g, b, a uint32) {
+	clamp := func(v float32) uint32 {
+		return uint32(math.Round(float64(min(max(v, 0), 1)) * 0xffff))
+	}
+	a = clamp(c.A)
+	return min(clamp(c.R), a), min(clamp(c.G), a), min(clamp(c.B), a), a
+}
+
+var FloatModel = color.ModelFunc(func(c color.Color) color.Color {
+	if c, ok := c.(FloatColor); ok {
+		return c
+	}
+	r, g, b, a := c.RGBA()
+	return FloatColor{float32(r) / 0xffff, float32(g) / 0xffff, float32(b) / 0xffff, float32(a) / 0xffff}
+})
+
+// FloatImage holds 32-bit float RGBA pixels, so slicing an HDR sheet keeps
+// values an 8 or 16-bit image would clamp. Drawing it onto other images
+// clamps to 0..1 like any color.
+type FloatImage struct {
+	Pix    []float32
+	Stride int
+	Rect   image.Rectangle
+}
+
+func NewFloatImage(r image.Rectangle) *FloatImage {
+	return &FloatImage{Pix: make([]float32, 4*r.Dx()*r.Dy()), Stride: 4 * r.Dx(), Rect: r}
+}
+
+func (img *FloatImage) ColorModel() color.Model { return FloatModel }
+
+func (img *FloatImage) Bounds() image.Rectangle { return img.Rect }
+
+func (img *FloatImage) PixOffset(x, y int) int {
+	return (y-img.Rect.Min.Y)*img.Stride + (x-img.Rect.Min.X)*4
+}
+
+func (img *FloatImage) At(x, y int) color.Color {
+	return img.FloatAt(x, y)
+}
+
+func (img *FloatImage) FloatAt(x, y int) FloatColor {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return FloatColor{}
+	}
+	i := img.PixOffset(x, y)
+	return FloatColor{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]}
+}
+
+func (img *FloatImage) Set(x, y int, c color.Color) {
+	img.SetFloat(x, y, FloatModel.Convert(c).(FloatColor))
+}
+
+func (img *FloatImage) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(img.Rect)
+	if r.Empty() {
+		return &FloatImage{}
+	}
+	i := img.PixOffset(r.Min.X, r.Min.Y)
+	return &FloatImage{Pix: img.Pix[i:], Stride: img.Stride, Rect: r}
+}
+
+func (img *FloatImage) SetFloat(x, y int, c FloatColor) {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return
+	}
+	i := img.PixOffset(x, y)
+	img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+}
+
+// toFloatImage widens any image to float pixels, reusing float images as is.
+func toFloatImage(img image.Image) *FloatImage {
+	if float, ok := img.(*FloatImage); ok {
+		return float
+	}
+
+	bounds := img.Bounds()
+	float := NewFloatImage(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			float.Set(x, y, img.At(x, y))
+		}
+	}
+	return float
+}
+
+// copyFloat copies src's pixels from sp onto r of dst without clamping them,
+// which draw.Draw would.
+func copyFloat(dst *FloatImage, r image.Rectangle, src *FloatImage, sp image.Point) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.SetFloat(x, y, src.FloatAt(sp.X+x-r.Min.X, sp.Y+y-r.Min.Y))
+		}
+	}
+}
+
+// checkFloat refuses the options that would clamp a float sheet's values by
+// working on it as an ordinary color image.
+func (unpacker Unpacker) checkFloat(sheet Sheet) error {
+	var options []string
+	if unpacker.SRGB {
+		options = append(options, "--srgb")
+	}
+	if unpacker.Unpremultiply {
+		options = append(options, "--unpremultiply")
+	}
+	if len(unpacker.Channels) > 1 || len(unpacker.Channels) == 1 && unpacker.Channels[0].Channels != "RGBA" {
+		options = append(options, "--channels")
+	}
+	if len(options) > 0 {
+		return fmt.Errorf("cannot apply %s to float sheet %s without clamping it", strings.Join(options, ", "), sheet.Image)
+	}
+	return nil
+}
+
+// halfToFloat widens an IEEE 754 half-precision float.
+func halfToFloat(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h) & 0x3ff
+
+	switch {
+	case exp == 0 && mant == 0:
+		return math.Float32frombits(sign)
+	case exp == 0:
+		// Subnormal halves are normal floats.
+		for mant&0x400 == 0 {
+			mant <<= 1
+			exp--
+		}
+		exp++
+		mant &= 0x3ff
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp+112)<<23 | mant<<13)
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Formats with an Encode can be written by convert. Encoders of single-image
+// formats only write the first sheet of a pack.
+type AtlasFormat struct {
+	Name      string
+	Extension string
+	Parse     func(data []byte, path string) (Pack, error)
+	Sniff     func(data []byte, path string) bool
+	Encode    func(pack Pack) ([]byte, error)
+}
+
+// Formats are sniffed in order, so more specific formats come first.
+var atlasFormats = []AtlasFormat{
+	{Name: "multiatlas", Extension: ".json", Parse: parseMultiAtlasPack, Sniff: sniffJSONFrames("textures"), Encode: encodeMultiAtlasPack},
+	{Name: "hash", Extension: ".json", Parse: parseHashPack, Sniff: sniffJSONFrames("{"), Encode: encodeHashPack},
+	{Name: "array", Extension: ".json", Parse: parseArrayPack, Sniff: sniffJSONFrames("["), Encode: encodeArrayPack},
+	{Name: "xml", Extension: ".xml", Parse: ignorePath(parseXMLPack), Sniff: sniffContains("<TextureAtlas"), Encode: encodeXMLPack},
+	{Name: "plist", Parse: parsePlistPack, Sniff: sniffContains("<plist")},
+	{Name: "unity", Parse: parseUnityMeta, Sniff: sniffUnityMeta},
+	{Name: "atlas", Parse: ignorePath(parseSpineAtlas), Sniff: sniffTextAtlas},
+}
+
+var textAtlasField = regexp.MustCompile(`(?m)^\s*(size|bounds|xy)\s*:`)
+
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".webp", ".gif", ".bmp", ".tga", ".exr", ".hdr", ".pfm"}
+
+type flexFloat float64
+
+// TexturePacker writes the meta scale as a string while Phaser writes a number.
+func (f *flexFloat) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid scale %s", data)
+	}
+
+	*f = flexFloat(n)
+	return nil
+}
+
+type jsonAtlasMeta struct {
+	App     string    `json:"app"`
+	Version string    `json:"version"`
+	Image   string    `json:"image"`
+	Format  string    `json:"format"`
+	Size    Size      `json:"size"`
+	Scale   flexFloat `json:"scale"`
+	Related []string  `json:"related_multi_packs,omitempty"`
+	Extrude flexFloat `json:"extrude,omitempty"`
+}
+
+func (meta jsonAtlasMeta) pack(textures []Texture) Pack {
+	sheet := Sheet{
+		Format:   meta.Format,
+		Image:    meta.Image,
+		Scale:    float64(meta.Scale),
+		Size:     meta.Size,
+		Textures: textures,
+	}
+	if sheet.Scale == 0 {
+		sheet.Scale = 1
+	}
+
+	packMeta := make(map[string]string)
+	if meta.App != "" {
+		packMeta["app"] = meta.App
+	}
+	if meta.Version != "" {
+		packMeta["version"] = meta.Version
+	}
+	if meta.Extrude > 0 {
+		packMeta["extrude"] = strconv.Itoa(int(meta.Extrude))
+	}
+
+	return Pack{Meta: packMeta, Sheets: []Sheet{sheet}, Related: meta.Related}
+}
+
+func newJSONAtlasMeta(pack Pack) jsonAtlasMeta {
+	sheet := pack.Sheets[0]
+	return jsonAtlasMeta{
+		App:     pack.Meta["app"],
+		Version: pack.Meta["version"],
+		Image:   sheet.Image,
+		Format:  sheet.Format,
+		Size:    sheet.Size,
+		Scale:   flexFloat(sheet.Scale),
+		Related: pack.Related,
+		Extrude: flexFloat(packExtrude(pack)),
+	}
+}
+
+func ignorePath(parse func(data []byte) (Pack, error)) func(data []byte, path string) (Pack, error) {
+	return func(data []byte, _ string) (Pack, error) {
+		return parse(data)
+	}
+}
+
+func frameName(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	for _, imageExt := range imageExtensions {
+		if ext == imageExt {
+			return strings.TrimSuffix(name, path.Ext(name))
+		}
+	}
+	return name
+}
+
+func sniffJSONFrames(kind string) func(data []byte, path string) bool {
+	return func(data []byte, _ string) bool {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return false
+		}
+
+		if kind == "textures" {
+			_, ok := fields["textures"]
+			return ok
+		}
+
+		frames := bytes.TrimSpace(fields["frames"])
+		return len(frames) > 0 && string(frames[:1]) == kind
+	}
+}
+
+func sniffContains(marker string) func(data []byte, path string) bool {
+	return func(data []byte, _ string) bool {
+		trimmed := bytes.TrimSpace(data)
+		return bytes.HasPrefix(trimmed, []byte("<")) && bytes.Contains(trimmed, []byte(marker))
+	}
+}
+
+func sniffUnityMeta(data []byte, path string) bool {
+	return filepath.Ext(path) == ".meta" || bytes.Contains(data, []byte("TextureImporter:"))
+}
+
+func sniffTextAtlas(data []byte, _ string) bool {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("<")) {
+		return false
+	}
+	return textAtlasField.Match(trimmed)
+}
+
+func atlasFormatNames() string {
+	names := make([]string, 0, len(atlasFormats))
+	for _, format := range atlasFormats {
+		names = append(names, format.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func detectAtlasFormat(data []byte, path string) (AtlasFormat, error) {
+	for _, format := range atlasFormats {
+		if format.Sniff(data, path) {
+			return format, nil
+		}
+	}
+
+	return AtlasFormat{}, fmt.Errorf("could not detect atlas format, pass --format with one of: %s", atlasFormatNames())
+}
+
+func parseMultiAtlasPack(data []byte, _ string) (Pack, error) {
+	var pack Pack
+	if err := json.Unmarshal(data, &pack); err != nil {
+		return Pack{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	return pack, nil
+}
+
+func parseHashPack(data []byte, _ string) (Pack, error) {
+	var raw struct {
+		Frames map[string]Texture `json:"frames"`
+		Meta   jsonAtlasMeta      `json:"meta"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Pack{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	// Hash keys carry no order, so recover the authored order from the source.
+	names, err := jsonObjectKeys(data, "frames")
+	if err != nil {
+		return Pack{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	textures := make([]Texture, 0, len(names))
+	for _, name := range names {
+		tex := raw.Frames[name]
+		tex.FileName = frameName(name)
+		textures = append(textures, tex)
+	}
+
+	return raw.Meta.pack(textures), nil
+}
+
+func parseArrayPack(data []byte, _ string) (Pack, error) {
+	var raw struct {
+		Frames []Texture     `json:"frames"`
+		Meta   jsonAtlasMeta `json:"meta"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Pack{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	for i := range raw.Frames {
+		raw.Frames[i].FileName = frameName(raw.Frames[i].FileName)
+	}
+
+	return raw.Meta.pack(raw.Frames), nil
+}
+
+// hashTexture matches Texture field for field, leaving the name to the hash key.
+type hashTexture struct {
+	FileName         string       `json:"-"`
+	Frame            Frame        `json:"frame"`
+	Rotated          bool         `json:"rotated"`
+	SourceSize       Size         `json:"sourceSize"`
+	SpriteSourceSize Frame        `json:"spriteSourceSize"`
+	Trimmed          bool         `json:"trimmed"`
+	Pivot            *Pivot       `json:"pivot,omitempty"`
+	Vertices         [][2]float64 `json:"vertices,omitempty"`
+	VerticesUV       [][2]float64 `json:"verticesUV,omitempty"`
+	Triangles        [][3]int     `json:"triangles,omitempty"`
+	Scale9Borders    *Frame       `json:"scale9Borders,omitempty"`
+}
+
+func encodeMultiAtlasPack(pack Pack) ([]byte, error) {
+	data, err := json.MarshalIndent(pack, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return data, nil
+}
+
+func encodeHashPack(pack Pack) ([]byte, error) {
+	meta, err := json.Marshal(newJSONAtlasMeta(pack))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	// Marshalling a map would sort the frames, so the object is built by hand
+	// to keep the authored order.
+	var buf bytes.Buffer
+	buf.WriteString(`{"frames":{`)
+	for i, tex := range pack.Sheets[0].Textures {
+		name, _ := json.Marshal(tex.FileName)
+		frame, err := json.Marshal(hashTexture(tex))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode JSON: %w", err)
+		}
+
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(frame)
+	}
+	buf.WriteString(`},"meta":`)
+	buf.Write(meta)
+	buf.WriteByte('}')
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+func encodeArrayPack(pack Pack) ([]byte, error) {
+	data, err := json.MarshalIndent(struct {
+		Frames []Texture     `json:"frames"`
+		Meta   jsonAtlasMeta `json:"meta"`
+	}{pack.Sheets[0].Textures, newJSONAtlasMeta(pack)}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return data, nil
+}
+
+func jsonObjectKeys(data []byte, field string) ([]string, error) {
+	// An empty field lists the keys of the document itself.
+	object := json.RawMessage(data)
+	if field != "" {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		object = fields[field]
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(object))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		key, _ := token.(string)
+		keys = append(keys, key)
+
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+func loadPack(path, format string) (Pack, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Pack{}, withExitCode(exitBadInput, fmt.Errorf("failed to read input: %w", err))
+	}
+
+	parse := func(atlasFormat AtlasFormat) (Pack, error) {
+		pack, err := atlasFormat.Parse(data, path)
+		if err != nil {
+			return Pack{}, withExitCode(exitBadInput, err)
+		}
+		if err := checkPackLimits(pack, path); err != nil {
+			return Pack{}, withExitCode(exitBadInput, err)
+		}
+		return pack, nil
+	}
+
+	if format == "" {
+		detected, err := detectAtlasFormat(data, path)
+		if err != nil {
+			return Pack{}, withExitCode(exitBadInput, err)
+		}
+		return parse(detected)
+	}
+
+	for _, atlasFormat := range atlasFormats {
+		if atlasFormat.Name == format {
+			return parse(atlasFormat)
+		}
+	}
+
+	return Pack{}, fmt.Errorf("unknown atlas format %q, expected one of: %s", format, atlasFormatNames())
+}
+
+// followRelatedPacks merges every pack reachable through related_multi_packs
+// into pack, rewriting sheet images to stay relative to the first pack.
+func followRelatedPacks(pack Pack, path, format string) (Pack, error) {
+	rootDir := filepath.Dir(path)
+	visited := map[string]bool{filepath.Clean(path): true}
+	queue := make([]string, 0, len(pack.Related))
+
+	for _, related := range pack.Related {
+		queue = append(queue, filepath.Join(rootDir, filepath.FromSlash(related)))
+	}
+
+	for len(queue) > 0 {
+		relatedPath := filepath.Clean(queue[0])
+		queue = queue[1:]
+
+		if visited[relatedPath] {
+			continue
+		}
+		visited[relatedPath] = true
+
+		related, err := loadPack(relatedPath, format)
+		if err != nil {
+			return Pack{}, fmt.Errorf("failed to load related pack %s: %w", relatedPath, err)
+		}
+
+		relDir, err := filepath.Rel(rootDir, filepath.Dir(relatedPath))
+		if err != nil {
+			return Pack{}, fmt.Errorf("failed to resolve related pack %s: %w", relatedPath, err)
+		}
+
+		for _, sh := range related.Sheets {
+			sh.Image = filepath.ToSlash(filepath.Join(relDir, sh.Image))
+			pack.Sheets = append(pack.Sheets, sh)
+		}
+
+		for _, next := range related.Related {
+			queue = append(queue, filepath.Join(filepath.Dir(relatedPath), filepath.FromSlash(next)))
+		}
+	}
+
+	return pack, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+)
+
+// fuzzPack seeds a parser with its selftest fixture and checks that no input
+// makes it panic, nor a pack it accepts make the limit checks panic.
+func fuzzPack(f *testing.F, fixture string, parse func(data []byte, path string) (Pack, error)) {
+	seed, err := selftestFixtures.ReadFile("selftest/" + fixture)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(seed)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pack, err := parse(data, fixture)
+		if err != nil {
+			return
+		}
+		_ = checkPackLimits(pack, fixture)
+	})
+}
+
+func FuzzParseMultiAtlasPack(f *testing.F) {
+	fuzzPack(f, "multiatlas.json", parseMultiAtlasPack)
+}
+
+func FuzzParseHashPack(f *testing.F) {
+	fuzzPack(f, "hash.json", parseHashPack)
+}
+
+func FuzzParseArrayPack(f *testing.F) {
+	fuzzPack(f, "array.json", parseArrayPack)
+}
+
+func FuzzParseXMLPack(f *testing.F) {
+	fuzzPack(f, "atlas.xml", ignorePath(parseXMLPack))
+}
+
+func FuzzParsePlistPack(f *testing.F) {
+	fuzzPack(f, "cocos.plist", parsePlistPack)
+}
+
+func FuzzParseUnityMeta(f *testing.F) {
+	fuzzPack(f, "page0.png.meta", parseUnityMeta)
+}
+
+func FuzzParseSpineAtlas(f *testing.F) {
+	fuzzPack(f, "spine.atlas", ignorePath(parseSpineAtlas))
+}
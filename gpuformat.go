@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// A PageEncoder compresses a PNG page into a GPU texture format by running
+// an external encoder, since Go has no native Basis or ETC encoders.
+type PageEncoder struct {
+	Name      string
+	Extension string
+	Tool      string
+	Args      func(input, output string) []string
+}
+
+var pageEncoders = []PageEncoder{
+	{
+		Name:      "ktx2",
+		Extension: ".ktx2",
+		Tool:      "basisu",
+		Args: func(input, output string) []string {
+			return []string{"-ktx2", "-file", input, "-output_file", output}
+		},
+	},
+	{
+		Name:      "etc2",
+		Extension: ".ktx",
+		Tool:      "etcpak",
+		Args: func(input, output string) []string {
+			return []string{"--etc2", "--rgba", input, output}
+		},
+	},
+}
+
+func findPageEncoders(names []string) ([]PageEncoder, error) {
+	var encoders []PageEncoder
+
+	for _, name := range names {
+		if name == "png" {
+			continue
+		}
+
+		found := false
+		for _, encoder := range pageEncoders {
+			if encoder.Name == name {
+				if _, err := exec.LookPath(encoder.Tool); err != nil {
+					return nil, fmt.Errorf("%s pages need %s on PATH: %w", encoder.Name, encoder.Tool, err)
+				}
+				encoders = append(encoders, encoder)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown page format %q, expected png, ktx2, or etc2", name)
+		}
+	}
+
+	return encoders, nil
+}
+
+// encode writes a compressed copy of the PNG page beside it and returns its path.
+func (encoder PageEncoder) encode(ctx context.Context, pngPath string) (string, error) {
+	outputPath := strings.TrimSuffix(pngPath, ".png") + encoder.Extension
+
+	output, err := exec.CommandContext(ctx, encoder.Tool, encoder.Args(pngPath, outputPath)...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s as %s: %w\n%s", pngPath, encoder.Name, err, strings.TrimSpace(string(output)))
+	}
+
+	return outputPath, nil
+}
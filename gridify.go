@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/image/draw"
+)
+
+type GridCell struct {
+	FileName string `json:"filename"`
+	Sheet    string `json:"sheet"`
+	Index    int    `json:"index"`
+	Column   int    `json:"column"`
+	Row      int    `json:"row"`
+	Frame    Frame  `json:"frame"`
+}
+
+type GridIndex struct {
+	Image   string     `json:"image"`
+	Cell    Size       `json:"cell"`
+	Columns int        `json:"columns"`
+	Rows    int        `json:"rows"`
+	Fit     string     `json:"fit"`
+	Frames  []GridCell `json:"frames"`
+}
+
+type Gridifier struct {
+	Pack
+	InputDir string
+	Cell     Size
+	Columns  int
+	Fit      string
+}
+
+func (gridifier Gridifier) placeTexture(grid *image.RGBA, cell image.Rectangle, sprite *image.NRGBA) {
+	bounds := sprite.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if gridifier.Fit == "scale" {
+		ratio := math.Min(float64(cell.Dx())/float64(width), float64(cell.Dy())/float64(height))
+		width = max(1, int(float64(width)*ratio))
+		height = max(1, int(float64(height)*ratio))
+	}
+
+	offset := image.Point{(cell.Dx() - width) / 2, (cell.Dy() - height) / 2}
+	target := image.Rectangle{cell.Min.Add(offset), cell.Min.Add(offset).Add(image.Point{width, height})}
+
+	if gridifier.Fit == "scale" {
+		draw.CatmullRom.Scale(grid, target, sprite, bounds, draw.Src, nil)
+		return
+	}
+
+	draw.Draw(grid, target.Intersect(cell), sprite, bounds.Min.Add(target.Intersect(cell).Min.Sub(target.Min)), draw.Src)
+}
+
+func (gridifier Gridifier) gridify() (*image.RGBA, GridIndex, error) {
+	total := 0
+	for _, sh := range gridifier.Sheets {
+		total += len(sh.Textures)
+	}
+
+	columns := gridifier.Columns
+	if columns <= 0 {
+		columns = max(1, int(math.Ceil(math.Sqrt(float64(total)))))
+	}
+	rows := max(1, (total+columns-1)/columns)
+
+	index := GridIndex{
+		Cell:    gridifier.Cell,
+		Columns: columns,
+		Rows:    rows,
+		Fit:     gridifier.Fit,
+		Frames:  make([]GridCell, 0, total),
+	}
+
+	grid, err := newCanvas(columns*gridifier.Cell.Width, rows*gridifier.Cell.Height)
+	if err != nil {
+		return nil, GridIndex{}, err
+	}
+
+	i := 0
+	for _, sh := range gridifier.Sheets {
+		img, err := decodeSheet(gridifier.InputDir, sh)
+		if err != nil {
+			return nil, GridIndex{}, err
+		}
+
+		for _, tex := range sh.Textures {
+			column, row := i%columns, i/columns
+			cell := Frame{
+				Width:  gridifier.Cell.Width,
+				Height: gridifier.Cell.Height,
+				X:      column * gridifier.Cell.Width,
+				Y:      row * gridifier.Cell.Height,
+			}
+
+			gridifier.placeTexture(grid, cell.Rect(), renderTexture(tex, img))
+
+			index.Frames = append(index.Frames, GridCell{
+				FileName: tex.FileName,
+				Sheet:    sh.Image,
+				Index:    i,
+				Column:   column,
+				Row:      row,
+				Frame:    cell,
+			})
+			i++
+		}
+	}
+
+	return grid, index, nil
+}
+
+func newGridifyCmd() *cobra.Command {
+	var outputPath string
+	var cell string = "64x64"
+	var columns int = 0
+	var fit string = "center"
+
+	var gridifyCmd = &cobra.Command{
+		Use:   "gridify <path>",
+		Short: "Re-layout all frames onto a uniform grid sheet",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var path = args[0]
+			format, _ := cmd.Flags().GetString("format")
+
+			if fit != "center" && fit != "scale" {
+				return fmt.Errorf("invalid fit %q, expected center or scale", fit)
+			}
+
+			cellSize, err := parseSize(cell)
+			if err != nil {
+				return err
+			}
+
+			pack, err := loadPack(path, format)
+			if err != nil {
+				return err
+			}
+
+			if outputPath == "" {
+				outputPath = strings.TrimSuffix(path, filepath.Ext(path)) + "-grid.png"
+			}
+
+			gridifier := Gridifier{
+				Pack:     pack,
+				InputDir: filepath.Dir(path),
+				Cell:     cellSize,
+				Columns:  columns,
+				Fit:      fit,
+			}
+
+			grid, index, err := gridifier.gridify()
+			if err != nil {
+				return err
+			}
+			index.Image = filepath.Base(outputPath)
+
+			outputFile, err := os.Create(outputPath)
+			if err != nil {
+				return fmt.Errorf("failed to open output file: %w", err)
+			}
+
+			if err = png.Encode(outputFile, grid); err != nil {
+				outputFile.Close()
+				return fmt.Errorf("failed to encode grid as png: %w", err)
+			}
+
+			if err = outputFile.Close(); err != nil {
+				return fmt.Errorf("failed to write output file: %w", err)
+			}
+
+			data, err := json.MarshalIndent(index, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode grid index: %w", err)
+			}
+
+			indexPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".json"
+			if err = os.WriteFile(indexPath, data, 0o644); err != nil {
+				return fmt.Errorf("failed to write grid index: %w", err)
+			}
+
+			logf("[info] placed %d textures on a %dx%d grid\n", len(index.Frames), index.Columns, index.Rows)
+			logf("[info] wrote %s and %s\n", outputPath, indexPath)
+
+			return nil
+		},
+	}
+
+	gridifyCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output grid image")
+	gridifyCmd.Flags().StringVarP(&cell, "cell", "", cell, "Cell size as WxH")
+	gridifyCmd.Flags().IntVarP(&columns, "columns", "c", columns, "Number of grid columns (0 for square)")
+	gridifyCmd.Flags().StringVarP(&fit, "fit", "", fit, "Fit mode for frames: center or scale")
+
+	return gridifyCmd
+}
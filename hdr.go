@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	image.RegisterFormat("hdr", "#?", decodeRadiance, decodeRadianceConfig)
+	image.RegisterFormat("pfm", "PF", decodePFM, decodePFMConfig)
+	image.RegisterFormat("pfm", "Pf", decodePFM, decodePFMConfig)
+}
+
+// readRadianceHeader skips the header's variables up to the blank line and
+// reads the resolution line, accepting only the usual top-down orientation.
+func readRadianceHeader(r *bufio.Reader) (int, int, error) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid Radiance HDR header: %w", err)
+		}
+		if strings.HasPrefix(line, "FORMAT=") && strings.TrimSpace(line) != "FORMAT=32-bit_rle_rgbe" {
+			return 0, 0, fmt.Errorf("unsupported Radiance HDR %s", strings.TrimSpace(line))
+		}
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+	}
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid Radiance HDR resolution: %w", err)
+	}
+	var width, height int
+	if _, err := fmt.Sscanf(line, "-Y %d +X %d", &height, &width); err != nil {
+		return 0, 0, fmt.Errorf("unsupported Radiance HDR orientation %q", strings.TrimSpace(line))
+	}
+	return width, height, nil
+}
+
+func decodeRadianceConfig(r io.Reader) (image.Config, error) {
+	width, height, err := readRadianceHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: FloatModel, Width: width, Height: height}, nil
+}
+
+// readRGBEScanline reads one scanline, either run-length encoded one
+// component at a time or as flat RGBE pixels.
+func readRGBEScanline(r *bufio.Reader, scanline []byte) error {
+	width := len(scanline) / 4
+	head, err := r.Peek(4)
+	if err != nil {
+		return err
+	}
+
+	if width < 8 || width > 0x7fff || head[0] != 2 || head[1] != 2 || head[2]&0x80 != 0 {
+		_, err := io.ReadFull(r, scanline)
+		return err
+	}
+	if int(head[2])<<8|int(head[3]) != width {
+		return fmt.Errorf("scanline width mismatch")
+	}
+	r.Discard(4)
+
+	for component := range 4 {
+		for x := 0; x < width; {
+			count, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			if count > 128 {
+				count -= 128
+				value, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+				if x+int(count) > width {
+					return fmt.Errorf("run past the end of the scanline")
+				}
+				for range count {
+					scanline[x*4+component] = value
+					x++
+				}
+				continue
+			}
+			if count == 0 || x+int(count) > width {
+				return fmt.Errorf("invalid run length")
+			}
+			for range count {
+				value, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+				scanline[x*4+component] = value
+				x++
+			}
+		}
+	}
+	return nil
+}
+
+// decodeRadiance reads a Radiance RGBE image, the common .hdr format for
+// lightmaps and environment maps.
+func decodeRadiance(r io.Reader) (image.Image, error) {
+	reader := bufio.NewReader(r)
+	width, height, err := readRadianceHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	img := NewFloatImage(image.Rect(0, 0, width, height))
+	scanline := make([]byte, width*4)
+	for y := range height {
+		if err := readRGBEScanline(reader, scanline); err != nil {
+			return nil, fmt.Errorf("invalid Radiance HDR scanline %d: %w", y, err)
+		}
+		for x := range width {
+			rgbe := scanline[x*4 : x*4+4]
+			if rgbe[3] == 0 {
+				img.SetFloat(x, y, FloatColor{A: 1})
+				continue
+			}
+			scale := float32(math.Ldexp(1, int(rgbe[3])-136))
+			img.SetFloat(x, y, FloatColor{float32(rgbe[0]) * scale, float32(rgbe[1]) * scale, float32(rgbe[2]) * scale, 1})
+		}
+	}
+	return img, nil
+}
+
+// readPFMHeader reads the three whitespace-separated header tokens, the
+// scale's sign giving the byte order.
+func readPFMHeader(r *bufio.Reader) (channels, width, height int, order binary.ByteOrder, err error) {
+	var tokens []string
+	for len(tokens) < 4 {
+		token, err := readPFMToken(r)
+		if err != nil {
+			return 0, 0, 0, nil, fmt.Errorf("invalid PFM header: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	switch tokens[0] {
+	case "PF":
+		channels = 3
+	case "Pf":
+		channels = 1
+	default:
+		return 0, 0, 0, nil, fmt.Errorf("invalid PFM magic %q", tokens[0])
+	}
+	width, errW := strconv.Atoi(tokens[1])
+	height, errH := strconv.Atoi(tokens[2])
+	scale, errS := strconv.ParseFloat(tokens[3], 64)
+	if errW != nil || errH != nil || errS != nil || width <= 0 || height <= 0 {
+		return 0, 0, 0, nil, fmt.Errorf("invalid PFM header %q", strings.Join(tokens, " "))
+	}
+
+	order = binary.ByteOrder(binary.BigEndian)
+	if scale < 0 {
+		order = binary.LittleEndian
+	}
+	return channels, width, height, order, nil
+}
+
+// readPFMToken reads one token, consuming exactly one whitespace byte after
+// it so the raster starts right where the header ends.
+func readPFMToken(r *bufio.Reader) (string, error) {
+	var token bytes.Buffer
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			if token.Len() == 0 {
+				continue
+			}
+			if b == '\r' {
+				if next, err := r.Peek(1); err == nil && next[0] == '\n' {
+					r.Discard(1)
+				}
+			}
+			return token.String(), nil
+		}
+		token.WriteByte(b)
+	}
+}
+
+func decodePFMConfig(r io.Reader) (image.Config, error) {
+	_, width, height, _, err := readPFMHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: FloatModel, Width: width, Height: height}, nil
+}
+
+// decodePFM reads a Portable Float Map, whose rows run bottom to top.
+func decodePFM(r io.Reader) (image.Image, error) {
+	reader := bufio.NewReader(r)
+	channels, width, height, order, err := readPFMHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	img := NewFloatImage(image.Rect(0, 0, width, height))
+	row := make([]float32, width*channels)
+	for y := height - 1; y >= 0; y-- {
+		if err := binary.Read(reader, order, row); err != nil {
+			return nil, fmt.Errorf("invalid PFM raster: %w", err)
+		}
+		for x := range width {
+			if channels == 1 {
+				v := row[x]
+				img.SetFloat(x, y, FloatColor{v, v, v, 1})
+			} else {
+				img.SetFloat(x, y, FloatColor{row[x*3], row[x*3+1], row[x*3+2], 1})
+			}
+		}
+	}
+	return img, nil
+}
+
+// pfmSprites writes color Portable Float Maps. PFM has no alpha, so the
+// premultiplied color is written as is.
+type pfmSprites struct{}
+
+func (pfmSprites) Extension() string { return ".pfm" }
+
+func (pfmSprites) Encode(w io.Writer, img image.Image) error {
+	float := toFloatImage(img)
+	bounds := float.Bounds()
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "PF\n%d %d\n-1.0\n", bounds.Dx(), bounds.Dy())
+	row := make([]float32, bounds.Dx()*3)
+	for y := bounds.Max.Y - 1; y >= bounds.Min.Y; y-- {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := float.FloatAt(x, y)
+			i := (x - bounds.Min.X) * 3
+			row[i], row[i+1], row[i+2] = c.R, c.G, c.B
+		}
+		binary.Write(out, binary.LittleEndian, row)
+	}
+	return out.Flush()
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// A ColorHistogram counts pixels by color. Fully transparent pixels are all
+// counted as transparent black, since their color is never seen.
+type ColorHistogram map[color.NRGBA]int
+
+func (histogram ColorHistogram) add(img image.Image, region image.Rectangle) {
+	region = region.Intersect(img.Bounds())
+
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		for x := region.Min.X; x < region.Max.X; x++ {
+			px := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if px.A == 0 {
+				px = color.NRGBA{}
+			}
+			histogram[px]++
+		}
+	}
+}
+
+// fits reports whether every color would get its own entry in an n-color
+// palette, making quantizing to n colors lossless.
+func (histogram ColorHistogram) fits(n int) bool {
+	return len(histogram) <= n
+}
+
+// top returns the n most frequent colors, ties broken by color so the order
+// is stable between runs.
+func (histogram ColorHistogram) top(n int) []colorCount {
+	counts := make([]colorCount, 0, len(histogram))
+	for c, count := range histogram {
+		counts = append(counts, colorCount{c, count})
+	}
+	slices.SortFunc(counts, func(a, b colorCount) int {
+		if a.count != b.count {
+			return cmp.Compare(b.count, a.count)
+		}
+		return cmp.Compare(hexAlphaColor(a.color), hexAlphaColor(b.color))
+	})
+	return counts[:min(n, len(counts))]
+}
+
+func hexAlphaColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
+
+type HistogramEntry struct {
+	Color string  `json:"color"`
+	Count int     `json:"count"`
+	Share float64 `json:"share"`
+}
+
+type SheetHistogram struct {
+	Image        string           `json:"image"`
+	Pixels       int              `json:"pixels"`
+	UniqueColors int              `json:"uniqueColors"`
+	Palette      bool             `json:"palette"`
+	Top          []HistogramEntry `json:"top"`
+}
+
+// frameRegion returns the sheet pixels a frame covers, turned on its side
+// when the frame is rotated.
+func frameRegion(tex Texture) image.Rectangle {
+	if tex.Rotated {
+		return image.Rect(tex.Frame.X, tex.Frame.Y, tex.Frame.X+tex.Frame.Height, tex.Frame.Y+tex.Frame.Width)
+	}
+	return tex.Frame.Rect()
+}
+
+// histogramSheet counts the colors of a sheet's frames, leaving out the
+// padding and empty space between them.
+func histogramSheet(inputDir string, sheet Sheet, top int) (SheetHistogram, error) {
+	img, err := decodeSheet(inputDir, sheet)
+	if err != nil {
+		return SheetHistogram{}, err
+	}
+
+	histogram := make(ColorHistogram)
+	for _, tex := range sheet.Textures {
+		histogram.add(img, frameRegion(tex))
+	}
+
+	result := SheetHistogram{
+		Image:        sheet.Image,
+		UniqueColors: len(histogram),
+		Palette:      histogram.fits(256),
+	}
+	for _, count := range histogram {
+		result.Pixels += count
+	}
+	for _, entry := range histogram.top(top) {
+		result.Top = append(result.Top, HistogramEntry{
+			Color: hexAlphaColor(entry.color),
+			Count: entry.count,
+			Share: float64(entry.count) / float64(result.Pixels),
+		})
+	}
+	return result, nil
+}
+
+func newColorsCmd() *cobra.Command {
+	var top int = 8
+	var asJSON bool = false
+
+	var colorsCmd = &cobra.Command{
+		Use:   "colors <path>",
+		Short: "Report each sheet's color histogram and whether it fits a 256-color palette",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var path = args[0]
+			format, _ := cmd.Flags().GetString("format")
+
+			if top < 0 {
+				return fmt.Errorf("invalid top %d, expected 0 or more", top)
+			}
+
+			pack, err := loadPack(path, format)
+			if err != nil {
+				return err
+			}
+
+			histograms := make([]SheetHistogram, 0, len(pack.Sheets))
+			for _, sh := range pack.Sheets {
+				histogram, err := histogramSheet(filepath.Dir(path), sh, top)
+				if err != nil {
+					return err
+				}
+				histograms = append(histograms, histogram)
+			}
+
+			if asJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(histograms)
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, histogram := range histograms {
+				fmt.Fprintf(writer, "%s\t%d pixels\t%d colors\t\n", histogram.Image, histogram.Pixels, histogram.UniqueColors)
+				for _, entry := range histogram.Top {
+					fmt.Fprintf(writer, "  %s\t%d\t%.1f%%\t\n", entry.Color, entry.Count, entry.Share*100)
+				}
+			}
+			writer.Flush()
+
+			for _, histogram := range histograms {
+				if histogram.Palette {
+					logf("[info] %s fits in %d colors, so --quantize %d is lossless\n", histogram.Image, histogram.UniqueColors, max(2, histogram.UniqueColors))
+				}
+			}
+
+			return nil
+		},
+	}
+
+	colorsCmd.Flags().IntVarP(&top, "top", "", top, "Number of most frequent colors to list per sheet")
+	colorsCmd.Flags().BoolVarP(&asJSON, "json", "", asJSON, "Print the histograms as JSON")
+
+	return colorsCmd
+}
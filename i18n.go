@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+var languages = []string{"en", "ja", "zh"}
+
+// language is the language messages are printed in, chosen by --lang or the
+// locale environment.
+var language = "en"
+
+// catalogs translate messages keyed by their English text, so a message
+// without a translation falls back to English as is. Translations keep the
+// format verbs, flags, mode names, and [info] and [warn] prefixes of the
+// English text, using explicit argument indexes where the word order differs.
+var catalogs = map[string]map[string]string{
+	"ja": {
+		"Error:": "エラー:",
+		"[info] computed %d pivots from sprite pixels\n":              "[info] スプライトのピクセルから %d 個のピボットを算出しました\n",
+		"[info] decoding %s alone, its %s exceed --max-memory\n":      "[info] %s のデコード後のサイズ %s が --max-memory を超えるため、単独で処理します\n",
+		"[info] extracted %d textures from %d sheets\n":               "[info] %[2]d 枚のシートから %[1]d 個のテクスチャを抽出しました\n",
+		"[info] extracting %d frames from %s\n":                       "[info] %[2]s から %[1]d フレームを抽出します\n",
+		"[info] finished %s, %d frames in %.2fs\n":                    "[info] %s を完了しました。%d フレーム、%.2f 秒\n",
+		"[info] found %d aliased frames\n":                            "[info] エイリアスのフレームが %d 個見つかりました\n",
+		"[info] found %d atlases in loader manifest\n":                "[info] ローダーマニフェストにアトラスが %d 個見つかりました\n",
+		"[info] found %d resolution variants\n":                       "[info] 解像度のバリアントが %d 個見つかりました\n",
+		"[info] found %d texture sheets\n":                            "[info] テクスチャシートが %d 枚見つかりました\n",
+		"[info] resuming, %d frames already done\n":                   "[info] 再開します。%d フレームは完了済みです\n",
+		"[info] shrinking frames by %dpx of extrusion\n":              "[info] 押し出し分 %dpx だけフレームを縮小します\n",
+		"[info] writing to %s\n":                                      "[info] %s に書き出します\n",
+		"[info] wrote %s to %s\n":                                     "[info] %s を %s に書き出しました\n",
+		"[warn] %s is from another atlas or version, starting over\n": "[warn] %s は別のアトラスまたはバージョンのものなので、最初からやり直します\n",
+		"[warn] failed to extract %q from %s: %v\n":                   "[warn] %[2]s からの %[1]q の抽出に失敗しました: %[3]v\n",
+		"[warn] failed to read %s: %v\n":                              "[warn] %s を読み込めませんでした: %v\n",
+		"[warn] skipping %q, its output %s is taken by %q\n":          "[warn] 出力先 %[2]s は %[3]q が使用しているため、%[1]q をスキップします\n",
+		"[warn] writing %q to %s, its output %s is taken by %q\n":     "[warn] 出力先 %[3]s は %[4]q が使用しているため、%[1]q を %[2]s に書き出します\n",
+		"--error-report requires --keep-going":                        "--error-report には --keep-going が必要です",
+		"--quiet and --verbose cannot be combined":                    "--quiet と --verbose は同時に指定できません",
+		"--skip-aliases requires --manifest to record the aliases":    "--skip-aliases はエイリアスを記録するために --manifest が必要です",
+		"augmentations require --export dataset":                      "データ拡張には --export dataset が必要です",
+		"failed to convert %s: %w":                                    "%s の変換に失敗しました: %w",
+		"failed to create output directory: %w":                       "出力ディレクトリの作成に失敗しました: %w",
+		"failed to decode texture sheet %s: %w":                       "テクスチャシート %s のデコードに失敗しました: %w",
+		"failed to encode resume state: %w":                           "再開用の状態のエンコードに失敗しました: %w",
+		"failed to encode sprite as %s: %w":                           "スプライトの %s へのエンコードに失敗しました: %w",
+		"failed to hash %s: %w":                                       "%s のハッシュ計算に失敗しました: %w",
+		"failed to open output file: %w":                              "出力ファイルを開けませんでした: %w",
+		"failed to open resume state: %w":                             "再開用の状態を開けませんでした: %w",
+		"failed to open texture sheet: %w":                            "テクスチャシートを開けませんでした: %w",
+		"failed to write output file: %w":                             "出力ファイルの書き込みに失敗しました: %w",
+		"failed to write resume state: %w":                            "再開用の状態の書き込みに失敗しました: %w",
+		"frames %q and %q would both be written to %s (%d collisions), pass --on-collision skip or suffix to keep going": "フレーム %q と %q がどちらも %s に書き出されます (衝突 %d 件)。続行するには --on-collision skip または suffix を指定してください",
+		"invalid %s %d, must not be negative":                                                                          "%s %d は無効です。負の値は指定できません",
+		"invalid auto-pivot %q, expected none, center, bottom-center, or opaque-centroid":                              "auto-pivot %q は無効です。none、center、bottom-center、opaque-centroid のいずれかを指定してください",
+		"invalid checkpoint-frames %d, must be at least 1":                                                             "checkpoint-frames %d は無効です。1 以上を指定してください",
+		"invalid collision mode %q, expected error, skip, or suffix":                                                   "衝突モード %q は無効です。error、skip、suffix のいずれかを指定してください",
+		"invalid color mode %q, expected auto, always, or never":                                                       "カラーモード %q は無効です。auto、always、never のいずれかを指定してください",
+		"invalid dedup mode %q, expected none, hardlink, symlink, or copy":                                             "dedup モード %q は無効です。none、hardlink、symlink、copy のいずれかを指定してください",
+		"invalid export mode %q, expected dataset, locales, or montage":                                                "エクスポートモード %q は無効です。dataset、locales、montage のいずれかを指定してください",
+		"invalid flatten-char %q, must be non-empty and valid in file names":                                           "flatten-char %q は無効です。ファイル名に使える空でない文字を指定してください",
+		"invalid language %q, expected en, ja, or zh":                                                                  "言語 %q は無効です。en、ja、zh のいずれかを指定してください",
+		"invalid log format %q, expected text or json":                                                                 "ログ形式 %q は無効です。text または json を指定してください",
+		"invalid max-memory %d, must not be negative":                                                                  "max-memory %d は無効です。負の値は指定できません",
+		"invalid nine-slice mode %q, expected none, json, or split":                                                    "nine-slice モード %q は無効です。none、json、split のいずれかを指定してください",
+		"invalid overlap %d, expected 0 to under half the tile size":                                                   "overlap %d は無効です。0 以上、タイルサイズの半分未満を指定してください",
+		"invalid progress %q, expected bar, total, or json":                                                            "progress %q は無効です。bar、total、json のいずれかを指定してください",
+		"invalid scale %g, must be positive":                                                                           "scale %g は無効です。正の値を指定してください",
+		"invalid shrink %d, must not be negative":                                                                      "shrink %d は無効です。負の値は指定できません",
+		"invalid sidecar %q, expected none or json":                                                                    "sidecar %q は無効です。none または json を指定してください",
+		"invalid size %q, dimensions must be positive":                                                                 "サイズ %q は無効です。幅と高さは正の値にしてください",
+		"invalid size %q, expected WxH":                                                                                "サイズ %q は無効です。WxH の形式で指定してください",
+		"invalid summary %q, expected table, json, or none":                                                            "summary %q は無効です。table、json、none のいずれかを指定してください",
+		"invalid symlinks mode %q, expected reject or follow":                                                          "symlinks モード %q は無効です。reject または follow を指定してください",
+		"invalid tile size %d":                                                                                         "タイルサイズ %d は無効です",
+		"invalid trim mode %q, expected full or tight":                                                                 "トリムモード %q は無効です。full または tight を指定してください",
+		"no %s variant of %s, found %s":                                                                                "%[2]s に %[1]s のバリアントはありません。見つかったもの: %[3]s",
+		"output directory %s is not empty and was not written by an earlier run, pass --force to write into it anyway": "出力ディレクトリ %s は空ではなく、以前の実行で書き出されたものでもありません。それでも書き込むには --force を指定してください",
+	},
+	"zh": {
+		"Error:": "错误:",
+		"[info] computed %d pivots from sprite pixels\n":              "[info] 已根据精灵像素计算出 %d 个轴心点\n",
+		"[info] decoding %s alone, its %s exceed --max-memory\n":      "[info] %s 解码后的 %s 超过 --max-memory，因此单独处理\n",
+		"[info] extracted %d textures from %d sheets\n":               "[info] 已从 %[2]d 张图集中提取 %[1]d 个纹理\n",
+		"[info] extracting %d frames from %s\n":                       "[info] 正在从 %[2]s 提取 %[1]d 帧\n",
+		"[info] finished %s, %d frames in %.2fs\n":                    "[info] 已完成 %s，%d 帧，用时 %.2f 秒\n",
+		"[info] found %d aliased frames\n":                            "[info] 发现 %d 个别名帧\n",
+		"[info] found %d atlases in loader manifest\n":                "[info] 在加载器清单中发现 %d 个图集\n",
+		"[info] found %d resolution variants\n":                       "[info] 发现 %d 个分辨率变体\n",
+		"[info] found %d texture sheets\n":                            "[info] 发现 %d 张纹理图集\n",
+		"[info] resuming, %d frames already done\n":                   "[info] 继续上次运行，已完成 %d 帧\n",
+		"[info] shrinking frames by %dpx of extrusion\n":              "[info] 按 %dpx 的挤出边距收缩帧\n",
+		"[info] writing to %s\n":                                      "[info] 正在写入 %s\n",
+		"[info] wrote %s to %s\n":                                     "[info] 已将 %s 写入 %s\n",
+		"[warn] %s is from another atlas or version, starting over\n": "[warn] %s 来自其他图集或版本，将重新开始\n",
+		"[warn] failed to extract %q from %s: %v\n":                   "[warn] 从 %[2]s 提取 %[1]q 失败: %[3]v\n",
+		"[warn] failed to read %s: %v\n":                              "[warn] 读取 %s 失败: %v\n",
+		"[warn] skipping %q, its output %s is taken by %q\n":          "[warn] 跳过 %[1]q，其输出 %[2]s 已被 %[3]q 占用\n",
+		"[warn] writing %q to %s, its output %s is taken by %q\n":     "[warn] 将 %[1]q 写入 %[2]s，其输出 %[3]s 已被 %[4]q 占用\n",
+		"--error-report requires --keep-going":                        "--error-report 需要 --keep-going",
+		"--quiet and --verbose cannot be combined":                    "--quiet 和 --verbose 不能同时使用",
+		"--skip-aliases requires --manifest to record the aliases":    "--skip-aliases 需要 --manifest 来记录别名",
+		"augmentations require --export dataset":                      "数据增强需要 --export dataset",
+		"failed to convert %s: %w":                                    "转换 %s 失败: %w",
+		"failed to create output directory: %w":                       "创建输出目录失败: %w",
+		"failed to decode texture sheet %s: %w":                       "解码纹理图集 %s 失败: %w",
+		"failed to encode resume state: %w":                           "编码续传状态失败: %w",
+		"failed to encode sprite as %s: %w":                           "将精灵编码为 %s 失败: %w",
+		"failed to hash %s: %w":                                       "计算 %s 的哈希失败: %w",
+		"failed to open output file: %w":                              "打开输出文件失败: %w",
+		"failed to open resume state: %w":                             "打开续传状态失败: %w",
+		"failed to open texture sheet: %w":                            "打开纹理图集失败: %w",
+		"failed to write output file: %w":                             "写入输出文件失败: %w",
+		"failed to write resume state: %w":                            "写入续传状态失败: %w",
+		"frames %q and %q would both be written to %s (%d collisions), pass --on-collision skip or suffix to keep going": "帧 %q 和 %q 都会写入 %s（共 %d 处冲突），请传入 --on-collision skip 或 suffix 以继续",
+		"invalid %s %d, must not be negative":                                                                          "无效的 %s %d，不能为负数",
+		"invalid auto-pivot %q, expected none, center, bottom-center, or opaque-centroid":                              "无效的 auto-pivot %q，应为 none、center、bottom-center 或 opaque-centroid",
+		"invalid checkpoint-frames %d, must be at least 1":                                                             "无效的 checkpoint-frames %d，至少应为 1",
+		"invalid collision mode %q, expected error, skip, or suffix":                                                   "无效的冲突模式 %q，应为 error、skip 或 suffix",
+		"invalid color mode %q, expected auto, always, or never":                                                       "无效的颜色模式 %q，应为 auto、always 或 never",
+		"invalid dedup mode %q, expected none, hardlink, symlink, or copy":                                             "无效的去重模式 %q，应为 none、hardlink、symlink 或 copy",
+		"invalid export mode %q, expected dataset, locales, or montage":                                                "无效的导出模式 %q，应为 dataset、locales 或 montage",
+		"invalid flatten-char %q, must be non-empty and valid in file names":                                           "无效的 flatten-char %q，必须非空且可用于文件名",
+		"invalid language %q, expected en, ja, or zh":                                                                  "无效的语言 %q，应为 en、ja 或 zh",
+		"invalid log format %q, expected text or json":                                                                 "无效的日志格式 %q，应为 text 或 json",
+		"invalid max-memory %d, must not be negative":                                                                  "无效的 max-memory %d，不能为负数",
+		"invalid nine-slice mode %q, expected none, json, or split":                                                    "无效的九宫格模式 %q，应为 none、json 或 split",
+		"invalid overlap %d, expected 0 to under half the tile size":                                                   "无效的 overlap %d，应在 0 到图块尺寸一半以内",
+		"invalid progress %q, expected bar, total, or json":                                                            "无效的 progress %q，应为 bar、total 或 json",
+		"invalid scale %g, must be positive":                                                                           "无效的 scale %g，必须为正数",
+		"invalid shrink %d, must not be negative":                                                                      "无效的 shrink %d，不能为负数",
+		"invalid sidecar %q, expected none or json":                                                                    "无效的 sidecar %q，应为 none 或 json",
+		"invalid size %q, dimensions must be positive":                                                                 "无效的尺寸 %q，宽高必须为正数",
+		"invalid size %q, expected WxH":                                                                                "无效的尺寸 %q，应为 WxH 格式",
+		"invalid summary %q, expected table, json, or none":                                                            "无效的 summary %q，应为 table、json 或 none",
+		"invalid symlinks mode %q, expected reject or follow":                                                          "无效的 symlinks 模式 %q，应为 reject 或 follow",
+		"invalid tile size %d":                                                                                         "无效的图块尺寸 %d",
+		"invalid trim mode %q, expected full or tight":                                                                 "无效的裁剪模式 %q，应为 full 或 tight",
+		"no %s variant of %s, found %s":                                                                                "%[2]s 没有 %[1]s 变体，找到的有 %[3]s",
+		"output directory %s is not empty and was not written by an earlier run, pass --force to write into it anyway": "输出目录 %s 非空且不是之前的运行写入的，如仍要写入请传入 --force",
+	},
+}
+
+// tr translates a message into the chosen language.
+func tr(message string) string {
+	if translated, ok := catalogs[language][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// detectLanguage reads the language from the locale environment in the
+// order POSIX gives it precedence, like ja_JP.UTF-8, falling back to English.
+func detectLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		code, _, _ := strings.Cut(strings.ToLower(value), "_")
+		code, _, _ = strings.Cut(code, ".")
+		if _, ok := catalogs[code]; ok {
+			return code
+		}
+		return "en"
+	}
+	return "en"
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sourceTime is when the atlas or the sheet image last changed, whichever
+// is later. Outputs older than that may be stale.
+func (unpacker Unpacker) sourceTime(sheet Sheet) time.Time {
+	var latest time.Time
+	for _, path := range []string{unpacker.AtlasPath, filepath.Join(unpacker.InputDir, sheet.Image)} {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// upToDate reports whether an earlier run's output for tex can be kept.
+// With SkipExisting the file has to exist and, when that run left a
+// manifest, match the hash it recorded, so files a crashed or edited run
+// left behind are extracted again. With OnlyNewer it has to be newer than
+// since instead, which reads no files.
+func (unpacker Unpacker) upToDate(tex Texture, since time.Time) bool {
+	path := unpacker.outputPath(tex)
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	if unpacker.OnlyNewer && !info.ModTime().After(since) {
+		return false
+	}
+
+	if unpacker.SkipExisting && unpacker.previous != nil {
+		frame, ok := unpacker.previous.Frames[tex.FileName]
+		rel, _ := filepath.Rel(unpacker.OutputDir, path)
+		if !ok || frame.SHA256 == "" || frame.Path != filepath.ToSlash(rel) {
+			return false
+		}
+		sum, err := hashFile(path)
+		if err != nil || sum != frame.SHA256 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// freshFrames lists the frames of sheet whose outputs are up to date or
+// that a resumed run already finished, or nil when the run is neither
+// incremental nor resumed.
+func (unpacker Unpacker) freshFrames(sheet Sheet) map[textureKey]bool {
+	incremental := unpacker.SkipExisting || unpacker.OnlyNewer
+	if !incremental && unpacker.resume == nil {
+		return nil
+	}
+
+	since := unpacker.sourceTime(sheet)
+	fresh := make(map[textureKey]bool)
+	for _, tex := range sheet.Textures {
+		if unpacker.resume.completed(tex.FileName, unpacker.outputPath(tex)) || incremental && unpacker.upToDate(tex, since) {
+			fresh[keyOf(tex)] = true
+		}
+	}
+	return fresh
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+type IndexEntry struct {
+	Frame string `json:"frame"`
+	Pack  string `json:"pack"`
+	Sheet string `json:"sheet"`
+}
+
+// A FrameIndex maps pixel hashes to every indexed frame with those pixels,
+// so a sprite found loose on disk can be traced back to the packs it came
+// from. It is only ever built by hand with index add.
+type FrameIndex struct {
+	Frames map[string][]IndexEntry `json:"frames"`
+}
+
+func defaultIndexPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "txunpak-index.json"
+	}
+	return filepath.Join(dir, "phaser-unpacker", "index.json")
+}
+
+// loadFrameIndex reads the index, starting an empty one when there is none yet.
+func loadFrameIndex(path string) (*FrameIndex, error) {
+	index := &FrameIndex{Frames: make(map[string][]IndexEntry)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("invalid index %s: %w", path, err)
+	}
+	if index.Frames == nil {
+		index.Frames = make(map[string][]IndexEntry)
+	}
+	return index, nil
+}
+
+// save replaces the index through a rename, like the manifest, so a crash
+// never leaves it truncated.
+func (index *FrameIndex) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode index: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// add indexes every frame of the pack at packPath, replacing what an earlier
+// add of the same pack recorded.
+func (index *FrameIndex) add(pack Pack, packPath string) (int, error) {
+	for hash, entries := range index.Frames {
+		entries = slices.DeleteFunc(entries, func(entry IndexEntry) bool { return entry.Pack == packPath })
+		if len(entries) == 0 {
+			delete(index.Frames, hash)
+		} else {
+			index.Frames[hash] = entries
+		}
+	}
+
+	composer := newComposer(pack, filepath.Dir(packPath))
+	count := 0
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			sprite, err := composer.sprite(tex.FileName)
+			if err != nil {
+				return 0, err
+			}
+			hash := pixelHash(sprite)
+			index.Frames[hash] = append(index.Frames[hash], IndexEntry{Frame: tex.FileName, Pack: packPath, Sheet: sh.Image})
+			count++
+		}
+	}
+	return count, nil
+}
+
+func newIndexCmd() *cobra.Command {
+	var indexPath string = defaultIndexPath()
+
+	var indexCmd = &cobra.Command{
+		Use:   "index",
+		Short: "Keep a local index of unpacked frames to trace loose sprites back to their packs",
+	}
+
+	var addCmd = &cobra.Command{
+		Use:   "add <atlas>...",
+		Short: "Add every frame of the atlases to the index",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+
+			index, err := loadFrameIndex(indexPath)
+			if err != nil {
+				return err
+			}
+
+			for _, path := range args {
+				packPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("failed to resolve %s: %w", path, err)
+				}
+				pack, err := loadPack(packPath, format)
+				if err != nil {
+					return err
+				}
+				count, err := index.add(pack, packPath)
+				if err != nil {
+					return err
+				}
+				logf("[info] indexed %d frames from %s\n", count, packPath)
+			}
+
+			return index.save(indexPath)
+		},
+	}
+
+	var asJSON bool = false
+	var lookupCmd = &cobra.Command{
+		Use:   "lookup <image>...",
+		Short: "Find the indexed packs and frames with the same pixels as the images",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			index, err := loadFrameIndex(indexPath)
+			if err != nil {
+				return err
+			}
+
+			matches := make(map[string][]IndexEntry)
+			for _, path := range args {
+				img, err := decodeImageFile(path)
+				if err != nil {
+					return err
+				}
+				matches[path] = append([]IndexEntry{}, index.Frames[pixelHash(img)]...)
+			}
+
+			if asJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(matches)
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "IMAGE\tFRAME\tSHEET\tPACK")
+			for _, path := range args {
+				if len(matches[path]) == 0 {
+					fmt.Fprintf(writer, "%s\t-\t-\tnot indexed\n", path)
+				}
+				for _, entry := range matches[path] {
+					fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", path, entry.Frame, entry.Sheet, entry.Pack)
+				}
+			}
+			return writer.Flush()
+		},
+	}
+	lookupCmd.Flags().BoolVarP(&asJSON, "json", "", asJSON, "Print matches as JSON")
+
+	indexCmd.PersistentFlags().StringVarP(&indexPath, "index", "", indexPath, "Index file")
+	indexCmd.AddCommand(addCmd, lookupCmd)
+
+	return indexCmd
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+type FrameFailure struct {
+	Sheet string `json:"sheet"`
+	Frame string `json:"frame,omitempty"`
+	Error string `json:"error"`
+}
+
+// A FailureLog collects what failed with --keep-going, so one bad frame or
+// sheet is reported at the end instead of stopping everything else. A
+// failure without a frame is a whole sheet that could not be read.
+type FailureLog struct {
+	mu       sync.Mutex
+	failures []FrameFailure
+	frames   int
+}
+
+func (log *FailureLog) add(sheet, frame string, frames int, err error) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	log.failures = append(log.failures, FrameFailure{Sheet: sheet, Frame: frame, Error: err.Error()})
+	log.frames += frames
+}
+
+func (log *FailureLog) failed() bool {
+	return log != nil && len(log.failures) > 0
+}
+
+// report lists every failure, writes them as JSON to path when it is set,
+// and returns the error the run ends with.
+func (log *FailureLog) report(path string) error {
+	slices.SortFunc(log.failures, func(a, b FrameFailure) int {
+		return cmp.Or(strings.Compare(a.Sheet, b.Sheet), strings.Compare(a.Frame, b.Frame))
+	})
+
+	if porcelainOut != nil {
+		for _, failure := range log.failures {
+			porcelainOut.record("fail", failure.Sheet, failure.Frame, failure.Error)
+		}
+	} else {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "SHEET\tFRAME\tERROR")
+		for _, failure := range log.failures {
+			frame := failure.Frame
+			if frame == "" {
+				frame = "(whole sheet)"
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\n", failure.Sheet, frame, failure.Error)
+		}
+		writer.Flush()
+	}
+
+	if path != "" {
+		data, err := json.MarshalIndent(log.failures, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode error report: %w", err)
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			return withExitCode(exitOutput, fmt.Errorf("failed to write error report: %w", err))
+		}
+		logf("[info] wrote error report to %s\n", path)
+	}
+
+	return withExitCode(exitPartial, fmt.Errorf("%d frames failed, the rest were extracted", log.frames))
+}
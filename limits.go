@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io"
+)
+
+// DecodeLimits cap the size of any image read or allocated, so a hostile pack
+// cannot exhaust memory through a crafted PNG header or an absurd frame size.
+type DecodeLimits struct {
+	MaxDimension int
+	MaxPixels    int64
+}
+
+// 16384 is the largest texture most GPUs accept, and 2^28 pixels is 1 GiB of RGBA.
+var decodeLimits = DecodeLimits{MaxDimension: 16384, MaxPixels: 1 << 28}
+
+func (limits DecodeLimits) check(width, height int) error {
+	if width < 0 || height < 0 {
+		return fmt.Errorf("invalid image size %dx%d", width, height)
+	}
+	if width > limits.MaxDimension || height > limits.MaxDimension {
+		return fmt.Errorf("image size %dx%d exceeds the %d pixel dimension limit, raise --max-dimension to allow it", width, height, limits.MaxDimension)
+	}
+	if int64(width)*int64(height) > limits.MaxPixels {
+		return fmt.Errorf("image size %dx%d exceeds the %d pixel count limit, raise --max-pixels to allow it", width, height, limits.MaxPixels)
+	}
+	return nil
+}
+
+// decodeImage reads the header first, so an oversized image is refused before
+// its pixel buffer is allocated.
+func decodeImage(r io.ReadSeeker) (image.Image, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeLimits.check(config.Width, config.Height); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// newCanvas allocates a canvas whose size came from input data, refusing
+// sizes past the limits instead of allocating them.
+func newCanvas(width, height int) (*image.RGBA, error) {
+	if err := decodeLimits.check(width, height); err != nil {
+		return nil, err
+	}
+	return image.NewRGBA(image.Rect(0, 0, width, height)), nil
+}
+
+// checkPackLimits refuses packs whose sheets or frames would allocate more
+// than the limits allow once rendered.
+func checkPackLimits(pack Pack, path string) error {
+	for _, sh := range pack.Sheets {
+		if err := decodeLimits.check(sh.Size.Width, sh.Size.Height); err != nil {
+			return fmt.Errorf("invalid sheet %s in %s: %w", sh.Image, path, err)
+		}
+		for _, tex := range sh.Textures {
+			if err := decodeLimits.check(tex.SourceSize.Width, tex.SourceSize.Height); err != nil {
+				return fmt.Errorf("invalid frame %q in %s: %w", tex.FileName, path, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// LintRule configures one rule. Pattern is read by naming and Size by
+// max-frame-size; the other rules take only a level.
+type LintRule struct {
+	Level   string `yaml:"level"`
+	Pattern string `yaml:"pattern"`
+	Size    string `yaml:"size"`
+}
+
+type LintConfig struct {
+	Rules map[string]LintRule `yaml:"rules"`
+}
+
+type LintIssue struct {
+	Frame   string `json:"frame"`
+	Sheet   string `json:"sheet"`
+	Rule    string `json:"rule"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+var lintLevels = []string{"off", "warn", "error"}
+
+// A lintCheck returns why tex breaks the rule, or "" when it does not.
+type lintCheck func(rule LintRule, tex Texture) string
+
+var lintChecks = map[string]lintCheck{
+	"naming": func(rule LintRule, tex Texture) string {
+		if !regexp.MustCompile(rule.Pattern).MatchString(tex.FileName) {
+			return fmt.Sprintf("name does not match %s", rule.Pattern)
+		}
+		return ""
+	},
+	"max-frame-size": func(rule LintRule, tex Texture) string {
+		limit, _ := parseSize(rule.Size)
+		if tex.SourceSize.Width > limit.Width || tex.SourceSize.Height > limit.Height {
+			return fmt.Sprintf("size %dx%d exceeds %s", tex.SourceSize.Width, tex.SourceSize.Height, rule.Size)
+		}
+		return ""
+	},
+	"require-pivot": func(_ LintRule, tex Texture) string {
+		if tex.Pivot == nil {
+			return "no pivot set"
+		}
+		return ""
+	},
+	"no-uppercase": func(_ LintRule, tex Texture) string {
+		if strings.ToLower(tex.FileName) != tex.FileName {
+			return "name contains uppercase letters"
+		}
+		return ""
+	},
+	"no-spaces": func(_ LintRule, tex Texture) string {
+		if strings.ContainsFunc(tex.FileName, func(r rune) bool { return r == ' ' || r == '\t' }) {
+			return "name contains spaces"
+		}
+		return ""
+	},
+	"require-trim": func(_ LintRule, tex Texture) string {
+		if !tex.Trimmed {
+			return "frame is not trimmed"
+		}
+		return ""
+	},
+}
+
+// defaultLintConfig flags what breaks most pipelines and leaves the
+// team-specific rules off until a config enables them.
+func defaultLintConfig() LintConfig {
+	return LintConfig{Rules: map[string]LintRule{
+		"naming":         {Level: "off"},
+		"max-frame-size": {Level: "warn", Size: "2048x2048"},
+		"require-pivot":  {Level: "off"},
+		"no-uppercase":   {Level: "warn"},
+		"no-spaces":      {Level: "error"},
+		"require-trim":   {Level: "off"},
+	}}
+}
+
+// loadLintConfig overlays the file's rules on the defaults, so a config only
+// names the rules it changes.
+func loadLintConfig(configPath string) (LintConfig, error) {
+	config := defaultLintConfig()
+	if configPath == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return LintConfig{}, fmt.Errorf("failed to read lint config: %w", err)
+	}
+
+	var overrides LintConfig
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return LintConfig{}, fmt.Errorf("invalid lint config: %w", err)
+	}
+
+	for name, rule := range overrides.Rules {
+		if _, ok := lintChecks[name]; !ok {
+			return LintConfig{}, fmt.Errorf("invalid lint rule %q, expected %s", name, strings.Join(slices.Sorted(maps.Keys(lintChecks)), ", "))
+		}
+
+		merged := config.Rules[name]
+		if rule.Level != "" {
+			merged.Level = rule.Level
+		}
+		if rule.Pattern != "" {
+			merged.Pattern = rule.Pattern
+		}
+		if rule.Size != "" {
+			merged.Size = rule.Size
+		}
+		config.Rules[name] = merged
+	}
+
+	return config, config.validate()
+}
+
+func (config LintConfig) validate() error {
+	for name, rule := range config.Rules {
+		if !slices.Contains(lintLevels, rule.Level) {
+			return fmt.Errorf("invalid level %q for lint rule %s, expected off, warn, or error", rule.Level, name)
+		}
+		if rule.Level == "off" {
+			continue
+		}
+
+		switch name {
+		case "naming":
+			if rule.Pattern == "" {
+				return fmt.Errorf("lint rule naming needs a pattern")
+			}
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				return fmt.Errorf("invalid naming pattern %q: %w", rule.Pattern, err)
+			}
+		case "max-frame-size":
+			if _, err := parseSize(rule.Size); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func lintPack(pack Pack, config LintConfig) []LintIssue {
+	var issues []LintIssue
+
+	rules := slices.Sorted(maps.Keys(config.Rules))
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			for _, name := range rules {
+				rule := config.Rules[name]
+				if rule.Level == "off" {
+					continue
+				}
+				if message := lintChecks[name](rule, tex); message != "" {
+					issues = append(issues, LintIssue{Frame: tex.FileName, Sheet: sh.Image, Rule: name, Level: rule.Level, Message: message})
+				}
+			}
+		}
+	}
+
+	return issues
+}
+
+func newLintCmd() *cobra.Command {
+	var configPath string
+	var asJSON bool = false
+
+	var lintCmd = &cobra.Command{
+		Use:   "lint <atlas>",
+		Short: "Check atlas frames against naming, size, pivot, and trim rules",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var path = args[0]
+			format, _ := cmd.Flags().GetString("format")
+
+			config, err := loadLintConfig(configPath)
+			if err != nil {
+				return err
+			}
+
+			pack, err := loadPack(path, format)
+			if err != nil {
+				return err
+			}
+
+			issues := lintPack(pack, config)
+
+			counts := make(map[string]int)
+			for _, issue := range issues {
+				counts[issue.Level]++
+			}
+
+			if asJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(issues); err != nil {
+					return err
+				}
+			} else {
+				for _, issue := range issues {
+					fmt.Printf("%-5s  %s  %s: %s\n", issue.Level, issue.Frame, issue.Rule, issue.Message)
+				}
+				logf("[info] %d errors, %d warnings\n", counts["error"], counts["warn"])
+			}
+
+			if counts["error"] > 0 {
+				return fmt.Errorf("%s failed %d lint checks", path, counts["error"])
+			}
+			return nil
+		},
+	}
+
+	lintCmd.Flags().StringVarP(&configPath, "config", "", "", "YAML file enabling, disabling, or configuring lint rules")
+	lintCmd.Flags().BoolVarP(&asJSON, "json", "", asJSON, "Print issues as JSON")
+
+	return lintCmd
+}
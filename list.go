@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+type FrameListing struct {
+	Frame      string `json:"frame"`
+	Sheet      string `json:"sheet"`
+	X          int    `json:"x"`
+	Y          int    `json:"y"`
+	Width      int    `json:"w"`
+	Height     int    `json:"h"`
+	SourceSize Size   `json:"sourceSize"`
+	Trimmed    bool   `json:"trimmed"`
+	Rotated    bool   `json:"rotated"`
+}
+
+func listFrames(pack Pack) []FrameListing {
+	var listings []FrameListing
+
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			listings = append(listings, FrameListing{
+				Frame:      tex.FileName,
+				Sheet:      sh.Image,
+				X:          tex.Frame.X,
+				Y:          tex.Frame.Y,
+				Width:      tex.Frame.Width,
+				Height:     tex.Frame.Height,
+				SourceSize: tex.SourceSize,
+				Trimmed:    tex.Trimmed,
+				Rotated:    tex.Rotated,
+			})
+		}
+	}
+
+	return listings
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func newListCmd() *cobra.Command {
+	var asJSON bool = false
+
+	var listCmd = &cobra.Command{
+		Use:   "list <path>",
+		Short: "List every frame in an atlas without extracting anything",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var path = args[0]
+			format, _ := cmd.Flags().GetString("format")
+
+			pack, err := loadPack(path, format)
+			if err != nil {
+				return err
+			}
+
+			listings := listFrames(pack)
+
+			if porcelainOut != nil {
+				for _, listing := range listings {
+					porcelainOut.record("frame", listing.Frame, listing.Sheet,
+						listing.X, listing.Y, listing.Width, listing.Height,
+						listing.SourceSize.Width, listing.SourceSize.Height,
+						listing.Trimmed, listing.Rotated)
+				}
+				return nil
+			}
+
+			if asJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(listings)
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "FRAME\tSHEET\tPOSITION\tSIZE\tSOURCE\tTRIMMED\tROTATED")
+			for _, listing := range listings {
+				fmt.Fprintf(writer, "%s\t%s\t%d,%d\t%dx%d\t%dx%d\t%s\t%s\n",
+					listing.Frame, listing.Sheet,
+					listing.X, listing.Y,
+					listing.Width, listing.Height,
+					listing.SourceSize.Width, listing.SourceSize.Height,
+					yesNo(listing.Trimmed), yesNo(listing.Rotated))
+			}
+			writer.Flush()
+
+			return nil
+		},
+	}
+
+	listCmd.Flags().BoolVarP(&asJSON, "json", "", asJSON, "Print frames as JSON")
+
+	return listCmd
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type LoaderFile struct {
+	Type       string `json:"type"`
+	Key        string `json:"key"`
+	URL        string `json:"url"`
+	TextureURL string `json:"textureURL"`
+	AtlasURL   string `json:"atlasURL"`
+	Path       string `json:"path"`
+}
+
+type LoaderSection struct {
+	Path   string       `json:"path"`
+	Prefix string       `json:"prefix"`
+	Files  []LoaderFile `json:"files"`
+}
+
+type LoaderAtlas struct {
+	Key       string
+	AtlasPath string
+	Format    string
+	Pack      Pack
+}
+
+// Phaser loader file types that describe atlases, mapped to the format used to parse them.
+var loaderAtlasFormats = map[string]string{
+	"atlas":      "",
+	"atlasXML":   "xml",
+	"multiatlas": "multiatlas",
+}
+
+func sniffLoaderManifest(data []byte) bool {
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return false
+	}
+
+	for _, raw := range sections {
+		var section struct {
+			Files []json.RawMessage `json:"files"`
+		}
+		if json.Unmarshal(raw, &section) == nil && section.Files != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (file LoaderFile) atlasURL(ext string) string {
+	switch {
+	case file.AtlasURL != "":
+		return file.AtlasURL
+	case file.URL != "":
+		return file.URL
+	}
+	return file.Key + ext
+}
+
+// loadLoaderManifest resolves every atlas entry of a Phaser loader pack.json,
+// rewriting sheet images relative to their atlas file.
+func loadLoaderManifest(path string, data []byte) ([]LoaderAtlas, error) {
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return nil, fmt.Errorf("invalid loader manifest: %w", err)
+	}
+
+	names, err := jsonObjectKeys(data, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid loader manifest: %w", err)
+	}
+
+	manifestDir := filepath.Dir(path)
+	var atlases []LoaderAtlas
+
+	for _, name := range names {
+		var section LoaderSection
+		if err := json.Unmarshal(sections[name], &section); err != nil {
+			continue
+		}
+
+		baseDir := filepath.Join(manifestDir, filepath.FromSlash(section.Path))
+
+		for _, file := range section.Files {
+			format, ok := loaderAtlasFormats[file.Type]
+			if !ok {
+				continue
+			}
+
+			ext := ".json"
+			if file.Type == "atlasXML" {
+				ext = ".xml"
+			}
+
+			atlasPath := filepath.Join(baseDir, filepath.FromSlash(file.atlasURL(ext)))
+			pack, err := loadPack(atlasPath, format)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load %s atlas %q: %w", file.Type, file.Key, err)
+			}
+
+			atlasDir := filepath.Dir(atlasPath)
+			var imageDir string
+			switch {
+			case file.Type == "multiatlas" && file.Path != "":
+				imageDir = filepath.Join(baseDir, filepath.FromSlash(file.Path))
+			case file.TextureURL != "" && len(pack.Sheets) == 1:
+				texturePath := filepath.Join(baseDir, filepath.FromSlash(file.TextureURL))
+				pack.Sheets[0].Image = filepath.Base(texturePath)
+				imageDir = filepath.Dir(texturePath)
+			}
+
+			if imageDir != "" {
+				for i := range pack.Sheets {
+					relPath, err := filepath.Rel(atlasDir, filepath.Join(imageDir, filepath.FromSlash(pack.Sheets[i].Image)))
+					if err != nil {
+						return nil, fmt.Errorf("failed to resolve image for atlas %q: %w", file.Key, err)
+					}
+					pack.Sheets[i].Image = filepath.ToSlash(relPath)
+				}
+			}
+
+			atlases = append(atlases, LoaderAtlas{
+				Key:       section.Prefix + file.Key,
+				AtlasPath: atlasPath,
+				Format:    format,
+				Pack:      pack,
+			})
+		}
+	}
+
+	if len(atlases) == 0 {
+		return nil, fmt.Errorf("no atlas entries found in loader manifest %s", path)
+	}
+
+	return atlases, nil
+}
+
+func isLoaderManifest(path string) (bool, []byte, error) {
+	if filepath.Ext(path) != ".json" {
+		return false, nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, nil, withExitCode(exitBadInput, fmt.Errorf("failed to read input: %w", err))
+	}
+
+	return sniffLoaderManifest(data), data, nil
+}
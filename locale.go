@@ -0,0 +1,119 @@
+package main
+
+import (
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Suffixes like _ja or _pt-BR, told apart from other suffixes by the known
+// locale list.
+var localeSuffix = regexp.MustCompile(`^(.+)[_-]([a-z]{2,3}(?:[-_][A-Z]{2})?)$`)
+
+var defaultLocales = []string{
+	"ar", "cs", "da", "de", "el", "en", "en-GB", "en-US", "es", "es-MX", "fi", "fr", "fr-CA",
+	"he", "hi", "hu", "id", "it", "ja", "ko", "ms", "nl", "no", "pl", "pt", "pt-BR", "ro",
+	"ru", "sv", "th", "tr", "uk", "vi", "zh", "zh-CN", "zh-TW",
+}
+
+// A LocaleIndex maps localized frames to their base name and locale. A base
+// counts as localized once it has two locale variants, or any when the
+// locales were given explicitly, so one stray suffix like panel_tr is not
+// mistaken for Turkish.
+type LocaleIndex struct {
+	Locales []string
+	bases   map[string]map[string]string
+	frames  map[string][2]string
+}
+
+func newLocaleIndex(pack Pack, known []string) LocaleIndex {
+	explicit := len(known) > 0
+	if !explicit {
+		known = defaultLocales
+	}
+
+	candidates := make(map[string]map[string]string)
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			match := localeSuffix.FindStringSubmatch(tex.FileName)
+			if match == nil {
+				continue
+			}
+
+			locale := strings.ReplaceAll(match[2], "_", "-")
+			if !slices.Contains(known, locale) {
+				continue
+			}
+
+			if candidates[match[1]] == nil {
+				candidates[match[1]] = make(map[string]string)
+			}
+			candidates[match[1]][locale] = tex.FileName
+		}
+	}
+
+	index := LocaleIndex{bases: make(map[string]map[string]string), frames: make(map[string][2]string)}
+	for base, variants := range candidates {
+		if len(variants) < 2 && !explicit {
+			continue
+		}
+
+		index.bases[base] = variants
+		for locale, name := range variants {
+			index.frames[name] = [2]string{base, locale}
+			if !slices.Contains(index.Locales, locale) {
+				index.Locales = append(index.Locales, locale)
+			}
+		}
+	}
+	slices.Sort(index.Locales)
+
+	return index
+}
+
+// locale returns the base name and locale of a frame, or an empty locale for
+// frames shared by every locale.
+func (index LocaleIndex) locale(name string) (string, string) {
+	if frame, ok := index.frames[name]; ok {
+		return frame[0], frame[1]
+	}
+	return name, ""
+}
+
+// missing lists, per localized base, which of the locales it has no frame for.
+func (index LocaleIndex) missing(locales []string) map[string][]string {
+	missing := make(map[string][]string)
+
+	for base, variants := range index.bases {
+		for _, locale := range locales {
+			if _, ok := variants[locale]; !ok {
+				missing[base] = append(missing[base], locale)
+			}
+		}
+	}
+
+	return missing
+}
+
+func (index LocaleIndex) reportMissing(locale string) {
+	locales := index.Locales
+	if locale != "" {
+		locales = []string{locale}
+	}
+
+	missing := index.missing(locales)
+	for _, base := range slices.Sorted(maps.Keys(missing)) {
+		logf("[warn] %s is missing translations for %s\n", base, strings.Join(missing[base], ", "))
+	}
+}
+
+func (unpacker Unpacker) localePath(texture Texture) string {
+	base, locale := unpacker.Locales.locale(texture.FileName)
+	if locale == "" {
+		return filepath.Join(unpacker.OutputDir, filepath.FromSlash(unpacker.outputName(texture))+unpacker.encoder().Extension())
+	}
+
+	return filepath.Join(unpacker.OutputDir, locale, filepath.FromSlash(unpacker.nameFor(texture, base))+unpacker.encoder().Extension())
+}
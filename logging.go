@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logFormats = []string{"text", "json"}
+
+// Verbosity levels, lowered by each -q and raised by each -v. Errors are
+// printed at every level.
+const (
+	verbositySilent = -2 // nothing else
+	verbosityQuiet  = -1 // warnings and the summary
+	verbosityNormal = 0  // messages
+	verbositySheets = 1  // each sheet as it starts and finishes
+	verbosityFrames = 2  // each file written
+)
+
+var verbosity = verbosityNormal
+
+// jsonLog receives --log-format json records, nil when messages are printed
+// as text.
+var jsonLog *JSONLog
+
+// A LogRecord is one line of the JSON log. Event names what happened:
+// message for what is otherwise printed as [info] or [warn], sheet when a
+// sheet starts, write for each file written, fail for each frame or sheet
+// that failed, summary once a run completes, and error when the command fails.
+type LogRecord struct {
+	Time    string      `json:"time"`
+	Event   string      `json:"event"`
+	Level   string      `json:"level,omitempty"`
+	Message string      `json:"message,omitempty"`
+	Sheet   string      `json:"sheet,omitempty"`
+	Frame   string      `json:"frame,omitempty"`
+	Path    string      `json:"path,omitempty"`
+	Frames  int         `json:"frames,omitempty"`
+	Bytes   int64       `json:"bytes,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Summary *RunSummary `json:"summary,omitempty"`
+}
+
+// A JSONLog writes one LogRecord per line, for build systems and servers
+// that run txunpak and parse what it did.
+type JSONLog struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func newJSONLog(w io.Writer) *JSONLog {
+	return &JSONLog{encoder: json.NewEncoder(w)}
+}
+
+func (log *JSONLog) write(record LogRecord) {
+	record.Time = time.Now().UTC().Format(time.RFC3339Nano)
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.encoder.Encode(record)
+}
+
+// logf prints a message at the level its prefix gives, warnings with -q
+// and [info] messages unless quiet.
+func logf(format string, args ...any) {
+	level := verbosityNormal
+	if strings.HasPrefix(format, "[warn] ") {
+		level = verbosityQuiet
+	}
+	logAt(level, format, args...)
+}
+
+// logAt prints a message when the verbosity is at least level, warnings in
+// yellow, or with a JSON log records it there instead, its level taken from
+// the [info] or [warn] prefix messages start with. A log file gets text
+// messages at its own level.
+func logAt(level int, format string, args ...any) {
+	color := ""
+	if strings.HasPrefix(format, "[warn] ") {
+		color = colorYellow
+	}
+	logColored(level, color, format, args...)
+}
+
+// logSuccess prints the message a command ends with when it succeeded, in
+// green.
+func logSuccess(format string, args ...any) {
+	logColored(verbosityNormal, colorGreen, format, args...)
+}
+
+func logColored(level int, color, format string, args ...any) {
+	if jsonLog == nil {
+		if verbosity >= level {
+			fmt.Print(paint(color, fmt.Sprintf(format, args...)))
+		}
+		if fileLog != nil && fileLog.level >= level {
+			fileLog.printf(format, args...)
+		}
+		return
+	}
+	if verbosity < level {
+		return
+	}
+
+	message := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	kind := "info"
+	for _, prefix := range []string{"info", "warn"} {
+		if rest, ok := strings.CutPrefix(message, "["+prefix+"] "); ok {
+			kind, message = prefix, rest
+		}
+	}
+	jsonLog.write(LogRecord{Event: "message", Level: kind, Message: message})
+}
+
+// startJSONLog records messages and unpacking events as JSON lines on
+// stderr, and in the log file when there is one, leaving stdout to what the
+// command prints as its result.
+func startJSONLog() {
+	var w io.Writer = os.Stderr
+	if fileLog != nil {
+		w = io.MultiWriter(os.Stderr, fileLog.file)
+	}
+	jsonLog = newJSONLog(w)
+}
+
+// fileLog tees messages to --log-file, nil without it.
+var fileLog *FileLog
+
+// A FileLog keeps a timestamped record of a run for later auditing. It logs
+// at least each sheet and each failed frame whatever the terminal shows, and
+// writes unbuffered so a crash loses nothing that was logged.
+type FileLog struct {
+	mu    sync.Mutex
+	file  *os.File
+	level int
+}
+
+// openFileLog appends to the log at path, so runs into the same file
+// accumulate.
+func openFileLog(path string) (*FileLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, withExitCode(exitOutput, fmt.Errorf("failed to open log file: %w", err))
+	}
+	return &FileLog{file: file, level: max(verbosity, verbositySheets)}, nil
+}
+
+func (log *FileLog) printf(format string, args ...any) {
+	line := time.Now().UTC().Format(time.RFC3339) + " " + fmt.Sprintf(format, args...)
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	io.WriteString(log.file, line)
+}
@@ -1,20 +1,28 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
+	"cmp"
+	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	"image/draw"
-	"image/png"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/vbauerster/mpb/v8"
-	"github.com/vbauerster/mpb/v8/decor"
 	_ "golang.org/x/image/webp"
 	"golang.org/x/term"
 )
@@ -36,6 +44,17 @@ func (sz Size) Rect() image.Rectangle {
 	return image.Rectangle{sz.Min(), sz.Max()}
 }
 
+func parseSize(s string) (Size, error) {
+	var sz Size
+	if _, err := fmt.Sscanf(s, "%dx%d", &sz.Width, &sz.Height); err != nil {
+		return Size{}, fmt.Errorf(tr("invalid size %q, expected WxH"), s)
+	}
+	if sz.Width <= 0 || sz.Height <= 0 {
+		return Size{}, fmt.Errorf(tr("invalid size %q, dimensions must be positive"), s)
+	}
+	return sz, nil
+}
+
 type Frame struct {
 	Width  int `json:"w"`
 	Height int `json:"h"`
@@ -55,13 +74,24 @@ func (fr Frame) Rect() image.Rectangle {
 	return image.Rectangle{fr.Min(), fr.Max()}
 }
 
+// Pivot is a frame's anchor as a fraction of its source size.
+type Pivot struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
 type Texture struct {
-	FileName         string `json:"filename"`
-	Frame            Frame  `json:"frame"`
-	Rotated          bool   `json:"rotated"`
-	SourceSize       Size   `json:"sourceSize"`
-	SpriteSourceSize Frame  `json:"spriteSourceSize"`
-	Trimmed          bool   `json:"trimmed"`
+	FileName         string       `json:"filename"`
+	Frame            Frame        `json:"frame"`
+	Rotated          bool         `json:"rotated"`
+	SourceSize       Size         `json:"sourceSize"`
+	SpriteSourceSize Frame        `json:"spriteSourceSize"`
+	Trimmed          bool         `json:"trimmed"`
+	Pivot            *Pivot       `json:"pivot,omitempty"`
+	Vertices         [][2]float64 `json:"vertices,omitempty"`
+	VerticesUV       [][2]float64 `json:"verticesUV,omitempty"`
+	Triangles        [][3]int     `json:"triangles,omitempty"`
+	Scale9Borders    *Frame       `json:"scale9Borders,omitempty"`
 }
 
 type Sheet struct {
@@ -73,123 +103,642 @@ type Sheet struct {
 }
 
 type Pack struct {
-	Meta   map[string]string `json:"meta"`
-	Sheets []Sheet           `json:"textures"`
+	Meta    map[string]string `json:"meta"`
+	Sheets  []Sheet           `json:"textures"`
+	Related []string          `json:"-"`
 }
 
 type Unpacker struct {
 	Pack
-	PackName  string
-	InputDir  string
-	OutputDir string
-	Workers   int
+	PackName       string
+	AtlasPath      string
+	InputDir       string
+	OutputDir      string
+	Workers        int
+	MaxMemory      int64
+	DecodeWorkers  int
+	EncodeWorkers  int
+	IOWorkers      int
+	Export         string
+	Labels         map[string]string
+	Attributions   Attributions
+	Augment        Augmenter
+	Locales        LocaleIndex
+	Renames        []Rename
+	Names          NameStrategy
+	Encoder        SpriteEncoder
+	Flatten        string
+	FollowSymlinks bool
+	SkipAliases    bool
+	StrictNames    bool
+	Order          string
+	Summary        string
+	Progress       string
+	SRGB           bool
+	TrimMode       string
+	Shrink         int
+	Scale          float64
+	Filter         string
+	Background     *color.NRGBA
+	Channels       []ChannelOutput
+	Unpremultiply  bool
+	Anchors        bool
+	AutoPivot      string
+	Meshes         bool
+	NineSlice      string
+	Sidecar        string
+	Dedup          string
+	OnCollision    string
+	TileSize       int
+	TileOverlap    int
+	TileFrames     bool
+	SkipExisting   bool
+	OnlyNewer      bool
+	Resume         bool
+	KeepGoing      bool
+	Force          bool
+	ErrorReport    string
+	aliases        map[string]Texture
+	collisions     map[textureKey]string
+	slots          map[string]FrameSlot
+	Manifest       bool
+	Checkpoint     Checkpoint
+	MTime          time.Time
+	manifest       *ManifestWriter
+	dedup          *Deduper
+	previous       *Manifest
+	resume         *ResumeState
+	progress       *ProgressStream
+	ioSlots        chan struct{}
+	failures       *FailureLog
+	datasetFrames  *sync.Map
+	profile        ColorProfile
+	factor         float64
 }
 
 func isTTY() bool {
 	return term.IsTerminal(int(os.Stdout.Fd()))
 }
 
-func (unpacker Unpacker) unpackTexture(texture Texture, img image.Image) error {
+func decodeSheet(inputDir string, sheet Sheet) (image.Image, error) {
+	sheetPath := filepath.Join(inputDir, sheet.Image)
+
+	sheetFile, err := os.Open(sheetPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, withExitCode(exitMissingSheet, fmt.Errorf(tr("failed to open texture sheet: %w"), err))
+	}
+	if err != nil {
+		return nil, fmt.Errorf(tr("failed to open texture sheet: %w"), err)
+	}
+	defer sheetFile.Close()
+
+	img, err := decodeImage(sheetFile)
+	if err != nil {
+		return nil, withExitCode(exitBadInput, fmt.Errorf(tr("failed to decode texture sheet %s: %w"), sheetPath, err))
+	}
+
+	return img, nil
+}
+
+// sheetPoint returns the sheet pixel behind pixel x, y of the frame. Rotated
+// frames are stored turned 90° clockwise, so their columns run down the sheet.
+func (texture Texture) sheetPoint(x, y int) image.Point {
+	if texture.Rotated {
+		return image.Pt(texture.Frame.X+texture.Frame.Height-1-y, texture.Frame.Y+x)
+	}
+	return image.Pt(texture.Frame.X+x, texture.Frame.Y+y)
+}
+
+// unrotateFrame turns a rotated frame upright into an image of the sheet's
+// pixel format covering texture.Frame, so it can be drawn from like the sheet.
+func unrotateFrame(texture Texture, img image.Image) image.Image {
+	rect := texture.Frame.Rect()
+
+	var frame draw.Image
+	switch img.(type) {
+	case *image.Gray:
+		frame = image.NewGray(rect)
+	case *image.Gray16:
+		frame = image.NewGray16(rect)
+	case *image.NRGBA64:
+		frame = image.NewNRGBA64(rect)
+	case *image.RGBA64:
+		frame = image.NewRGBA64(rect)
+	case *FloatImage:
+		frame = NewFloatImage(rect)
+	default:
+		frame = image.NewNRGBA(rect)
+	}
+
+	for y := range rect.Dy() {
+		for x := range rect.Dx() {
+			p := texture.sheetPoint(x, y)
+			frame.Set(rect.Min.X+x, rect.Min.Y+y, img.At(p.X, p.Y))
+		}
+	}
+
+	return frame
+}
+
+// renderTexture keeps straight alpha, since premultiplying would round
+// semi-transparent pixels and the extracted frame would no longer match the sheet.
+func renderTexture(texture Texture, img image.Image) *image.NRGBA {
 	spriteSize := texture.SourceSize.Rect()
-	sprite := image.NewRGBA(spriteSize)
+	sprite := newPooledNRGBA(spriteSize)
 
 	destFrame := texture.SpriteSourceSize.Rect()
 	sourceFrame := texture.Frame.Rect()
 
+	if texture.Rotated {
+		img = unrotateFrame(texture, img)
+	}
 	draw.Draw(sprite, destFrame, img, sourceFrame.Min, draw.Src)
+	if texture.polygonal() {
+		maskPolygon(texture, sprite)
+	}
 
-	encoder := png.Encoder{CompressionLevel: png.DefaultCompression}
+	return sprite
+}
 
-	outputPath := filepath.Join(unpacker.OutputDir, texture.FileName+".png")
+// extractTexture renders a frame in the sheet's own pixel format where that
+// format can hold it, so 16-bit depth, float, and grayscale survive extraction.
+// Grayscale has no alpha, so trimmed frames padded with transparency fall back
+// to color at the same depth.
+func extractTexture(texture Texture, img image.Image) image.Image {
+	spriteSize := texture.SourceSize.Rect()
+	// Polygon frames are masked with transparency, which grayscale cannot hold.
+	padded := texture.SpriteSourceSize.Rect() != spriteSize || texture.polygonal()
 
-	if strings.Contains(texture.FileName, "/") {
-		parts := strings.Split(texture.FileName, "/")
-		subDir := filepath.Join(unpacker.OutputDir, filepath.Join(parts...))
+	var sprite draw.Image
+	switch img.(type) {
+	case *image.Gray:
+		if padded {
+			return renderTexture(texture, img)
+		}
+		sprite = image.NewGray(spriteSize)
+	case *image.Gray16:
+		if padded {
+			sprite = image.NewNRGBA64(spriteSize)
+		} else {
+			sprite = image.NewGray16(spriteSize)
+		}
+	case *image.NRGBA64, *image.RGBA64:
+		sprite = image.NewNRGBA64(spriteSize)
+	case *FloatImage:
+		if texture.Rotated {
+			img = unrotateFrame(texture, img)
+		}
+		float := NewFloatImage(spriteSize)
+		copyFloat(float, texture.SpriteSourceSize.Rect(), img.(*FloatImage), texture.Frame.Rect().Min)
+		if texture.polygonal() {
+			maskPolygon(texture, float)
+		}
+		return float
+	default:
+		return renderTexture(texture, img)
+	}
 
+	if texture.Rotated {
+		img = unrotateFrame(texture, img)
+	}
+	draw.Draw(sprite, texture.SpriteSourceSize.Rect(), img, texture.Frame.Rect().Min, draw.Src)
+	if texture.polygonal() {
+		maskPolygon(texture, sprite)
+	}
+
+	return sprite
+}
+
+func (unpacker Unpacker) outputPath(texture Texture) string {
+	switch unpacker.Export {
+	case "dataset":
+		return unpacker.datasetPath(texture)
+	case "locales":
+		return unpacker.localePath(texture)
+	}
+
+	if resolved := unpacker.collisions[keyOf(texture)]; resolved != "" {
+		return resolved
+	}
+	return filepath.Join(unpacker.OutputDir, filepath.FromSlash(unpacker.outputName(texture))+unpacker.encoder().Extension())
+}
+
+// encodeSprite encodes the sprite as it is written, with the sheet's color
+// profile when the format can embed one.
+func (unpacker Unpacker) encodeSprite(w io.Writer, sprite image.Image) error {
+	encoder := unpacker.encoder()
+	var err error
+	if profiles, ok := encoder.(profileEncoder); ok && len(unpacker.profile.Chunks) > 0 {
+		err = profiles.EncodeProfile(w, sprite, unpacker.profile)
+	} else {
+		err = encoder.Encode(w, sprite)
+	}
+	if err != nil {
+		return fmt.Errorf(tr("failed to encode sprite as %s: %w"), strings.TrimPrefix(encoder.Extension(), "."), err)
+	}
+	return nil
+}
+
+// writeSprite encodes sprite to outputPath, returning the bytes written.
+func (unpacker Unpacker) writeSprite(outputPath string, sprite image.Image) (int64, error) {
+	if unpacker.dedup != nil {
+		if err := unpacker.unlink(outputPath); err != nil {
+			return 0, err
+		}
+	}
+	if err := unpacker.checkOutput(outputPath); err != nil {
+		return 0, err
+	}
+
+	if subDir := filepath.Dir(outputPath); subDir != unpacker.OutputDir {
 		if err := os.MkdirAll(subDir, 0o755); err != nil {
-			return fmt.Errorf("failed to create output directory: %w", err)
+			return 0, withExitCode(exitOutput, fmt.Errorf(tr("failed to create output directory: %w"), err))
+		}
+	}
+
+	if unpacker.dedup != nil {
+		return unpacker.dedup.write(dedupKey(sprite, unpacker.profile), outputPath, func() (int64, error) {
+			return unpacker.createSprite(outputPath, sprite)
+		})
+	}
+	return unpacker.createSprite(outputPath, sprite)
+}
+
+func (unpacker Unpacker) createSprite(outputPath string, sprite image.Image) (int64, error) {
+	// With a cap on writes the sprite is encoded first, so a write slot is
+	// only held while the file is written.
+	var encoded *bytes.Buffer
+	if unpacker.ioSlots != nil {
+		encoded = &bytes.Buffer{}
+		if err := unpacker.encodeSprite(encoded, sprite); err != nil {
+			return 0, err
 		}
+		unpacker.ioSlots <- struct{}{}
+		defer func() { <-unpacker.ioSlots }()
 	}
 
-	outputFile, err := os.Create(outputPath)
+	// The sprite is written beside its path and renamed over it, so a file
+	// an earlier run left there, perhaps hard linked to other sprites, is
+	// replaced rather than written through.
+	tmpPath := filepath.Join(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".tmp")
+	if err := os.Remove(tmpPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, withExitCode(exitOutput, fmt.Errorf(tr("failed to open output file: %w"), err))
+	}
+	outputFile, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
 	if err != nil {
-		return fmt.Errorf("failed to open output file: %w", err)
+		return 0, withExitCode(exitOutput, fmt.Errorf(tr("failed to open output file: %w"), err))
 	}
 
-	if err = encoder.Encode(outputFile, sprite); err != nil {
-		return fmt.Errorf("failed to encode sprite as png: %w", err)
+	counter := &countingWriter{Writer: outputFile}
+	if encoded != nil {
+		if _, err := encoded.WriteTo(counter); err != nil {
+			outputFile.Close()
+			os.Remove(tmpPath)
+			return 0, withExitCode(exitOutput, fmt.Errorf(tr("failed to write output file: %w"), err))
+		}
+	} else if err := unpacker.encodeSprite(counter, sprite); err != nil {
+		outputFile.Close()
+		os.Remove(tmpPath)
+		return 0, err
 	}
 
 	if err = outputFile.Close(); err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
+		os.Remove(tmpPath)
+		return 0, withExitCode(exitOutput, fmt.Errorf(tr("failed to write output file: %w"), err))
+	}
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		os.Remove(tmpPath)
+		return 0, withExitCode(exitOutput, fmt.Errorf(tr("failed to write output file: %w"), err))
 	}
 
-	return nil
+	return counter.n, nil
 }
 
-func (unpacker Unpacker) unpackSheet(sheet Sheet, sheetBar, totalBar *mpb.Bar) error {
-	sheetPath := filepath.Join(unpacker.InputDir, sheet.Image)
+func (unpacker Unpacker) unpackTexture(texture Texture, img image.Image) (int64, error) {
+	unpacker.profile = unpacker.profile.stamped(unpacker.attribution(texture))
+	// Sprites are written before this returns, so their pixels can go back to
+	// the pool.
+	sprite := extractTexture(texture, img)
+	defer releaseSprite(sprite)
+	if unpacker.factor != 0 && unpacker.factor != 1 {
+		sprite = scaleSprite(sprite, unpacker.factor, unpacker.Filter)
+		defer releaseSprite(sprite)
+	}
+	if unpacker.Background != nil {
+		sprite = matte(sprite, *unpacker.Background)
+	}
 
-	sheetFile, err := os.Open(sheetPath)
+	outputPath := unpacker.outputPath(texture)
+	written, err := unpacker.writeFrame(texture, outputPath, sprite)
 	if err != nil {
-		return fmt.Errorf("failed to open texture sheet: %w", err)
+		return 0, err
 	}
+	unpacker.emit(Event{Kind: EventWrite, Frame: texture.FileName, Path: outputPath, Bytes: written})
 
-	img, _, err := image.Decode(sheetFile)
-	if err != nil {
-		return fmt.Errorf("failed to decode webp file: %w", err)
+	for i := range unpacker.Augment.Variants {
+		variant := unpacker.Augment.apply(sprite, unpacker.Augment.params(texture, i))
+		variantPath := unpacker.augmentPath(texture, i)
+		n, err := unpacker.writeChannels(variantPath, variant)
+		if err != nil {
+			return 0, err
+		}
+		unpacker.emit(Event{Kind: EventWrite, Frame: texture.FileName, Path: variantPath, Bytes: n})
+		written += n
+	}
+
+	return written, nil
+}
+
+// skips reports whether tex is not written at all, having lost its output
+// path to another frame or being an alias written once with --skip-aliases.
+func (unpacker Unpacker) skips(tex Texture) bool {
+	if unpacker.collided(tex) {
+		return true
 	}
+	_, alias := unpacker.aliases[tex.FileName]
+	return alias && unpacker.SkipAliases
+}
 
-	jobs := make(chan Texture)
-	results := make(chan error, len(sheet.Textures))
+// A sheetRun is one sheet's share of an extraction: the decoded sheet, the
+// unpacker carrying its color profile and scale, and what its frames add up
+// to. Frames of every sheet are extracted by one pool of workers.
+type sheetRun struct {
+	Sheet
+	unpacker  Unpacker
+	img       image.Image
+	fresh     map[textureKey]bool
+	bar       *mpb.Bar
+	ready     chan struct{}
+	release   func()
+	err       error
+	start     time.Time
+	seconds   float64
+	remaining atomic.Int64
+	written   atomic.Int64
+	skipped   atomic.Int64
+	collided  atomic.Int64
+	failed    atomic.Int64
+}
 
-	var wg sync.WaitGroup
+type frameJob struct {
+	run *sheetRun
+	tex Texture
+}
 
-	for range unpacker.Workers {
-		wg.Go(func() {
-			for tex := range jobs {
-				if err := unpacker.unpackTexture(tex, img); err != nil {
-					results <- err
-					return
-				}
-				if sheetBar != nil {
-					sheetBar.Increment()
-				}
-				if totalBar != nil {
-					totalBar.Increment()
-				}
-				results <- nil
-			}
-		})
+func newSheetRun(sheet Sheet, bar *mpb.Bar) *sheetRun {
+	run := &sheetRun{Sheet: sheet, bar: bar, ready: make(chan struct{})}
+	run.remaining.Store(int64(len(sheet.Textures)))
+	return run
+}
+
+// prepareSheet decodes the sheet and reads what its frames share. A sheet
+// whose frames are all up to date is not decoded at all.
+func (unpacker Unpacker) prepareSheet(run *sheetRun) error {
+	sheet := run.Sheet
+	run.start = time.Now()
+	run.unpacker = unpacker
+	unpacker.emit(Event{Kind: EventSheet, Sheet: sheet.Image, Frames: len(sheet.Textures)})
+
+	if err := unpacker.checkInput(sheet); err != nil {
+		return err
 	}
 
-	go func() {
-		for _, tex := range sheet.Textures {
-			jobs <- tex
+	run.fresh = unpacker.freshFrames(sheet)
+	if !slices.ContainsFunc(sheet.Textures, func(tex Texture) bool { return !run.fresh[keyOf(tex)] && !unpacker.skips(tex) }) {
+		return nil
+	}
+
+	img, err := decodeSheet(unpacker.InputDir, sheet)
+	if err != nil {
+		return err
+	}
+	if _, ok := img.(*FloatImage); ok {
+		if err := unpacker.checkFloat(sheet); err != nil {
+			return err
 		}
-		close(jobs)
-	}()
+	}
 
-	wg.Wait()
-	close(results)
+	// Every sprite from this sheet carries its color profile, or sRGB with --srgb.
+	unpacker.profile, err = readColorProfile(filepath.Join(unpacker.InputDir, sheet.Image))
+	if err != nil {
+		return err
+	}
+	if unpacker.SRGB {
+		if img, unpacker.profile, err = toSRGB(img, unpacker.profile); err != nil {
+			return fmt.Errorf(tr("failed to convert %s: %w"), sheet.Image, err)
+		}
+	}
+	if unpacker.Unpremultiply {
+		img = unpremultiply(img)
+	}
+	unpacker.factor = unpacker.spriteScale(sheet)
+
+	run.img = img
+	run.unpacker = unpacker
+	return nil
+}
 
-	for err := range results {
+func (run *sheetRun) extract(tex Texture) error {
+	unpacker := run.unpacker
+	if _, ok := unpacker.collisions[keyOf(tex)]; ok {
+		run.collided.Add(1)
+	}
+	if unpacker.skips(tex) || run.fresh[keyOf(tex)] {
+		run.skipped.Add(1)
+	} else {
+		n, err := unpacker.unpackTexture(tex, run.img)
 		if err != nil {
 			return err
 		}
-	}
+		run.written.Add(n)
+
+		if unpacker.Sidecar == "json" {
+			n, err := unpacker.writeSidecar(run.Sheet, tex)
+			if err != nil {
+				return err
+			}
+			run.written.Add(n)
+		}
 
+		if unpacker.resume != nil {
+			if err := unpacker.resume.record(tex.FileName, unpacker.outputPath(tex)); err != nil {
+				return err
+			}
+		}
+	}
+	// Up-to-date frames are already on disk, so they are indexed too.
+	if unpacker.datasetFrames != nil && !unpacker.skips(tex) {
+		unpacker.datasetFrames.Store(keyOf(tex), struct{}{})
+	}
+	if unpacker.manifest != nil && !unpacker.collided(tex) {
+		if err := unpacker.manifest.record(tex.FileName, unpacker.manifestFrame(run.Sheet, tex)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// done counts off n frames, finishing the sheet with its last.
+func (run *sheetRun) done(n int) {
+	if run.bar != nil {
+		run.bar.IncrBy(n)
+	}
+	if run.remaining.Add(int64(-n)) == 0 {
+		run.finish()
+	}
+}
+
+// finish releases the decoded sheet, which no frame needs any more.
+func (run *sheetRun) finish() {
+	run.img = nil
+	if run.release != nil {
+		run.release()
+	}
+	run.seconds = time.Since(run.start).Seconds()
+	logAt(verbositySheets, tr("[info] finished %s, %d frames in %.2fs\n"), run.Image, run.summary().Frames, run.seconds)
+}
+
+func (run *sheetRun) summary() SheetSummary {
+	summary := SheetSummary{
+		Sheet:      run.Image,
+		Skipped:    int(run.skipped.Load()),
+		Failed:     int(run.failed.Load()),
+		Collisions: int(run.collided.Load()),
+		Bytes:      run.written.Load(),
+		Seconds:    run.seconds,
+	}
+	summary.Frames = len(run.Textures) - summary.Skipped - summary.Failed
+	return summary
+}
+
 func (unpacker Unpacker) unpack(noProgress bool) error {
+	start := time.Now()
 	numSheets := len(unpacker.Pack.Sheets)
 
-	fmt.Printf("[info] found %d texture sheets\n", numSheets)
-	fmt.Printf("[info] writing to %s\n", unpacker.OutputDir)
+	logf(tr("[info] found %d texture sheets\n"), numSheets)
+	logf(tr("[info] writing to %s\n"), unpacker.OutputDir)
+
+	unpacker.slots = FrameSlots(unpacker.Pack)
+
+	if unpacker.StrictNames {
+		if err := unpacker.checkNames(); err != nil {
+			return err
+		}
+	}
+
+	if err := unpacker.checkPaths(); err != nil {
+		return err
+	}
+
+	if err := unpacker.checkOverwrite(); err != nil {
+		return err
+	}
 
 	if err := os.MkdirAll(unpacker.OutputDir, 0o755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+		return withExitCode(exitOutput, fmt.Errorf(tr("failed to create output directory: %w"), err))
+	}
+	if err := markRun(unpacker.OutputDir, unpacker.AtlasPath); err != nil {
+		return err
+	}
+
+	// A negative shrink defers to the extrusion the atlas records.
+	shrink := unpacker.Shrink
+	if shrink < 0 {
+		shrink = packExtrude(unpacker.Pack)
+	}
+	if shrink > 0 {
+		logf(tr("[info] shrinking frames by %dpx of extrusion\n"), shrink)
+		shrunk, err := shrinkPack(unpacker.Pack, shrink)
+		if err != nil {
+			return err
+		}
+		unpacker.Pack = shrunk
+	}
+
+	if unpacker.AutoPivot != "" && unpacker.AutoPivot != "none" {
+		pivoted, computed, err := autoPivots(unpacker.Pack, unpacker.InputDir, unpacker.AutoPivot)
+		if err != nil {
+			return err
+		}
+		logf(tr("[info] computed %d pivots from sprite pixels\n"), computed)
+		unpacker.Pack = pivoted
+	}
+
+	if unpacker.Anchors {
+		if err := writeAnchors(unpacker.Pack, filepath.Join(unpacker.OutputDir, "anchors.json")); err != nil {
+			return err
+		}
+	}
+
+	if unpacker.Meshes {
+		if err := writeMeshes(unpacker.Pack, filepath.Join(unpacker.OutputDir, "meshes.json")); err != nil {
+			return err
+		}
+	}
+
+	if unpacker.NineSlice == "json" {
+		if err := writeNineSlices(unpacker.Pack, filepath.Join(unpacker.OutputDir, "nine-slice.json")); err != nil {
+			return err
+		}
+	}
+
+	if unpacker.TrimMode == "tight" {
+		if err := writeTrimOffsets(unpacker.Pack, filepath.Join(unpacker.OutputDir, "offsets.json")); err != nil {
+			return err
+		}
+		unpacker.Pack = tightPack(unpacker.Pack)
+	}
+
+	if unpacker.Export == "montage" {
+		return unpacker.writeMontages()
+	}
+
+	if unpacker.TileSize > 0 {
+		return unpacker.writeUpscaleTiles()
+	}
+
+	unpacker.aliases = findAliases(unpacker.Pack)
+	if len(unpacker.aliases) > 0 {
+		logf(tr("[info] found %d aliased frames\n"), len(unpacker.aliases))
+	}
+
+	// Ordered after indexing, so {index} and aliases still follow the atlas.
+	if unpacker.Order != "" {
+		unpacker.Pack = orderPack(unpacker.Pack, unpacker.Order)
+	}
+
+	collisions, err := unpacker.resolveCollisions()
+	if err != nil {
+		return err
+	}
+	unpacker.collisions = collisions
+
+	if unpacker.Dedup != "" && unpacker.Dedup != "none" {
+		unpacker.dedup = newDeduper(unpacker.Dedup)
+	}
+
+	if unpacker.SkipExisting {
+		if unpacker.previous, err = readManifest(unpacker.OutputDir); err != nil {
+			return err
+		}
+	}
+
+	if unpacker.KeepGoing {
+		unpacker.failures = &FailureLog{}
+	}
+
+	if unpacker.Export == "dataset" {
+		unpacker.datasetFrames = &sync.Map{}
+	}
+
+	if unpacker.Resume {
+		if unpacker.resume, err = openResumeState(unpacker.OutputDir, unpacker.AtlasPath); err != nil {
+			return err
+		}
+	}
+
+	if unpacker.Manifest {
+		unpacker.manifest = newManifestWriter(filepath.Join(unpacker.OutputDir, "manifest.json"), unpacker.PackName, unpacker.Pack, unpacker.Checkpoint)
 	}
 
 	var p *mpb.Progress = nil
@@ -198,7 +747,11 @@ func (unpacker Unpacker) unpack(noProgress bool) error {
 
 	totalTextures := 0
 
-	if isTTY() && !noProgress {
+	// Progress bars would garble the lines -v prints, and -q asks for none.
+	// Progress records go to stderr instead, so nothing stops them.
+	if unpacker.Progress == "json" {
+		unpacker.progress = newProgressStream(os.Stderr, unpacker.Sheets)
+	} else if isTTY() && !noProgress && verbosity == verbosityNormal {
 		p = mpb.New()
 		sheetBars = make(map[string]*mpb.Bar)
 	}
@@ -206,70 +759,303 @@ func (unpacker Unpacker) unpack(noProgress bool) error {
 	for _, sh := range unpacker.Sheets {
 		totalTextures += len(sh.Textures)
 
-		if p != nil {
-			sheetBars[sh.Image] = p.AddBar(
-				int64(len(sh.Textures)),
-				mpb.PrependDecorators(
-					decor.Name(sh.Image+" ", decor.WCSyncWidth),
-					decor.CountersNoUnit("%d / %d"),
-				),
-				mpb.AppendDecorators(
-					decor.Percentage(),
-				),
-			)
+		// Packs with dozens of sheets can collapse them into the total.
+		if p != nil && unpacker.Progress != "total" {
+			sheetBars[sh.Image] = p.AddBar(int64(len(sh.Textures)), barDecorators(sh.Image)...)
 		}
 	}
 
 	if p != nil {
-		totalBar = p.AddBar(
-			int64(totalTextures),
-			mpb.PrependDecorators(
-				decor.Name("Total ", decor.WCSyncWidth),
-				decor.CountersNoUnit("%d / %d"),
-			),
-			mpb.AppendDecorators(
-				decor.Percentage(),
-			),
-		)
-	}
-
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+		totalBar = p.AddBar(int64(totalTextures), barDecorators("Total")...)
+	}
+
+	runs := make([]*sheetRun, len(unpacker.Sheets))
+	for i, sh := range unpacker.Sheets {
+		runs[i] = newSheetRun(sh, sheetBars[sh.Image])
+	}
+
+	// --max-memory bounds the decoded sheets held at once, each held until
+	// its last frame is done.
+	var budget *MemoryBudget
+	if unpacker.MaxMemory > 0 {
+		budget = newMemoryBudget(unpacker.MaxMemory)
+	}
+
+	// Without --keep-going the first error stops the run: no more sheets are
+	// decoded and no more frames handed out.
+	stop := make(chan struct{})
+	var stopOnce sync.Once
 	var firstErr error
+	fail := func(err error) {
+		stopOnce.Do(func() {
+			firstErr = err
+			close(stop)
+			if budget != nil {
+				budget.stop()
+			}
+		})
+	}
 
-	for _, sh := range unpacker.Sheets {
-		wg.Add(1)
-
-		go func(sh Sheet, sBar, tBar *mpb.Bar) {
-			defer wg.Done()
-			if err := unpacker.unpackSheet(sh, sBar, tBar); err != nil {
-				mu.Lock()
-				if firstErr == nil {
-					firstErr = err
+	// Fewer IO workers than encoders cap the files written at once, for disks
+	// that slow down under many concurrent writes.
+	encodeWorkers := cmp.Or(unpacker.EncodeWorkers, unpacker.Workers)
+	if ioWorkers := cmp.Or(unpacker.IOWorkers, unpacker.Workers); ioWorkers < encodeWorkers {
+		unpacker.ioSlots = make(chan struct{}, ioWorkers)
+	}
+
+	// Sheets are decoded in order, each in its own goroutine as soon as one of
+	// DecodeWorkers slots is free, and released with their last frame. A
+	// slot is held from decoding until then, so at most DecodeWorkers sheets
+	// are decoding or decoded at once, and the next sheets decode while the
+	// workers extract the frames of those before them.
+	decoded := make(chan struct{}, cmp.Or(unpacker.DecodeWorkers, unpacker.Workers))
+	var preparing sync.WaitGroup
+	preparing.Go(func() {
+		for _, run := range runs {
+			select {
+			case decoded <- struct{}{}:
+			case <-stop:
+				return
+			}
+			reserved := int64(0)
+			if budget != nil {
+				needed := sheetBytes(unpacker.InputDir, run.Sheet)
+				if needed > budget.limit {
+					logAt(verbositySheets, tr("[info] decoding %s alone, its %s exceed --max-memory\n"), run.Image, formatBytes(needed))
+				}
+				var ok bool
+				if reserved, ok = budget.acquire(needed); !ok {
+					return
+				}
+			}
+			run.release = func() {
+				if budget != nil {
+					budget.release(reserved)
+				}
+				<-decoded
+			}
+			preparing.Go(func() {
+				run.err = unpacker.prepareSheet(run)
+				close(run.ready)
+			})
+		}
+	})
+
+	// One pool of EncodeWorkers extracts the frames of every sheet, sheet by sheet,
+	// so the number of goroutines and open files stays bounded however many
+	// sheets the pack has.
+	jobs := make(chan frameJob)
+	go func() {
+		defer close(jobs)
+		for _, run := range runs {
+			select {
+			case <-run.ready:
+			case <-stop:
+				return
+			}
+
+			if run.err != nil {
+				if unpacker.failures == nil {
+					fail(run.err)
+					return
+				}
+				// The sheet could not be read, so none of its frames were written.
+				unpacker.emit(Event{Kind: EventFail, Sheet: run.Image, Err: run.err})
+				unpacker.failures.add(run.Image, "", len(run.Textures), run.err)
+				run.failed.Store(int64(len(run.Textures)))
+				if totalBar != nil {
+					totalBar.IncrBy(len(run.Textures))
+				}
+			}
+			if run.err != nil || len(run.Textures) == 0 {
+				run.done(len(run.Textures))
+				continue
+			}
+
+			for _, tex := range run.Textures {
+				select {
+				case jobs <- frameJob{run, tex}:
+				case <-stop:
+					return
 				}
-				mu.Unlock()
 			}
-		}(sh, sheetBars[sh.Image], totalBar)
+		}
+	}()
+
+	var workers sync.WaitGroup
+	for range encodeWorkers {
+		workers.Go(func() {
+			for job := range jobs {
+				if err := job.run.extract(job.tex); err != nil {
+					unpacker.emit(Event{Kind: EventFail, Sheet: job.run.Image, Frame: job.tex.FileName, Err: err})
+					if unpacker.failures == nil {
+						fail(err)
+						continue
+					}
+					job.run.failed.Add(1)
+					unpacker.failures.add(job.run.Image, job.tex.FileName, 1, err)
+				}
+				job.run.done(1)
+				if totalBar != nil {
+					totalBar.Increment()
+				}
+				if unpacker.progress != nil {
+					unpacker.progress.increment(job.run.Image)
+				}
+			}
+		})
 	}
 
-	wg.Wait()
+	workers.Wait()
+	preparing.Wait()
 	if p != nil {
+		if firstErr != nil {
+			for _, bar := range append(slices.Collect(maps.Values(sheetBars)), totalBar) {
+				bar.Abort(false)
+			}
+		}
 		p.Wait()
 	}
+	if unpacker.progress != nil {
+		unpacker.progress.close()
+	}
 
 	if firstErr != nil {
+		// Leave the partial manifest behind so the frames that were written are known.
+		if unpacker.manifest != nil {
+			unpacker.manifest.close(false)
+		}
+		if unpacker.resume != nil {
+			unpacker.resume.close(false)
+		}
 		return firstErr
 	}
 
-	fmt.Printf("[info] extracted %d textures from %d sheets\n", totalTextures, len(unpacker.Sheets))
+	if unpacker.Export == "dataset" {
+		if err := unpacker.writeDatasetIndex(); err != nil {
+			return err
+		}
+	}
+
+	// With failures the run is left incomplete, so --resume retries the failed frames.
+	complete := !unpacker.failures.failed()
+
+	if unpacker.manifest != nil {
+		if err := unpacker.manifest.close(complete); err != nil {
+			return err
+		}
+	}
+
+	if unpacker.resume != nil {
+		if err := unpacker.resume.close(complete); err != nil {
+			return err
+		}
+	}
+
+	if unpacker.dedup != nil {
+		if err := unpacker.dedup.settle(); err != nil {
+			return err
+		}
+		unpacker.dedup.report()
+	}
 
+	if !unpacker.MTime.IsZero() {
+		if err := stampTimes(unpacker.OutputDir, unpacker.MTime); err != nil {
+			return err
+		}
+	}
+
+	summaries := make([]SheetSummary, len(runs))
+	for i, run := range runs {
+		summaries[i] = run.summary()
+	}
+	summary := newRunSummary(summaries, time.Since(start))
+	if porcelainOut != nil {
+		summary.porcelain(porcelainOut)
+	}
+	if jsonLog != nil {
+		jsonLog.write(LogRecord{Event: "summary", Summary: &summary})
+	}
+	if unpacker.Summary == "" {
+		logSuccess(tr("[info] extracted %d textures from %d sheets\n"), totalTextures, len(unpacker.Sheets))
+	} else if verbosity > verbositySilent {
+		if err := summary.print(unpacker.Summary); err != nil {
+			return err
+		}
+	}
+
+	if !complete {
+		return unpacker.failures.report(unpacker.ErrorReport)
+	}
 	return nil
 }
 
-func main() {
+func newRootCmd() *cobra.Command {
 	var outputDir string
 	var workers int = 2 * runtime.NumCPU()
+	var decodeWorkers, encodeWorkers, ioWorkers int
+	var maxMemory int64
 	var noProgress bool = false
+	var export string
+	var labelMap string
+	var attributionPath string
+	var augmentations []string
+	var variants int = 4
+	var seed uint64 = 1
+	var format string
+	var porcelain bool = false
+	var lang string
+	var logFormat string = logFormats[0]
+	var quiet, verbose int
+	var logFile string
+	var colorMode string = colorModes[0]
+	var noFollow bool = false
+	var frames []string
+	var locale string
+	var knownLocales []string
+	var include []string
+	var exclude []string
+	var renameExprs []string
+	var nameTemplate string
+	var names string = nameStrategies[0]
+	var encoding = SpriteEncoding{Format: spriteFormats[0], Speed: 6, PNGCompression: "default"}
+	var symlinks string = symlinkModes[0]
+	var skipAliases bool = false
+	var strictNames bool = false
+	var order string = frameOrders[0]
+	var summary string = summaryModes[0]
+	var progress string = progressModes[0]
+	var preferFormats []string
+	var trimMode string = trimModes[0]
+	var channels string = channelModes[0]
+	var unpremultiplied bool = false
+	var anchors bool = false
+	var meshes bool = false
+	var nineSlice string = nineSliceModes[0]
+	var sidecar string = sidecarModes[0]
+	var dedup string = dedupModes[0]
+	var onCollision string = collisionModes[0]
+	var skipExisting bool = false
+	var onlyNewer bool = false
+	var resume bool = false
+	var force bool = false
+	var keepGoing bool = false
+	var errorReport string
+	var autoPivot string = autoPivotModes[0]
+	var tileSize int = 0
+	var tileOverlap int = 32
+	var tileFrames bool = false
+	var shrink int = 0
+	var scale float64 = 1
+	var resolution string
+	var background string
+	var scaleFilter string = "catmull-rom"
+	var srgb bool = false
+	var flatten bool = false
+	var flattenChar string = "_"
+	var manifest bool = true
+	var checkpoint = Checkpoint{Frames: 1000, Interval: 10 * time.Second}
+	var mtime string = os.Getenv("SOURCE_DATE_EPOCH")
 
 	if workers > 32 {
 		workers = 32
@@ -279,21 +1065,311 @@ func main() {
 		Use:   "txunpak <path>",
 		Short: "Unpoack Phaser assets",
 		Args:  cobra.ExactArgs(1),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			language = lang
+			if language == "" {
+				language = detectLanguage()
+			}
+			if !slices.Contains(languages, language) {
+				return fmt.Errorf(tr("invalid language %q, expected en, ja, or zh"), lang)
+			}
+
+			if quiet > 0 && verbose > 0 {
+				return errors.New(tr("--quiet and --verbose cannot be combined"))
+			}
+			verbosity = verbose - quiet
+
+			if !slices.Contains(logFormats, logFormat) {
+				return fmt.Errorf(tr("invalid log format %q, expected text or json"), logFormat)
+			}
+
+			if logFile != "" {
+				var err error
+				if fileLog, err = openFileLog(logFile); err != nil {
+					return err
+				}
+				fileLog.printf("[info] txunpak %s\n", strings.Join(os.Args[1:], " "))
+			}
+			if porcelain {
+				startPorcelain()
+			}
+			if !slices.Contains(colorModes, colorMode) {
+				return fmt.Errorf(tr("invalid color mode %q, expected auto, always, or never"), colorMode)
+			}
+			colorize = useColor(colorMode)
+			if logFormat == "json" {
+				// Errors are logged as a record, so cobra printing them too would break the stream.
+				cmd.Root().SilenceErrors, cmd.Root().SilenceUsage = true, true
+				startJSONLog()
+			}
+			return profiler.start()
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var path = args[0]
 
-			if filepath.Ext(path) != ".json" {
-				return fmt.Errorf("input file must be a .json file")
+			if export != "" && export != "dataset" && export != "locales" && export != "montage" {
+				return fmt.Errorf(tr("invalid export mode %q, expected dataset, locales, or montage"), export)
+			}
+
+			if len(augmentations) > 0 && export != "dataset" {
+				return errors.New(tr("augmentations require --export dataset"))
+			}
+
+			augmenter, err := newAugmenter(augmentations, variants, seed)
+			if err != nil {
+				return err
+			}
+
+			var labels map[string]string
+			var attributions Attributions
+			if attributionPath != "" {
+				if attributions, err = loadAttributions(attributionPath); err != nil {
+					return err
+				}
+			}
+
+			if labelMap != "" {
+				if labels, err = loadLabelMap(labelMap); err != nil {
+					return err
+				}
+			}
+
+			if checkpoint.Frames < 1 {
+				return fmt.Errorf(tr("invalid checkpoint-frames %d, must be at least 1"), checkpoint.Frames)
+			}
+
+			var stamp time.Time
+			if mtime != "" {
+				if stamp, err = parseMTime(mtime); err != nil {
+					return err
+				}
+			}
+
+			renames, err := parseRenames(renameExprs)
+			if err != nil {
+				return err
+			}
+
+			var template NameTemplate
+			if nameTemplate != "" {
+				if template, err = parseNameTemplate(nameTemplate); err != nil {
+					return err
+				}
+				if !cmd.Flags().Changed("names") {
+					names = "templated"
+				}
+			}
+
+			nameStrategy, err := newNameStrategy(names, template)
+			if err != nil {
+				return err
+			}
+
+			encoder, err := newSpriteEncoder(encoding)
+			if err != nil {
+				return err
+			}
+
+			if _, unsafe := sanitizeName(flattenChar); flatten && (flattenChar == "" || strings.Contains(flattenChar, "/") || unsafe) {
+				return fmt.Errorf(tr("invalid flatten-char %q, must be non-empty and valid in file names"), flattenChar)
+			}
+			if !flatten {
+				flattenChar = ""
+			}
+
+			if err := checkFrameOrder(order); err != nil {
+				return err
+			}
+
+			if !slices.Contains(progressModes, progress) {
+				return fmt.Errorf(tr("invalid progress %q, expected bar, total, or json"), progress)
+			}
+
+			if !slices.Contains(summaryModes, summary) {
+				return fmt.Errorf(tr("invalid summary %q, expected table, json, or none"), summary)
+			}
+
+			var matteColor *color.NRGBA
+			if background != "" {
+				c, err := parseColor(background)
+				if err != nil {
+					return err
+				}
+				matteColor = &c
+			}
+
+			if scale <= 0 {
+				return fmt.Errorf(tr("invalid scale %g, must be positive"), scale)
+			}
+			if err := checkScaleFilter(scaleFilter); err != nil {
+				return err
+			}
+
+			if shrink < 0 {
+				return fmt.Errorf(tr("invalid shrink %d, must not be negative"), shrink)
+			}
+			if !cmd.Flags().Changed("shrink") {
+				shrink = -1
+			}
+
+			for _, name := range []string{"decode-workers", "encode-workers", "io-workers"} {
+				if n, _ := cmd.Flags().GetInt(name); n < 0 {
+					return fmt.Errorf(tr("invalid %s %d, must not be negative"), name, n)
+				}
+			}
+			if maxMemory < 0 {
+				return fmt.Errorf(tr("invalid max-memory %d, must not be negative"), maxMemory)
+			}
+
+			if !slices.Contains(trimModes, trimMode) {
+				return fmt.Errorf(tr("invalid trim mode %q, expected full or tight"), trimMode)
+			}
+
+			channelMaps, err := channelOutputs(channels)
+			if err != nil {
+				return err
+			}
+
+			if !slices.Contains(nineSliceModes, nineSlice) {
+				return fmt.Errorf(tr("invalid nine-slice mode %q, expected none, json, or split"), nineSlice)
+			}
+
+			if !slices.Contains(sidecarModes, sidecar) {
+				return fmt.Errorf(tr("invalid sidecar %q, expected none or json"), sidecar)
+			}
+
+			if !slices.Contains(autoPivotModes, autoPivot) {
+				return fmt.Errorf(tr("invalid auto-pivot %q, expected none, center, bottom-center, or opaque-centroid"), autoPivot)
+			}
+
+			if tileSize < 0 {
+				return fmt.Errorf(tr("invalid tile size %d"), tileSize)
+			}
+			if tileSize > 0 && (tileOverlap < 0 || 2*tileOverlap >= tileSize) {
+				return fmt.Errorf(tr("invalid overlap %d, expected 0 to under half the tile size"), tileOverlap)
+			}
+
+			if !slices.Contains(dedupModes, dedup) {
+				return fmt.Errorf(tr("invalid dedup mode %q, expected none, hardlink, symlink, or copy"), dedup)
+			}
+			if !slices.Contains(collisionModes, onCollision) {
+				return fmt.Errorf(tr("invalid collision mode %q, expected error, skip, or suffix"), onCollision)
+			}
+
+			if skipAliases && !manifest {
+				return errors.New(tr("--skip-aliases requires --manifest to record the aliases"))
+			}
+
+			if errorReport != "" && !keepGoing {
+				return errors.New(tr("--error-report requires --keep-going"))
+			}
+
+			if !slices.Contains(symlinkModes, symlinks) {
+				return fmt.Errorf(tr("invalid symlinks mode %q, expected reject or follow"), symlinks)
 			}
 
-			data, err := os.ReadFile(path)
+			filter, err := newFrameFilter(frames, locale, include, exclude)
 			if err != nil {
-				return fmt.Errorf("failed to read input: %w", err)
+				return err
 			}
 
-			var pack Pack
-			if err := json.Unmarshal(data, &pack); err != nil {
-				return fmt.Errorf("invalid JSON: %w", err)
+			newUnpacker := func(pack Pack, packName, atlasPath, outputDir string) Unpacker {
+				locales := newLocaleIndex(pack, knownLocales)
+				if export == "locales" || locale != "" {
+					locales.reportMissing(locale)
+				}
+
+				return Unpacker{
+					Pack:           filter.apply(preferSheetFormats(pack, filepath.Dir(atlasPath), preferFormats), locales),
+					PackName:       packName,
+					AtlasPath:      atlasPath,
+					InputDir:       filepath.Dir(atlasPath),
+					OutputDir:      outputDir,
+					Workers:        workers,
+					MaxMemory:      maxMemory,
+					DecodeWorkers:  decodeWorkers,
+					EncodeWorkers:  encodeWorkers,
+					IOWorkers:      ioWorkers,
+					Export:         export,
+					Labels:         labels,
+					Attributions:   attributions,
+					Augment:        augmenter,
+					Locales:        locales,
+					Renames:        renames,
+					Names:          nameStrategy,
+					Encoder:        encoder,
+					Flatten:        flattenChar,
+					FollowSymlinks: symlinks == "follow",
+					SkipAliases:    skipAliases,
+					StrictNames:    strictNames,
+					Order:          order,
+					Summary:        summary,
+					Progress:       progress,
+					SRGB:           srgb,
+					TrimMode:       trimMode,
+					Shrink:         shrink,
+					Scale:          scale,
+					Filter:         scaleFilter,
+					Background:     matteColor,
+					Channels:       channelMaps,
+					Unpremultiply:  unpremultiplied,
+					Anchors:        anchors,
+					AutoPivot:      autoPivot,
+					Meshes:         meshes,
+					NineSlice:      nineSlice,
+					Sidecar:        sidecar,
+					Dedup:          dedup,
+					OnCollision:    onCollision,
+					TileSize:       tileSize,
+					TileOverlap:    tileOverlap,
+					TileFrames:     tileFrames,
+					SkipExisting:   skipExisting,
+					OnlyNewer:      onlyNewer,
+					Resume:         resume,
+					Force:          force,
+					KeepGoing:      keepGoing,
+					ErrorReport:    errorReport,
+					Manifest:       manifest,
+					Checkpoint:     checkpoint,
+					MTime:          stamp,
+				}
+			}
+
+			manifest, data, err := isLoaderManifest(path)
+			if err != nil {
+				return err
+			}
+
+			if manifest {
+				atlases, err := loadLoaderManifest(path, data)
+				if err != nil {
+					return err
+				}
+
+				packs := make([]Pack, 0, len(atlases))
+				for _, atlas := range atlases {
+					packs = append(packs, atlas.Pack)
+				}
+				if err := filter.missing(packs...); err != nil {
+					return err
+				}
+
+				if outputDir == "" {
+					outputDir = strings.TrimSuffix(path, filepath.Ext(path))
+				}
+
+				logf(tr("[info] found %d atlases in loader manifest\n"), len(atlases))
+				for _, atlas := range atlases {
+					unpacker := newUnpacker(atlas.Pack, atlas.Key, atlas.AtlasPath, filepath.Join(outputDir, filepath.FromSlash(atlas.Key)))
+					if len(unpacker.Sheets) == 0 {
+						continue
+					}
+					if err := unpacker.unpack(noProgress); err != nil {
+						return err
+					}
+				}
+
+				return nil
 			}
 
 			inputDir := filepath.Dir(path)
@@ -302,23 +1378,186 @@ func main() {
 				outputDir = filepath.Join(filepath.Dir(path), packName)
 			}
 
-			unpacker := Unpacker{
-				Pack:      pack,
-				PackName:  packName,
-				InputDir:  inputDir,
-				OutputDir: outputDir,
-				Workers:   workers,
+			unpackAtlas := func(path, outputDir string) error {
+				pack, err := loadPack(path, format)
+				if err != nil {
+					return err
+				}
+
+				if !noFollow {
+					if pack, err = followRelatedPacks(pack, path, format); err != nil {
+						return err
+					}
+				}
+
+				if err := filter.missing(pack); err != nil {
+					return err
+				}
+
+				unpacker := newUnpacker(pack, packName, path, outputDir)
+				return unpacker.unpack(noProgress)
+			}
+
+			variants, err := findResolutionVariants(path)
+			if err != nil {
+				return err
+			}
+
+			if resolution != "" {
+				target, err := parseResolution(resolution)
+				if err != nil {
+					return err
+				}
+				var found []string
+				for _, variant := range variants {
+					if variant.Resolution == target {
+						return unpackAtlas(variant.Path, outputDir)
+					}
+					found = append(found, "@"+strconv.FormatFloat(variant.Resolution, 'f', -1, 64)+"x")
+				}
+				return fmt.Errorf(tr("no %s variant of %s, found %s"), resolution, path, strings.Join(found, ", "))
 			}
 
-			return unpacker.unpack(noProgress)
+			if len(variants) <= 1 {
+				return unpackAtlas(path, outputDir)
+			}
+
+			// Each resolution gets its own folder so they do not overwrite each other.
+			logf(tr("[info] found %d resolution variants\n"), len(variants))
+			for _, variant := range variants {
+				if err := unpackAtlas(variant.Path, outputDir+variant.suffix()); err != nil {
+					return err
+				}
+			}
+			return nil
 		},
 	}
 
+	rootCmd.PersistentFlags().StringVarP(&format, "format", "", "", "Atlas format: "+atlasFormatNames()+" (detected when empty)")
+	rootCmd.PersistentFlags().IntVarP(&decodeLimits.MaxDimension, "max-dimension", "", decodeLimits.MaxDimension, "Largest width or height of any image read or allocated")
+	rootCmd.PersistentFlags().Int64VarP(&decodeLimits.MaxPixels, "max-pixels", "", decodeLimits.MaxPixels, "Largest pixel count of any image read or allocated")
+	rootCmd.PersistentFlags().IntVarP(&pageCacheSize, "page-cache", "", pageCacheSize, "Decoded pages kept in memory by commands reading single frames")
+	rootCmd.PersistentFlags().StringVarP(&lang, "lang", "", "", "Message language: en, ja, or zh (from LANG when empty)")
+	rootCmd.PersistentFlags().CountVarP(&quiet, "quiet", "q", "Print only warnings and the summary, or with -qq nothing but errors")
+	rootCmd.PersistentFlags().CountVarP(&verbose, "verbose", "v", "Also print each sheet, or with -vv each file written")
+	rootCmd.PersistentFlags().StringVarP(&logFormat, "log-format", "", logFormat, "Message format: text, or json for JSON lines on stderr")
+	rootCmd.PersistentFlags().StringVarP(&colorMode, "color", "", colorMode, "Color status lines: auto on a terminal without NO_COLOR, always, or never")
+	rootCmd.PersistentFlags().StringVarP(&logFile, "log-file", "", "", "Also append every message, failed frames included, to this file")
+	rootCmd.PersistentFlags().BoolVarP(&porcelain, "porcelain", "", porcelain, "Print stable, versioned, tab-separated records for scripts, moving other output to stderr")
+	rootCmd.PersistentFlags().StringVarP(&workspaceLimits.Root, "temp-dir", "", workspaceLimits.Root, "Directory for temporary workspaces")
+	rootCmd.PersistentFlags().Int64VarP(&workspaceLimits.MaxBytes, "temp-limit", "", workspaceLimits.MaxBytes, "Largest size in bytes of any temporary workspace")
+	rootCmd.PersistentFlags().StringVarP(&profiler.CPU, "cpuprofile", "", "", "Write a CPU profile of the run to this file")
+	rootCmd.PersistentFlags().StringVarP(&profiler.Mem, "memprofile", "", "", "Write a heap profile to this file when the run ends")
+	rootCmd.PersistentFlags().StringVarP(&profiler.Trace, "trace", "", "", "Write an execution trace of the run to this file")
+	for _, name := range []string{"cpuprofile", "memprofile", "trace"} {
+		rootCmd.PersistentFlags().MarkHidden(name)
+	}
 	rootCmd.Flags().StringVarP(&outputDir, "output", "o", "", "Output directory")
-	rootCmd.Flags().IntVarP(&workers, "workers", "w", workers, "Number of concurrent workers")
+	rootCmd.Flags().IntVarP(&workers, "workers", "w", workers, "Number of workers extracting frames, shared by all sheets, and the default of the other worker limits")
+	rootCmd.Flags().IntVarP(&decodeWorkers, "decode-workers", "", 0, "Most sheets decoding or decoded at once, or 0 for --workers")
+	rootCmd.Flags().IntVarP(&encodeWorkers, "encode-workers", "", 0, "Number of workers cropping and encoding frames, or 0 for --workers")
+	rootCmd.Flags().IntVarP(&ioWorkers, "io-workers", "", 0, "Most frame files written at once, or 0 for --workers")
+	rootCmd.Flags().Int64VarP(&maxMemory, "max-memory", "", 0, "Most bytes of decoded sheets held at once, or 0 for no limit")
 	rootCmd.Flags().BoolVarP(&noProgress, "no-progress", "", noProgress, "Disable progress bars")
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println("Error:", err)
-		os.Exit(1)
+	rootCmd.Flags().BoolVarP(&noFollow, "no-follow", "", noFollow, "Do not unpack packs listed in related_multi_packs")
+	rootCmd.Flags().StringSliceVarP(&frames, "frame", "", nil, "Only unpack the named frames, comma separated or repeated")
+	rootCmd.Flags().StringSliceVarP(&include, "include", "", nil, "Only unpack frames matching these globs, ** spans folders")
+	rootCmd.Flags().StringSliceVarP(&exclude, "exclude", "", nil, "Skip frames matching these globs, ** spans folders")
+	rootCmd.Flags().StringVarP(&locale, "locale", "", "", "Only unpack shared frames and those localized for this locale")
+	rootCmd.Flags().StringSliceVarP(&knownLocales, "locales", "", nil, "Locale suffixes to recognize, overriding the built-in list")
+	rootCmd.Flags().StringArrayVarP(&renameExprs, "rename", "", nil, "Rewrite output names with s/pattern/replacement/flags, repeatable")
+	rootCmd.Flags().StringVarP(&encoding.Format, "output-format", "", encoding.Format, "Sprite image format: "+strings.Join(spriteFormats, ", "))
+	rootCmd.Flags().IntVarP(&encoding.Quality, "quality", "", encoding.Quality, "Lossy quality from 1 to 100, 0 for the format default (jpeg 90, avif 60)")
+	rootCmd.Flags().IntVarP(&encoding.Speed, "speed", "", encoding.Speed, "AVIF encoder speed from 0 (smallest) to 10 (fastest)")
+	rootCmd.Flags().StringVarP(&encoding.PNGCompression, "png-compression", "", encoding.PNGCompression, "PNG compression: default, none, fast, or best")
+	rootCmd.Flags().BoolVarP(&encoding.PNGBufferPool, "png-buffer-pool", "", encoding.PNGBufferPool, "Reuse PNG encoder buffers across frames")
+	rootCmd.Flags().IntVarP(&encoding.Colors, "quantize", "", encoding.Colors, "Reduce PNG sprites to an indexed palette of at most this many colors")
+	rootCmd.Flags().StringVarP(&names, "names", "", names, "Output naming: "+strings.Join(nameStrategies, ", "))
+	rootCmd.Flags().StringVarP(&nameTemplate, "name-template", "", "", "Output name layout from {frame}, {name}, {dir}, {sheet}, {index}, {w}, {h}")
+	rootCmd.Flags().BoolVarP(&flatten, "flatten", "", flatten, "Write every frame into one folder, replacing / in names")
+	rootCmd.Flags().StringVarP(&flattenChar, "flatten-char", "", flattenChar, "Replacement for / when flattening")
+	rootCmd.Flags().BoolVarP(&strictNames, "strict-names", "", strictNames, "Fail on frame names invalid on Windows instead of sanitizing them")
+	rootCmd.Flags().StringVarP(&symlinks, "symlinks", "", symlinks, "Symlinks below the input and output directories: reject or follow")
+	rootCmd.Flags().BoolVarP(&manifest, "manifest", "", manifest, "Write manifest.json mapping every extracted frame to its file and hash in the output directory")
+	rootCmd.Flags().IntVarP(&checkpoint.Frames, "checkpoint-frames", "", checkpoint.Frames, "Flush the partial manifest after this many frames")
+	rootCmd.Flags().DurationVarP(&checkpoint.Interval, "checkpoint-interval", "", checkpoint.Interval, "Flush the partial manifest at least this often")
+	rootCmd.Flags().StringVarP(&mtime, "mtime", "", mtime, "Set the modification time of every output to this Unix time or RFC 3339 time, SOURCE_DATE_EPOCH by default")
+	rootCmd.Flags().StringSliceVarP(&preferFormats, "prefer-format", "", nil, "Sheet image formats to prefer when several ship side by side, like png,webp,ktx2")
+	rootCmd.Flags().StringVarP(&background, "background", "", "", "Composite sprites over this color (#rrggbb or #rrggbbaa) instead of transparency")
+	rootCmd.Flags().StringVarP(&resolution, "resolution", "", "", "Unpack only this resolution of a multi-resolution export, like 2x or 0.5x")
+	rootCmd.Flags().Float64VarP(&scale, "scale", "", scale, "Resize sprites by this factor, on top of undoing the atlas scale")
+	rootCmd.Flags().StringVarP(&scaleFilter, "filter", "", scaleFilter, "Scaling filter: nearest for pixel art, bilinear, or catmull-rom")
+	rootCmd.Flags().IntVarP(&shrink, "shrink", "", shrink, "Contract every frame by this many pixels of extrusion bleed, read from the atlas meta when unset")
+	rootCmd.Flags().StringVarP(&sidecar, "sidecar", "", sidecar, "Per-sprite metadata: none, or json to write frame rect, trim, rotation, sheet, and pivot next to each sprite")
+	rootCmd.Flags().StringVarP(&dedup, "dedup", "", dedup, "Write pixel-identical sprites once: none, or hardlink, symlink, or copy the duplicates")
+	rootCmd.Flags().BoolVarP(&skipExisting, "skip-existing", "", skipExisting, "Keep outputs an earlier run left that still match its manifest instead of extracting them again")
+	rootCmd.Flags().BoolVarP(&onlyNewer, "only-newer", "", onlyNewer, "Keep outputs newer than the atlas and their sheet instead of extracting them again")
+	rootCmd.Flags().BoolVarP(&keepGoing, "keep-going", "", keepGoing, "Extract every frame that can be, reporting the frames that failed at the end")
+	rootCmd.Flags().StringVarP(&errorReport, "error-report", "", "", "Write the frames that failed with --keep-going to this JSON file")
+	rootCmd.Flags().BoolVarP(&force, "force", "", force, "Write into a non-empty output directory no earlier run produced")
+	rootCmd.Flags().BoolVarP(&resume, "resume", "", resume, "Log finished frames and pick up where a cancelled or crashed run stopped")
+	rootCmd.Flags().StringVarP(&onCollision, "on-collision", "", onCollision, "When frames share an output path: error, skip the later ones, or suffix them with _2, _3, ...")
+	rootCmd.Flags().IntVarP(&tileSize, "tile-for-upscale", "", tileSize, "Cut pages into overlapping tiles of this size for an external upscaler instead of extracting sprites")
+	rootCmd.Flags().IntVarP(&tileOverlap, "overlap", "", tileOverlap, "Pixels each upscale tile overlaps its neighbours by")
+	rootCmd.Flags().BoolVarP(&tileFrames, "tile-frames", "", tileFrames, "Tile each frame instead of each page")
+	rootCmd.Flags().StringVarP(&nineSlice, "nine-slice", "", nineSlice, "Nine-slice borders: none, json to write nine-slice.json, or split into nine patches")
+	rootCmd.Flags().BoolVarP(&meshes, "meshes", "", meshes, "Write the mesh of every polygon-trimmed frame to meshes.json")
+	rootCmd.Flags().BoolVarP(&anchors, "anchors", "", anchors, "Write each frame's pivot and trim offset to anchors.json")
+	rootCmd.Flags().StringVarP(&autoPivot, "auto-pivot", "", autoPivot, "Pivot for frames without one, from their opaque pixels: none, center, bottom-center, or opaque-centroid")
+	rootCmd.Flags().BoolVarP(&unpremultiplied, "unpremultiply", "", unpremultiplied, "Divide color by alpha for sheets packed with premultiplied alpha")
+	rootCmd.Flags().StringVarP(&channels, "channels", "", channels, "Channels to write: rgba, rgb, alpha, split into one grayscale image per channel, or a profile like RG=normal,B=roughness,A=mask")
+	rootCmd.Flags().StringVarP(&trimMode, "trim-mode", "", trimMode, "Sprite canvas: full restores the source size, tight keeps only the trimmed pixels")
+	rootCmd.Flags().StringVarP(&progress, "progress", "", progress, "Progress display: bar per sheet on a terminal, total for just the total bar, or json for periodic records on stderr")
+	rootCmd.Flags().StringVarP(&summary, "summary", "", summary, "End-of-run summary: table, json, or none")
+	rootCmd.Flags().BoolVarP(&srgb, "srgb", "", srgb, "Convert gamma-tagged sheets to sRGB instead of carrying their color profile through")
+	rootCmd.Flags().StringVarP(&order, "order", "", order, "Frame processing order: "+strings.Join(frameOrders, ", "))
+	rootCmd.Flags().BoolVarP(&skipAliases, "skip-aliases", "", skipAliases, "Write frames sharing a sheet rect once, recording the aliases in the manifest")
+	rootCmd.Flags().StringVarP(&export, "export", "", "", "Export mode: dataset, locales, or montage")
+	rootCmd.Flags().StringVarP(&labelMap, "label-map", "", "", "JSON file mapping frame names to dataset labels")
+	rootCmd.Flags().StringVarP(&attributionPath, "attribution", "", "", "JSON file mapping frame names or globs to their author, license, and source")
+	rootCmd.Flags().StringSliceVarP(&augmentations, "augment", "", nil, "Dataset augmentations: flip, rotate, hue, noise")
+	rootCmd.Flags().IntVarP(&variants, "variants", "", variants, "Number of augmented variants per frame")
+	rootCmd.Flags().Uint64VarP(&seed, "seed", "", seed, "Random seed for augmentations")
+	rootCmd.AddCommand(newGridifyCmd())
+	rootCmd.AddCommand(newComposeCmd())
+	rootCmd.AddCommand(newTilemapCmd())
+	rootCmd.AddCommand(newTextCmd())
+	rootCmd.AddCommand(newPackCmd())
+	rootCmd.AddCommand(newConvertCmd())
+	rootCmd.AddCommand(newRoundTripCmd())
+	rootCmd.AddCommand(newAdviseCmd())
+	rootCmd.AddCommand(newColorsCmd())
+	rootCmd.AddCommand(newListCmd())
+	rootCmd.AddCommand(newDiffCmd())
+	rootCmd.AddCommand(newCheckCmd())
+	rootCmd.AddCommand(newVerifyCmd())
+	rootCmd.AddCommand(newSearchCmd())
+	rootCmd.AddCommand(newLintCmd())
+	rootCmd.AddCommand(newOrphansCmd())
+	rootCmd.AddCommand(newExtractCmd())
+	rootCmd.AddCommand(newIndexCmd())
+	rootCmd.AddCommand(newPipelineCmd())
+	rootCmd.AddCommand(newReassembleCmd())
+	rootCmd.AddCommand(newSelftestCmd())
+	rootCmd.AddCommand(newBenchCmd())
+
+	return rootCmd
+}
+
+func main() {
+	err := newRootCmd().Execute()
+	if stopErr := profiler.stop(); err == nil {
+		err = stopErr
+	}
+	if err != nil {
+		if jsonLog != nil {
+			jsonLog.write(LogRecord{Event: "error", Error: err.Error()})
+		} else {
+			if fileLog != nil {
+				fileLog.printf("[error] %v\n", err)
+			}
+			fmt.Fprintln(os.Stderr, paint(colorRed, tr("Error:")+" "+err.Error()))
+		}
+		os.Exit(exitCode(err))
 	}
 }
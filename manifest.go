@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type ManifestFrame struct {
+	Path        string       `json:"path"`
+	Sheet       string       `json:"sheet"`
+	Width       int          `json:"w"`
+	Height      int          `json:"h"`
+	AliasOf     string       `json:"aliasOf,omitempty"`
+	SHA256      string       `json:"sha256,omitempty"`
+	Attribution *Attribution `json:"attribution,omitempty"`
+}
+
+type ManifestSheet struct {
+	Image  string  `json:"image"`
+	Width  int     `json:"w"`
+	Height int     `json:"h"`
+	Scale  float64 `json:"scale,omitempty"`
+	Frames int     `json:"frames"`
+}
+
+// A Manifest records every frame written so far. Complete stays false until
+// the run finishes, so a checkpoint left by a crash lists exactly what made it
+// to disk.
+type Manifest struct {
+	Pack     string                   `json:"pack"`
+	Meta     map[string]string        `json:"meta,omitempty"`
+	Sheets   []ManifestSheet          `json:"sheets"`
+	Complete bool                     `json:"complete"`
+	Frames   map[string]ManifestFrame `json:"frames"`
+}
+
+// Checkpoint sets how often the partial manifest is flushed, after so many
+// frames or so much time, whichever comes first.
+type Checkpoint struct {
+	Frames   int
+	Interval time.Duration
+}
+
+type ManifestWriter struct {
+	Path       string
+	Checkpoint Checkpoint
+	mu         sync.Mutex
+	manifest   Manifest
+	pending    int
+	flushed    time.Time
+}
+
+func newManifestWriter(path, packName string, pack Pack, checkpoint Checkpoint) *ManifestWriter {
+	sheets := make([]ManifestSheet, 0, len(pack.Sheets))
+	for _, sh := range pack.Sheets {
+		sheets = append(sheets, ManifestSheet{
+			Image:  sh.Image,
+			Width:  sh.Size.Width,
+			Height: sh.Size.Height,
+			Scale:  sh.Scale,
+			Frames: len(sh.Textures),
+		})
+	}
+
+	return &ManifestWriter{
+		Path:       path,
+		Checkpoint: checkpoint,
+		manifest:   Manifest{Pack: packName, Meta: pack.Meta, Sheets: sheets, Frames: make(map[string]ManifestFrame)},
+		flushed:    time.Now(),
+	}
+}
+
+// hashFile returns the hex SHA-256 of a written sprite.
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (writer *ManifestWriter) record(name string, frame ManifestFrame) error {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	writer.manifest.Frames[name] = frame
+	writer.pending++
+
+	if writer.pending >= writer.Checkpoint.Frames || time.Since(writer.flushed) >= writer.Checkpoint.Interval {
+		return writer.flush()
+	}
+	return nil
+}
+
+// close writes the final manifest, marked complete only when the run
+// succeeded. Skipped aliases are hashed here, once every file they may point
+// at has been written.
+func (writer *ManifestWriter) close(complete bool) error {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	dir := filepath.Dir(writer.Path)
+	for name, frame := range writer.manifest.Frames {
+		if frame.SHA256 != "" {
+			continue
+		}
+		if sum, err := hashFile(filepath.Join(dir, filepath.FromSlash(frame.Path))); err == nil {
+			frame.SHA256 = sum
+			writer.manifest.Frames[name] = frame
+		}
+	}
+
+	writer.manifest.Complete = complete
+	return writer.flush()
+}
+
+// flush replaces the manifest through a rename so a crash mid-write never
+// leaves it truncated. The caller holds the lock.
+func (writer *ManifestWriter) flush() error {
+	data, err := json.MarshalIndent(writer.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	tmpPath := writer.Path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0o644); err != nil {
+		return withExitCode(exitOutput, fmt.Errorf("failed to write manifest: %w", err))
+	}
+	if err := os.Rename(tmpPath, writer.Path); err != nil {
+		return withExitCode(exitOutput, fmt.Errorf("failed to write manifest: %w", err))
+	}
+
+	writer.pending = 0
+	writer.flushed = time.Now()
+	return nil
+}
+
+// manifestFrame records where a frame was written, which for a skipped alias
+// is the file of the frame it aliases. Frames written as several files, like
+// split channels, have no single file to hash.
+func (unpacker Unpacker) manifestFrame(sheet Sheet, texture Texture) ManifestFrame {
+	written, aliasOf := texture, ""
+	if canonical, ok := unpacker.aliases[texture.FileName]; ok {
+		aliasOf = canonical.FileName
+		if unpacker.SkipAliases {
+			written = canonical
+		}
+	}
+
+	path, _ := filepath.Rel(unpacker.OutputDir, unpacker.outputPath(written))
+	frame := ManifestFrame{
+		Path:        filepath.ToSlash(path),
+		Sheet:       sheet.Image,
+		Width:       texture.SourceSize.Width,
+		Height:      texture.SourceSize.Height,
+		AliasOf:     aliasOf,
+		Attribution: unpacker.attribution(texture),
+	}
+	if written.FileName == texture.FileName {
+		frame.SHA256, _ = hashFile(unpacker.outputPath(written))
+	}
+	return frame
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// matte composites sprite over a solid background, keeping 16-bit depth and
+// float values. Grayscale sprites have no transparency to fill, so they are
+// left alone.
+func matte(sprite image.Image, background color.NRGBA) image.Image {
+	var canvas draw.Image
+	switch sprite := sprite.(type) {
+	case *image.Gray, *image.Gray16:
+		return sprite
+	case *FloatImage:
+		return matteFloat(sprite, background)
+	case *image.NRGBA64:
+		canvas = image.NewNRGBA64(sprite.Bounds())
+	default:
+		canvas = image.NewNRGBA(sprite.Bounds())
+	}
+
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(canvas, canvas.Bounds(), sprite, sprite.Bounds().Min, draw.Over)
+	return canvas
+}
+
+// matteFloat composites premultiplied float pixels over the background
+// without the clamping draw.Draw would apply to them.
+func matteFloat(sprite *FloatImage, background color.NRGBA) *FloatImage {
+	bg := FloatModel.Convert(background).(FloatColor)
+	bounds := sprite.Bounds()
+	canvas := NewFloatImage(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := sprite.FloatAt(x, y)
+			under := 1 - c.A
+			canvas.SetFloat(x, y, FloatColor{c.R + bg.R*under, c.G + bg.G*under, c.B + bg.B*under, c.A + bg.A*under})
+		}
+	}
+	return canvas
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// A MemoryBudget bounds the bytes of decoded sheets held at once, for huge
+// sheets on machines that would run out of memory holding several. A sheet
+// larger than the whole budget takes all of it, so it is held alone and
+// sheets are processed one after another.
+type MemoryBudget struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int64
+	free    int64
+	stopped bool
+}
+
+func newMemoryBudget(limit int64) *MemoryBudget {
+	budget := &MemoryBudget{limit: limit, free: limit}
+	budget.cond = sync.NewCond(&budget.mu)
+	return budget
+}
+
+// acquire waits until n bytes, at most the whole budget, are free and takes
+// them, returning how many it took. It returns false once the run stopped.
+func (budget *MemoryBudget) acquire(n int64) (int64, bool) {
+	n = min(n, budget.limit)
+
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	for budget.free < n && !budget.stopped {
+		budget.cond.Wait()
+	}
+	if budget.stopped {
+		return 0, false
+	}
+	budget.free -= n
+	return n, true
+}
+
+func (budget *MemoryBudget) release(n int64) {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	budget.free += n
+	budget.cond.Broadcast()
+}
+
+// stop wakes everyone waiting in acquire, for a run that ended early.
+func (budget *MemoryBudget) stop() {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	budget.stopped = true
+	budget.cond.Broadcast()
+}
+
+// sheetBytes estimates the memory a sheet takes once decoded, from its image
+// header when it can be read and otherwise from the size the atlas gives.
+func sheetBytes(inputDir string, sheet Sheet) int64 {
+	width, height, depth := sheet.Size.Width, sheet.Size.Height, 4
+	if file, err := os.Open(filepath.Join(inputDir, sheet.Image)); err == nil {
+		config, _, err := image.DecodeConfig(file)
+		file.Close()
+		if err == nil {
+			width, height, depth = config.Width, config.Height, pixelDepth(config.ColorModel)
+		}
+	}
+	return int64(width) * int64(height) * int64(depth)
+}
+
+// pixelDepth is the bytes per pixel a color model decodes to, taking 8-bit
+// RGBA for any it does not know.
+func pixelDepth(model color.Model) int {
+	switch model {
+	case color.GrayModel:
+		return 1
+	case color.Gray16Model:
+		return 2
+	case color.RGBA64Model, color.NRGBA64Model:
+		return 8
+	case FloatModel:
+		return 16
+	}
+	return 4
+}
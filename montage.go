@@ -0,0 +1,109 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	montageCell  = 96
+	montageLabel = 16
+)
+
+var montageBackground = color.RGBA{0x2b, 0x2b, 0x2b, 0xff}
+
+// montageFolders groups frame names by folder, with frames outside any
+// folder under "root".
+func montageFolders(pack Pack) map[string]map[string]bool {
+	folders := make(map[string]map[string]bool)
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			folder := path.Dir(tex.FileName)
+			if folder == "." {
+				folder = "root"
+			}
+			if folders[folder] == nil {
+				folders[folder] = make(map[string]bool)
+			}
+			folders[folder][tex.FileName] = true
+		}
+	}
+	return folders
+}
+
+// montageLabelText fits a frame's base name into a cell, marking cuts with ~.
+func montageLabelText(name string) string {
+	label := path.Base(name)
+	limit := montageCell / basicfont.Face7x13.Advance
+	if len(label) > limit {
+		label = label[:limit-1] + "~"
+	}
+	return label
+}
+
+// montage lays out one folder's frames scaled into uniform cells, each
+// labeled with its frame name underneath.
+func (unpacker Unpacker) montage(frames map[string]bool) (*image.RGBA, int, error) {
+	gridifier := Gridifier{
+		Pack:     FrameFilter{Names: frames}.apply(unpacker.Pack, LocaleIndex{}),
+		InputDir: unpacker.InputDir,
+		Cell:     Size{Width: montageCell, Height: montageCell},
+		Fit:      "scale",
+	}
+
+	grid, index, err := gridifier.gridify()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rowHeight := montageCell + montageLabel
+	canvas, err := newCanvas(index.Columns*montageCell, index.Rows*rowHeight)
+	if err != nil {
+		return nil, 0, err
+	}
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(montageBackground), image.Point{}, draw.Src)
+
+	drawer := font.Drawer{Dst: canvas, Src: image.White, Face: basicfont.Face7x13}
+	for _, cell := range index.Frames {
+		origin := image.Point{cell.Column * montageCell, cell.Row * rowHeight}
+		target := image.Rectangle{origin, origin.Add(image.Point{montageCell, montageCell})}
+		draw.Draw(canvas, target, grid, cell.Frame.Rect().Min, draw.Over)
+
+		label := montageLabelText(cell.FileName)
+		width := drawer.MeasureString(label).Ceil()
+		drawer.Dot = fixed.P(origin.X+(montageCell-width)/2, origin.Y+montageCell+basicfont.Face7x13.Ascent+1)
+		drawer.DrawString(label)
+	}
+
+	return canvas, len(index.Frames), nil
+}
+
+// writeMontages writes one montage per frame folder in place of the frames
+// themselves, as a quick visual index of a large pack.
+func (unpacker Unpacker) writeMontages() error {
+	folders := montageFolders(unpacker.Pack)
+
+	for _, folder := range slices.Sorted(maps.Keys(folders)) {
+		canvas, count, err := unpacker.montage(folders[folder])
+		if err != nil {
+			return err
+		}
+
+		outputPath := filepath.Join(unpacker.OutputDir, filepath.FromSlash(folder)+unpacker.encoder().Extension())
+		if _, err := unpacker.writeSprite(outputPath, canvas); err != nil {
+			return err
+		}
+		logf("[info] wrote %s with %d frames\n", outputPath, count)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// parseMTime reads --mtime as Unix seconds, the form SOURCE_DATE_EPOCH
+// takes, or as an RFC 3339 time.
+func parseMTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	mtime, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid mtime %q, expected Unix seconds or an RFC 3339 time", value)
+	}
+	return mtime, nil
+}
+
+// stampTimes sets the modification time of dir and everything below it, so
+// trees extracted from the same pack match down to their timestamps and
+// archive or cache by content. Symlinks keep their own times, which cannot
+// be set portably.
+func stampTimes(dir string, mtime time.Time) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			return withExitCode(exitOutput, fmt.Errorf("failed to set the time of %s: %w", path, err))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A Rename is a sed-style s/pattern/replacement/flags rewrite of frame names.
+// Replacements use sed syntax: & for the match, \1 through \9 for groups, and
+// \L, \U, \E to lowercase, uppercase, or stop changing the case of what follows.
+type Rename struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+	Global      bool
+}
+
+// parseRename reads s/pattern/replacement/flags, taking whatever follows the s
+// as the delimiter so patterns full of slashes can use s|a/b|c| instead.
+func parseRename(expr string) (Rename, error) {
+	if len(expr) < 2 || expr[0] != 's' {
+		return Rename{}, fmt.Errorf("invalid rename %q, expected s/pattern/replacement/", expr)
+	}
+
+	delim := expr[1:2]
+	parts := splitUnescaped(expr[2:], delim[0])
+	if len(parts) != 3 {
+		return Rename{}, fmt.Errorf("invalid rename %q, expected s/pattern/replacement/", expr)
+	}
+
+	pattern, replacement, flags := parts[0], parts[1], parts[2]
+	pattern = strings.ReplaceAll(pattern, `\`+delim, delim)
+	replacement = strings.ReplaceAll(replacement, `\`+delim, delim)
+
+	rename := Rename{Replacement: replacement}
+	for _, flag := range flags {
+		switch flag {
+		case 'g':
+			rename.Global = true
+		case 'i':
+			pattern = "(?i)" + pattern
+		default:
+			return Rename{}, fmt.Errorf("invalid rename %q, unknown flag %q", expr, flag)
+		}
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Rename{}, fmt.Errorf("invalid rename %q: %w", expr, err)
+	}
+	rename.Pattern = re
+
+	return rename, nil
+}
+
+func parseRenames(exprs []string) ([]Rename, error) {
+	renames := make([]Rename, 0, len(exprs))
+	for _, expr := range exprs {
+		rename, err := parseRename(expr)
+		if err != nil {
+			return nil, err
+		}
+		renames = append(renames, rename)
+	}
+	return renames, nil
+}
+
+// splitUnescaped splits s on every delim not preceded by a backslash.
+func splitUnescaped(s string, delim byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case delim:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// expand builds the replacement for one match.
+func (rename Rename) expand(name string, match []int) string {
+	var out strings.Builder
+	caseFn := func(s string) string { return s }
+
+	write := func(s string) {
+		out.WriteString(caseFn(s))
+	}
+
+	repl := rename.Replacement
+	for i := 0; i < len(repl); i++ {
+		c := repl[i]
+		if c == '&' {
+			write(name[match[0]:match[1]])
+			continue
+		}
+		if c != '\\' || i+1 == len(repl) {
+			write(string(c))
+			continue
+		}
+
+		i++
+		switch next := repl[i]; {
+		case next >= '0' && next <= '9':
+			if group := int(next - '0'); 2*group+1 < len(match) && match[2*group] >= 0 {
+				write(name[match[2*group]:match[2*group+1]])
+			}
+		case next == 'L':
+			caseFn = strings.ToLower
+		case next == 'U':
+			caseFn = strings.ToUpper
+		case next == 'E':
+			caseFn = func(s string) string { return s }
+		default:
+			write(string(next))
+		}
+	}
+
+	return out.String()
+}
+
+func (rename Rename) apply(name string) string {
+	limit := 1
+	if rename.Global {
+		limit = -1
+	}
+
+	matches := rename.Pattern.FindAllStringSubmatchIndex(name, limit)
+	if matches == nil {
+		return name
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, match := range matches {
+		out.WriteString(name[last:match[0]])
+		out.WriteString(rename.expand(name, match))
+		last = match[1]
+	}
+	out.WriteString(name[last:])
+
+	return out.String()
+}
+
+// A NameStrategy maps a frame, already renamed, to the path it is written
+// under inside the output directory, without the .png extension.
+type NameStrategy interface {
+	OutputName(texture Texture, frame string, slot FrameSlot) string
+}
+
+// verbatimNames writes frames under their own names, however odd.
+type verbatimNames struct{}
+
+func (verbatimNames) OutputName(texture Texture, frame string, slot FrameSlot) string {
+	return frame
+}
+
+// safeNames sanitizes whatever another strategy produced, see sanitizeName.
+type safeNames struct {
+	NameStrategy
+}
+
+func (names safeNames) OutputName(texture Texture, frame string, slot FrameSlot) string {
+	name, _ := sanitizeName(names.NameStrategy.OutputName(texture, frame, slot))
+	return name
+}
+
+// hashedNames writes every frame as a short hash of its name, for pipelines
+// that only need stable, collision-free, portable file names.
+type hashedNames struct{}
+
+func (hashedNames) OutputName(texture Texture, frame string, slot FrameSlot) string {
+	sum := sha256.Sum256([]byte(frame))
+	return hex.EncodeToString(sum[:8])
+}
+
+type templatedNames struct {
+	Template NameTemplate
+}
+
+func (names templatedNames) OutputName(texture Texture, frame string, slot FrameSlot) string {
+	name := names.Template.expand(frame, texture, slot)
+	return strings.TrimPrefix(path.Clean(name), "/")
+}
+
+var nameStrategies = []string{"safe", "verbatim", "hashed", "templated"}
+
+func newNameStrategy(name string, template NameTemplate) (NameStrategy, error) {
+	if template != "" && name != "templated" {
+		return nil, fmt.Errorf("--name-template requires --names templated")
+	}
+
+	switch name {
+	case "safe":
+		return safeNames{verbatimNames{}}, nil
+	case "verbatim":
+		return verbatimNames{}, nil
+	case "hashed":
+		return hashedNames{}, nil
+	case "templated":
+		if template == "" {
+			return nil, fmt.Errorf("--names templated requires --name-template")
+		}
+		return safeNames{templatedNames{template}}, nil
+	}
+
+	return nil, fmt.Errorf("invalid names %q, expected %s", name, strings.Join(nameStrategies, ", "))
+}
+
+// outputName is the name a frame is written under, before any export mode
+// lays it out on disk.
+func (unpacker Unpacker) outputName(texture Texture) string {
+	return unpacker.nameFor(texture, texture.FileName)
+}
+
+// nameFor renames frame and lays it out with the naming strategy. The frame
+// may differ from the texture's own name when an export mode strips part of it.
+func (unpacker Unpacker) nameFor(texture Texture, frame string) string {
+	return unpacker.nameWith(unpacker.nameStrategy(), texture, frame)
+}
+
+func (unpacker Unpacker) nameWith(names NameStrategy, texture Texture, frame string) string {
+	name := names.OutputName(texture, unpacker.rename(frame), unpacker.slots[texture.FileName])
+	if unpacker.Flatten != "" {
+		name = strings.ReplaceAll(name, "/", unpacker.Flatten)
+	}
+	return name
+}
+
+func (unpacker Unpacker) nameStrategy() NameStrategy {
+	if unpacker.Names == nil {
+		return safeNames{verbatimNames{}}
+	}
+	return unpacker.Names
+}
+
+func (unpacker Unpacker) rename(name string) string {
+	for _, rename := range unpacker.Renames {
+		name = rename.apply(name)
+	}
+	return name
+}
+
+var nameTokens = regexp.MustCompile(`\{([a-z]+)(?::(\d+))?\}`)
+
+// A NameTemplate lays out output names from tokens: {frame} is the renamed
+// frame, {name} and {dir} its last segment and folder, {sheet} the sheet image
+// without extension, {index} the frame's position in the atlas, zero padded
+// with {index:3}, and {w} and {h} the frame's source size.
+type NameTemplate string
+
+type FrameSlot struct {
+	Sheet string
+	Index int
+}
+
+func parseNameTemplate(template string) (NameTemplate, error) {
+	for _, match := range nameTokens.FindAllStringSubmatch(template, -1) {
+		switch match[1] {
+		case "frame", "name", "dir", "sheet", "w", "h":
+			if match[2] != "" {
+				return "", fmt.Errorf("invalid name template %q, only {index} takes a width", template)
+			}
+		case "index":
+		default:
+			return "", fmt.Errorf("invalid name template %q, unknown token {%s}", template, match[1])
+		}
+	}
+
+	if !nameTokens.MatchString(template) {
+		return "", fmt.Errorf("invalid name template %q, expected at least one token", template)
+	}
+
+	return NameTemplate(template), nil
+}
+
+func FrameSlots(pack Pack) map[string]FrameSlot {
+	slots := make(map[string]FrameSlot)
+	index := 0
+	for _, sh := range pack.Sheets {
+		sheet := strings.TrimSuffix(path.Base(sh.Image), path.Ext(sh.Image))
+		for _, tex := range sh.Textures {
+			slots[tex.FileName] = FrameSlot{Sheet: sheet, Index: index}
+			index++
+		}
+	}
+	return slots
+}
+
+func (template NameTemplate) expand(frame string, texture Texture, slot FrameSlot) string {
+	return nameTokens.ReplaceAllStringFunc(string(template), func(token string) string {
+		match := nameTokens.FindStringSubmatch(token)
+		switch match[1] {
+		case "frame":
+			return frame
+		case "name":
+			return path.Base(frame)
+		case "dir":
+			if dir := path.Dir(frame); dir != "." {
+				return dir
+			}
+			return ""
+		case "sheet":
+			return slot.Sheet
+		case "index":
+			width, _ := strconv.Atoi(match[2])
+			return fmt.Sprintf("%0*d", width, slot.Index)
+		case "w":
+			return strconv.Itoa(texture.SourceSize.Width)
+		case "h":
+			return strconv.Itoa(texture.SourceSize.Height)
+		}
+		return token
+	})
+}
+
+var reservedNames = regexp.MustCompile(`(?i)^(con|prn|aux|nul|com[0-9]|lpt[0-9])$`)
+
+// sanitizeName rewrites each segment of name so it can be created on Windows
+// as well: characters Windows forbids become _, as do trailing dots and
+// spaces, and device names like CON get a trailing _. It reports whether
+// anything changed.
+func sanitizeName(name string) (string, bool) {
+	segments := strings.Split(name, "/")
+	changed := false
+
+	for i, segment := range segments {
+		if segment == "." || segment == ".." {
+			continue
+		}
+
+		clean := []byte(segment)
+		for j, c := range clean {
+			if c < 0x20 || strings.IndexByte(`<>:"|?*\`, c) >= 0 {
+				clean[j] = '_'
+			}
+		}
+		for j := len(clean) - 1; j >= 0 && (clean[j] == '.' || clean[j] == ' '); j-- {
+			clean[j] = '_'
+		}
+
+		base, _, _ := strings.Cut(string(clean), ".")
+		if reservedNames.MatchString(strings.TrimRight(base, " ")) {
+			clean = append([]byte(base+"_"), clean[len(base):]...)
+		}
+
+		if string(clean) != segment {
+			segments[i] = string(clean)
+			changed = true
+		}
+	}
+
+	return strings.Join(segments, "/"), changed
+}
+
+// checkNames fails on the first frame whose name is invalid on Windows, looking
+// past the sanitizing of safe names.
+func (unpacker Unpacker) checkNames() error {
+	names := unpacker.nameStrategy()
+	if safe, ok := names.(safeNames); ok {
+		names = safe.NameStrategy
+	}
+
+	for _, sh := range unpacker.Sheets {
+		for _, tex := range sh.Textures {
+			raw := unpacker.nameWith(names, tex, tex.FileName)
+			if sanitized, changed := sanitizeName(raw); changed {
+				return fmt.Errorf("invalid output name %q for frame %q on Windows, sanitized it would be %q", raw, tex.FileName, sanitized)
+			}
+		}
+	}
+	return nil
+}
+
+// checkPaths fails on the first frame whose output path would escape the
+// output directory, like ../../etc/passwd, before anything is written.
+func (unpacker Unpacker) checkPaths() error {
+	for _, sh := range unpacker.Sheets {
+		for _, tex := range sh.Textures {
+			paths := []string{unpacker.outputPath(tex)}
+			for i := range unpacker.Augment.Variants {
+				paths = append(paths, unpacker.augmentPath(tex, i))
+			}
+
+			for _, outputPath := range paths {
+				if !insideDir(unpacker.OutputDir, outputPath) {
+					return fmt.Errorf("frame %q would be written to %s, outside the output directory %s", tex.FileName, outputPath, unpacker.OutputDir)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var nineSliceModes = []string{"none", "json", "split"}
+
+// NineSlice holds a frame's border widths around its stretchable center, in
+// pixels of the untrimmed frame.
+type NineSlice struct {
+	Left   int `json:"left"`
+	Top    int `json:"top"`
+	Right  int `json:"right"`
+	Bottom int `json:"bottom"`
+}
+
+// nineSlice converts TexturePacker's scale9Borders, the center rect, into
+// border widths. Borders that do not fit the frame are ignored.
+func (tex Texture) nineSlice() (NineSlice, bool) {
+	center := tex.Scale9Borders
+	if center == nil || center.X < 0 || center.Y < 0 || center.Width < 0 || center.Height < 0 {
+		return NineSlice{}, false
+	}
+
+	slice := NineSlice{
+		Left:   center.X,
+		Top:    center.Y,
+		Right:  tex.SourceSize.Width - center.X - center.Width,
+		Bottom: tex.SourceSize.Height - center.Y - center.Height,
+	}
+	if slice.Right < 0 || slice.Bottom < 0 {
+		return NineSlice{}, false
+	}
+	return slice, true
+}
+
+var ninePatchNames = [3][3]string{{"tl", "t", "tr"}, {"l", "c", "r"}, {"bl", "b", "br"}}
+
+// ninePatches cuts sprite along the slice's borders, scaled to the sprite's
+// size when it was resized on extraction. Empty patches are left out.
+func ninePatches(sprite image.Image, slice NineSlice, sourceSize Size) map[string]image.Image {
+	bounds := sprite.Bounds()
+	scaleX := float64(bounds.Dx()) / float64(max(1, sourceSize.Width))
+	scaleY := float64(bounds.Dy()) / float64(max(1, sourceSize.Height))
+
+	xs := [4]int{
+		bounds.Min.X,
+		bounds.Min.X + int(math.Round(float64(slice.Left)*scaleX)),
+		bounds.Max.X - int(math.Round(float64(slice.Right)*scaleX)),
+		bounds.Max.X,
+	}
+	ys := [4]int{
+		bounds.Min.Y,
+		bounds.Min.Y + int(math.Round(float64(slice.Top)*scaleY)),
+		bounds.Max.Y - int(math.Round(float64(slice.Bottom)*scaleY)),
+		bounds.Max.Y,
+	}
+
+	patches := make(map[string]image.Image)
+	for row := range 3 {
+		for col := range 3 {
+			rect := image.Rect(xs[col], ys[row], xs[col+1], ys[row+1])
+			if rect.Empty() {
+				continue
+			}
+			patches[ninePatchNames[row][col]] = sprite.(interface {
+				SubImage(image.Rectangle) image.Image
+			}).SubImage(rect)
+		}
+	}
+	return patches
+}
+
+// writeFrame writes a sprite, or with --nine-slice split its nine patches
+// in its place when the frame has borders.
+func (unpacker Unpacker) writeFrame(texture Texture, outputPath string, sprite image.Image) (int64, error) {
+	slice, ok := texture.nineSlice()
+	if unpacker.NineSlice != "split" || !ok {
+		return unpacker.writeChannels(outputPath, sprite)
+	}
+
+	var written int64
+	ext := filepath.Ext(outputPath)
+	for name, patch := range ninePatches(sprite, slice, texture.SourceSize) {
+		patchPath := fmt.Sprintf("%s_%s%s", strings.TrimSuffix(outputPath, ext), name, ext)
+		n, err := unpacker.writeChannels(patchPath, patch)
+		if err != nil {
+			return 0, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+func writeNineSlices(pack Pack, path string) error {
+	borders := make(map[string]NineSlice)
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			if slice, ok := tex.nineSlice(); ok {
+				borders[tex.FileName] = slice
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(borders, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode nine-slice borders: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write nine-slice borders: %w", err)
+	}
+	return nil
+}
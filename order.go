@@ -0,0 +1,68 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var frameOrders = []string{"sheet", "name", "size-desc"}
+
+func checkFrameOrder(order string) error {
+	if !slices.Contains(frameOrders, order) {
+		return fmt.Errorf("invalid order %q, expected %s", order, strings.Join(frameOrders, ", "))
+	}
+	return nil
+}
+
+func frameArea(tex Texture) int {
+	return tex.SourceSize.Width * tex.SourceSize.Height
+}
+
+func sheetArea(sh Sheet) int {
+	area := 0
+	for _, tex := range sh.Textures {
+		area += frameArea(tex)
+	}
+	return area
+}
+
+// orderPack sorts sheets and the frames within them, which sets the order
+// they are extracted and their progress bars listed. Sheet order keeps the
+// atlas as written. Sorts are stable with names breaking ties, so runs repeat.
+func orderPack(pack Pack, order string) Pack {
+	if order == "sheet" {
+		return pack
+	}
+
+	sheets := slices.Clone(pack.Sheets)
+	for i := range sheets {
+		textures := slices.Clone(sheets[i].Textures)
+		switch order {
+		case "name":
+			slices.SortStableFunc(textures, func(a, b Texture) int {
+				return strings.Compare(a.FileName, b.FileName)
+			})
+		case "size-desc":
+			slices.SortStableFunc(textures, func(a, b Texture) int {
+				return cmp.Or(cmp.Compare(frameArea(b), frameArea(a)), strings.Compare(a.FileName, b.FileName))
+			})
+		}
+		sheets[i].Textures = textures
+	}
+
+	switch order {
+	case "name":
+		slices.SortStableFunc(sheets, func(a, b Sheet) int {
+			return strings.Compare(a.Image, b.Image)
+		})
+	case "size-desc":
+		slices.SortStableFunc(sheets, func(a, b Sheet) int {
+			return cmp.Or(cmp.Compare(sheetArea(b), sheetArea(a)), strings.Compare(a.Image, b.Image))
+		})
+	}
+
+	pack.Sheets = sheets
+	return pack
+}
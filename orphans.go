@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type OrphanReport struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Atlas  string `json:"atlas,omitempty"`
+}
+
+var atlasExtensions = []string{".json", ".xml", ".plist", ".atlas", ".txt", ".meta"}
+
+// pageStem drops the extension and any page number, so atlas-0.png and
+// atlas-3.png share the stem atlas.
+func pageStem(path string) string {
+	stem := strings.TrimSuffix(path, filepath.Ext(path))
+	stem = strings.TrimRight(stem, "0123456789")
+	return strings.TrimRight(stem, "-_.")
+}
+
+// findOrphans loads every atlas below dir and reports images no atlas uses,
+// and pages or related packs an atlas names that are not on disk. An
+// unreferenced image named like a page of a known atlas is reported as a
+// page whose atlas is missing.
+func findOrphans(dir string) ([]OrphanReport, int, error) {
+	var reports []OrphanReport
+	var images []string
+	referenced := make(map[string]bool)
+	atlases := 0
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(path))
+		if slices.Contains(imageExtensions, ext) {
+			images = append(images, path)
+			return nil
+		}
+		if !slices.Contains(atlasExtensions, ext) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		format, err := detectAtlasFormat(data, path)
+		if err != nil {
+			return nil
+		}
+
+		pack, err := loadPack(path, format.Name)
+		if err != nil {
+			reports = append(reports, OrphanReport{Path: path, Status: "invalid"})
+			return nil
+		}
+		atlases++
+
+		for _, sh := range pack.Sheets {
+			page := filepath.Join(filepath.Dir(path), filepath.FromSlash(sh.Image))
+			referenced[page] = true
+			if _, err := os.Stat(page); err != nil {
+				reports = append(reports, OrphanReport{Path: page, Status: "missing-page", Atlas: path})
+			}
+		}
+		for _, related := range pack.Related {
+			relatedPath := filepath.Join(filepath.Dir(path), filepath.FromSlash(related))
+			if _, err := os.Stat(relatedPath); err != nil {
+				reports = append(reports, OrphanReport{Path: relatedPath, Status: "missing-pack", Atlas: path})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to walk %s: %w", dir, err)
+	}
+
+	stems := make(map[string]bool)
+	for page := range referenced {
+		stems[pageStem(page)] = true
+	}
+
+	for _, image := range images {
+		switch {
+		case referenced[image]:
+		case stems[pageStem(image)]:
+			reports = append(reports, OrphanReport{Path: image, Status: "orphan-page"})
+		default:
+			reports = append(reports, OrphanReport{Path: image, Status: "unreferenced"})
+		}
+	}
+
+	slices.SortFunc(reports, func(a, b OrphanReport) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return reports, atlases, nil
+}
+
+func newOrphansCmd() *cobra.Command {
+	var asJSON bool = false
+
+	var orphansCmd = &cobra.Command{
+		Use:   "orphans <assets-dir>",
+		Short: "Find images no atlas uses and atlas pages or packs missing from disk",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reports, atlases, err := findOrphans(args[0])
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(reports)
+			}
+
+			counts := make(map[string]int)
+			for _, report := range reports {
+				counts[report.Status]++
+				if report.Atlas != "" {
+					fmt.Printf("%-12s  %s (from %s)\n", report.Status, report.Path, report.Atlas)
+				} else {
+					fmt.Printf("%-12s  %s\n", report.Status, report.Path)
+				}
+			}
+
+			var summary []string
+			for _, status := range slices.Sorted(maps.Keys(counts)) {
+				summary = append(summary, fmt.Sprintf("%d %s", counts[status], status))
+			}
+			if len(summary) == 0 {
+				summary = append(summary, "nothing orphaned")
+			}
+			logf("[info] scanned %d atlases: %s\n", atlases, strings.Join(summary, ", "))
+
+			return nil
+		},
+	}
+
+	orphansCmd.Flags().BoolVarP(&asJSON, "json", "", asJSON, "Print findings as JSON")
+
+	return orphansCmd
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runMarkerFile is left in every output directory a run writes to, whatever
+// it exports, so rerunning into it is never mistaken for clobbering a
+// directory of someone else's files.
+const runMarkerFile = ".txunpak-run.json"
+
+type runMarker struct {
+	Atlas string `json:"atlas"`
+}
+
+// markRun records that a run of atlasPath writes to dir.
+func markRun(dir, atlasPath string) error {
+	data, err := json.Marshal(runMarker{Atlas: filepath.ToSlash(atlasPath)})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, runMarkerFile), data, 0o644); err != nil {
+		return withExitCode(exitOutput, fmt.Errorf(tr("failed to write output file: %w"), err))
+	}
+	return nil
+}
+
+// producedByRun reports whether dir holds the run marker, or the manifest,
+// tile manifest, or resume log an earlier run leaves behind.
+func producedByRun(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, runMarkerFile)); err == nil {
+		return true
+	}
+	if manifest, err := readManifest(dir); err == nil && manifest != nil && manifest.Frames != nil {
+		return true
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "tiles.json")); err == nil {
+		var tiles UpscaleManifest
+		if json.Unmarshal(data, &tiles) == nil && tiles.TileSize > 0 {
+			return true
+		}
+	}
+	_, err := os.Stat(filepath.Join(dir, resumeStateFile))
+	return err == nil
+}
+
+// checkOverwrite refuses to write into a non-empty output directory no
+// earlier run produced unless Force is set, since whatever is there, like
+// hand-edited sprites, would be overwritten.
+func (unpacker Unpacker) checkOverwrite() error {
+	if unpacker.Force {
+		return nil
+	}
+	entries, err := os.ReadDir(unpacker.OutputDir)
+	if err != nil || len(entries) == 0 || producedByRun(unpacker.OutputDir) {
+		return nil
+	}
+	return fmt.Errorf(tr("output directory %s is not empty and was not written by an earlier run, pass --force to write into it anyway"), unpacker.OutputDir)
+}
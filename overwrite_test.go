@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRerunIntoOwnOutput extracts the multi-page fixture twice into the same
+// directory in every export mode, which must not need --force.
+func TestRerunIntoOwnOutput(t *testing.T) {
+	fixtures, err := fs.Sub(selftestFixtures, "selftest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputDir := t.TempDir()
+	if err := os.CopyFS(inputDir, fixtures); err != nil {
+		t.Fatal(err)
+	}
+	atlasPath := filepath.Join(inputDir, "multiatlas.json")
+	pack, err := loadPack(atlasPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoder, err := newSpriteEncoder(SpriteEncoding{Format: "png", PNGCompression: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(level int) { verbosity = level }(verbosity)
+	verbosity = verbosityQuiet
+
+	modes := map[string]func(*Unpacker){
+		"sprites":     func(unpacker *Unpacker) { unpacker.Manifest = true },
+		"no-manifest": func(unpacker *Unpacker) { unpacker.Manifest = false },
+		"dataset":     func(unpacker *Unpacker) { unpacker.Export = "dataset" },
+		"locales": func(unpacker *Unpacker) {
+			unpacker.Export = "locales"
+			unpacker.Locales = newLocaleIndex(pack, nil)
+		},
+		"montage": func(unpacker *Unpacker) { unpacker.Export = "montage" },
+		"tiles":   func(unpacker *Unpacker) { unpacker.TileSize = 16 },
+	}
+	for name, mode := range modes {
+		t.Run(name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			for run := range 2 {
+				unpacker := Unpacker{
+					Pack:      pack,
+					AtlasPath: atlasPath,
+					InputDir:  inputDir,
+					OutputDir: outputDir,
+					Workers:   2,
+					Encoder:   encoder,
+					Summary:   "none",
+				}
+				mode(&unpacker)
+				if err := unpacker.unpack(true); err != nil {
+					t.Fatalf("run %d: %v", run+1, err)
+				}
+			}
+		})
+	}
+}
+
+// TestRefuseForeignOutput checks that a directory no run wrote is still
+// refused without --force.
+func TestRefuseForeignOutput(t *testing.T) {
+	outputDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outputDir, "edited.png"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (Unpacker{OutputDir: outputDir}).checkOverwrite(); err == nil {
+		t.Fatal("expected a directory no run wrote to be refused")
+	}
+}
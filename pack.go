@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func decodeImageFile(path string) (image.Image, error) {
+	imageFile, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer imageFile.Close()
+
+	img, err := decodeImage(imageFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image %s: %w", path, err)
+	}
+
+	return img, nil
+}
+
+// opaqueBounds returns the smallest rect holding every non-transparent pixel,
+// keeping a single pixel for fully transparent images.
+func opaqueBounds(img image.Image) image.Rectangle {
+	bounds := img.Bounds()
+	opaque := image.Rectangle{}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0 {
+				opaque = opaque.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	if opaque.Empty() {
+		return image.Rectangle{bounds.Min, bounds.Min.Add(image.Point{1, 1})}
+	}
+	return opaque
+}
+
+func collectPackSprites(dir string, trim bool) ([]PackSprite, error) {
+	var sprites []PackSprite
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || frameName(entry.Name()) == entry.Name() {
+			return nil
+		}
+
+		var source, bounds image.Rectangle
+		if trim {
+			img, err := decodeImageFile(path)
+			if err != nil {
+				return err
+			}
+			source = img.Bounds().Sub(img.Bounds().Min)
+			bounds = opaqueBounds(img).Sub(img.Bounds().Min)
+		} else {
+			spriteFile, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to open sprite: %w", err)
+			}
+			defer spriteFile.Close()
+
+			config, _, err := image.DecodeConfig(spriteFile)
+			if err != nil {
+				return fmt.Errorf("failed to decode sprite %s: %w", path, err)
+			}
+			source = image.Rect(0, 0, config.Width, config.Height)
+			bounds = source
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		sprite := PackSprite{
+			Name:       frameName(filepath.ToSlash(relPath)),
+			Path:       path,
+			Width:      bounds.Dx(),
+			Height:     bounds.Dy(),
+			Bounds:     bounds,
+			SourceSize: Size{Width: source.Dx(), Height: source.Dy()},
+		}
+
+		sprites = append(sprites, sprite)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sprites) == 0 {
+		return nil, fmt.Errorf("no sprites found in %s", dir)
+	}
+
+	return sprites, nil
+}
+
+func printPackScores(layouts []PackLayout) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ALGORITHM\tPAGES\tDIMENSIONS\tEFFICIENCY")
+
+	for _, layout := range layouts {
+		dimensions := make([]string, 0, len(layout.Pages))
+		for _, page := range layout.Pages {
+			dimensions = append(dimensions, fmt.Sprintf("%dx%d", page.Width, page.Height))
+		}
+
+		fmt.Fprintf(writer, "%s\t%d\t%s\t%.1f%%\n", layout.Algorithm, len(layout.Pages), strings.Join(dimensions, ", "), layout.Efficiency()*100)
+	}
+
+	writer.Flush()
+}
+
+// pack describes the layout as a Phaser multi-atlas, one sheet per page.
+func (layout PackLayout) pack(imageNames []string) Pack {
+	pack := Pack{Meta: map[string]string{"app": "https://github.com/evaneliasyoung/phaser-unpacker"}}
+
+	for i, page := range layout.Pages {
+		sheet := Sheet{
+			Format:   "RGBA8888",
+			Image:    imageNames[i],
+			Scale:    1,
+			Size:     Size{Width: page.Width, Height: page.Height},
+			Textures: make([]Texture, 0, len(page.Sprites)),
+		}
+
+		for _, sprite := range page.Sprites {
+			sheet.Textures = append(sheet.Textures, Texture{
+				FileName:   sprite.Name,
+				Frame:      Frame{Width: sprite.Width, Height: sprite.Height, X: sprite.X, Y: sprite.Y},
+				SourceSize: sprite.SourceSize,
+				SpriteSourceSize: Frame{
+					Width:  sprite.Width,
+					Height: sprite.Height,
+					X:      sprite.Bounds.Min.X,
+					Y:      sprite.Bounds.Min.Y,
+				},
+				Trimmed: sprite.Bounds != sprite.SourceSize.Rect(),
+			})
+		}
+
+		slices.SortFunc(sheet.Textures, func(a, b Texture) int {
+			return strings.Compare(a.FileName, b.FileName)
+		})
+		pack.Sheets = append(pack.Sheets, sheet)
+	}
+
+	return pack
+}
+
+func renderPackPage(page PackPage) (*image.NRGBA, error) {
+	canvas := image.NewNRGBA(image.Rect(0, 0, page.Width, page.Height))
+
+	for _, sprite := range page.Sprites {
+		img, err := decodeImageFile(sprite.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		target := image.Rect(sprite.X, sprite.Y, sprite.X+sprite.Width, sprite.Y+sprite.Height)
+		draw.Draw(canvas, target, img, img.Bounds().Min.Add(sprite.Bounds.Min), draw.Src)
+	}
+
+	return canvas, nil
+}
+
+// writePackedAtlas writes every page next to outputPath, named after it with
+// a page suffix when there is more than one, followed by the atlas JSON. It
+// returns the paths of the written pages.
+func writePackedAtlas(layout PackLayout, outputPath string) ([]string, error) {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	base := strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+
+	imagePaths := make([]string, len(layout.Pages))
+	imageNames := make([]string, len(layout.Pages))
+	for i, page := range layout.Pages {
+		imagePath := base + ".png"
+		if len(layout.Pages) > 1 {
+			imagePath = fmt.Sprintf("%s-%d.png", base, i)
+		}
+		imagePaths[i] = imagePath
+		imageNames[i] = filepath.Base(imagePath)
+
+		canvas, err := renderPackPage(page)
+		if err != nil {
+			return nil, err
+		}
+		if err := writePNG(imagePath, canvas); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.MarshalIndent(layout.pack(imageNames), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode atlas: %w", err)
+	}
+
+	if err := os.WriteFile(outputPath, data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write atlas: %w", err)
+	}
+
+	return imagePaths, nil
+}
+
+func newPackCmd() *cobra.Command {
+	var outputPath string
+	var maxSize string = "2048x2048"
+	var padding int = 2
+	var algorithmName string = packAlgorithms[0].Name
+	var trim bool = false
+	var dryRun bool = false
+	var score bool = false
+	var groupBy string = "none"
+	var constraintsPath string
+	var pageFormats []string = []string{"png"}
+
+	var packCmd = &cobra.Command{
+		Use:   "pack <dir>",
+		Short: "Pack a directory of sprites into an atlas",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var dir = args[0]
+
+			pageSize, err := parseSize(maxSize)
+			if err != nil {
+				return err
+			}
+
+			if padding < 0 {
+				return fmt.Errorf("padding must not be negative")
+			}
+
+			encoders, err := findPageEncoders(pageFormats)
+			if err != nil {
+				return err
+			}
+
+			sprites, err := collectPackSprites(dir, trim)
+			if err != nil {
+				return err
+			}
+
+			algorithm, err := findPackAlgorithm(algorithmName)
+			if err != nil {
+				return err
+			}
+
+			options := PackOptions{MaxSize: pageSize, Padding: padding, GroupBy: groupBy}
+			if constraintsPath != "" {
+				constraints, err := loadPackConstraints(constraintsPath)
+				if err != nil {
+					return err
+				}
+				if sprites, err = constraints.apply(sprites); err != nil {
+					return err
+				}
+				options.Reserved = constraints.Reserved
+			}
+
+			layout, err := packSprites(sprites, algorithm, options)
+			if err != nil {
+				return err
+			}
+
+			layouts := []PackLayout{layout}
+			if score {
+				layouts = nil
+				for _, algorithm := range packAlgorithms {
+					layout, err := packSprites(sprites, algorithm, options)
+					if err != nil {
+						logf("[warn] skipping %s: %v\n", algorithm.Name, err)
+						continue
+					}
+					layouts = append(layouts, layout)
+				}
+			}
+
+			if dryRun {
+				logf("[info] packed %d sprites without writing files\n", len(sprites))
+				printPackScores(layouts)
+				return nil
+			}
+
+			if score {
+				printPackScores(layouts)
+			}
+
+			if outputPath == "" {
+				outputPath = filepath.Clean(dir) + ".json"
+			}
+
+			imagePaths, err := writePackedAtlas(layout, outputPath)
+			if err != nil {
+				return err
+			}
+
+			logf("[info] packed %d sprites onto %d pages at %.1f%% efficiency\n", len(sprites), len(layout.Pages), layout.Efficiency()*100)
+			logf("[info] wrote %s\n", outputPath)
+
+			for _, encoder := range encoders {
+				for _, imagePath := range imagePaths {
+					encodedPath, err := encoder.encode(cmd.Context(), imagePath)
+					if err != nil {
+						return err
+					}
+					logf("[info] wrote %s\n", encodedPath)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	packCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output atlas JSON, pages are written beside it")
+	packCmd.Flags().StringVarP(&maxSize, "max-size", "", maxSize, "Maximum page size as WxH")
+	packCmd.Flags().IntVarP(&padding, "padding", "p", padding, "Pixels between packed sprites")
+	packCmd.Flags().StringVarP(&algorithmName, "algorithm", "a", algorithmName, "Packing heuristic: maxrects-bssf, maxrects-bl, skyline, guillotine, or shelf")
+	packCmd.Flags().StringVarP(&groupBy, "group-by", "", groupBy, "Keep related frames on one page: none or prefix")
+	packCmd.Flags().StringVarP(&constraintsPath, "constraints", "", "", "YAML file of page pins, forced padding, and reserved regions")
+	packCmd.Flags().StringSliceVarP(&pageFormats, "page-format", "", pageFormats, "Page formats to write besides png: ktx2 (basisu) or etc2 (etcpak)")
+	packCmd.Flags().BoolVarP(&trim, "trim", "", trim, "Trim transparent borders before packing")
+	packCmd.Flags().BoolVarP(&dryRun, "dry-run", "", dryRun, "Pack without writing files")
+	packCmd.Flags().BoolVarP(&score, "score", "", score, "Compare every packing heuristic side-by-side")
+
+	return packCmd
+}
@@ -0,0 +1,630 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+)
+
+type Bin interface {
+	Insert(width, height int) (image.Point, bool)
+}
+
+// A Reserver is a Bin that can keep a fixed region of the page free.
+type Reserver interface {
+	Reserve(region image.Rectangle)
+}
+
+type PackAlgorithm struct {
+	Name string
+	New  func(width, height int) Bin
+}
+
+var packAlgorithms = []PackAlgorithm{
+	{Name: "maxrects-bssf", New: newMaxRectsBin(bestShortSideFit)},
+	{Name: "maxrects-bl", New: newMaxRectsBin(bottomLeft)},
+	{Name: "skyline", New: newSkylineBin},
+	{Name: "guillotine", New: newGuillotineBin},
+	{Name: "shelf", New: newShelfBin},
+}
+
+func findPackAlgorithm(name string) (PackAlgorithm, error) {
+	names := make([]string, 0, len(packAlgorithms))
+	for _, algorithm := range packAlgorithms {
+		if algorithm.Name == name {
+			return algorithm, nil
+		}
+		names = append(names, algorithm.Name)
+	}
+
+	return PackAlgorithm{}, fmt.Errorf("unknown packing algorithm %q, expected one of: %s", name, strings.Join(names, ", "))
+}
+
+// A PackSprite is packed at Width x Height, the size of Bounds within its
+// SourceSize image once trimmed. Page pins it to a page, and a positive
+// Padding forces that much clearance on every side.
+type PackSprite struct {
+	Name       string
+	Path       string
+	Width      int
+	Height     int
+	Bounds     image.Rectangle
+	SourceSize Size
+	Page       *int
+	Padding    int
+}
+
+type PackOptions struct {
+	MaxSize  Size
+	Padding  int
+	GroupBy  string
+	Reserved []ReservedRegion
+}
+
+type PackedSprite struct {
+	PackSprite
+	X int
+	Y int
+}
+
+type PackPage struct {
+	Width   int
+	Height  int
+	Sprites []PackedSprite
+}
+
+type PackLayout struct {
+	Algorithm string
+	Pages     []PackPage
+}
+
+func (layout PackLayout) Efficiency() float64 {
+	used, total := 0, 0
+	for _, page := range layout.Pages {
+		total += page.Width * page.Height
+		for _, sprite := range page.Sprites {
+			used += sprite.Width * sprite.Height
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return float64(used) / float64(total)
+}
+
+type shelfBin struct {
+	width  int
+	height int
+	x      int
+	y      int
+	shelf  int
+}
+
+func newShelfBin(width, height int) Bin {
+	return &shelfBin{width: width, height: height}
+}
+
+// Insert leaves the bin untouched when the rect does not fit, so replaying
+// the rects that did fit rebuilds the same bin.
+func (bin *shelfBin) Insert(width, height int) (image.Point, bool) {
+	x, y, shelf := bin.x, bin.y, bin.shelf
+	if x+width > bin.width {
+		x, y, shelf = 0, y+shelf, 0
+	}
+
+	if width > bin.width || y+height > bin.height {
+		return image.Point{}, false
+	}
+
+	bin.x, bin.y, bin.shelf = x+width, y, max(shelf, height)
+	return image.Point{x, y}, true
+}
+
+// A maxRectsScore ranks a candidate free rect, lower is better.
+type maxRectsScore func(free image.Rectangle, width, height int) (int, int)
+
+func bestShortSideFit(free image.Rectangle, width, height int) (int, int) {
+	leftoverX, leftoverY := free.Dx()-width, free.Dy()-height
+	return min(leftoverX, leftoverY), max(leftoverX, leftoverY)
+}
+
+func bottomLeft(free image.Rectangle, width, height int) (int, int) {
+	return free.Min.Y + height, free.Min.X
+}
+
+type maxRectsBin struct {
+	score maxRectsScore
+	free  []image.Rectangle
+}
+
+func newMaxRectsBin(score maxRectsScore) func(width, height int) Bin {
+	return func(width, height int) Bin {
+		return &maxRectsBin{score: score, free: []image.Rectangle{image.Rect(0, 0, width, height)}}
+	}
+}
+
+func (bin *maxRectsBin) Insert(width, height int) (image.Point, bool) {
+	best := -1
+	bestPrimary, bestSecondary := 0, 0
+
+	for i, free := range bin.free {
+		if free.Dx() < width || free.Dy() < height {
+			continue
+		}
+
+		primary, secondary := bin.score(free, width, height)
+		if best < 0 || primary < bestPrimary || (primary == bestPrimary && secondary < bestSecondary) {
+			best, bestPrimary, bestSecondary = i, primary, secondary
+		}
+	}
+
+	if best < 0 {
+		return image.Point{}, false
+	}
+
+	placed := image.Rectangle{bin.free[best].Min, bin.free[best].Min.Add(image.Point{width, height})}
+	bin.split(placed)
+
+	return placed.Min, true
+}
+
+func (bin *maxRectsBin) Reserve(region image.Rectangle) {
+	bin.split(region)
+}
+
+func (bin *maxRectsBin) split(placed image.Rectangle) {
+	var next []image.Rectangle
+
+	for _, free := range bin.free {
+		if !free.Overlaps(placed) {
+			next = append(next, free)
+			continue
+		}
+
+		if placed.Min.X > free.Min.X {
+			next = append(next, image.Rect(free.Min.X, free.Min.Y, placed.Min.X, free.Max.Y))
+		}
+		if placed.Max.X < free.Max.X {
+			next = append(next, image.Rect(placed.Max.X, free.Min.Y, free.Max.X, free.Max.Y))
+		}
+		if placed.Min.Y > free.Min.Y {
+			next = append(next, image.Rect(free.Min.X, free.Min.Y, free.Max.X, placed.Min.Y))
+		}
+		if placed.Max.Y < free.Max.Y {
+			next = append(next, image.Rect(free.Min.X, placed.Max.Y, free.Max.X, free.Max.Y))
+		}
+	}
+
+	// Drop free rects fully contained in another to keep the list small.
+	bin.free = bin.free[:0]
+	for i, a := range next {
+		contained := false
+		for j, b := range next {
+			if i != j && a.In(b) && (a != b || i > j) {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			bin.free = append(bin.free, a)
+		}
+	}
+}
+
+type skylineNode struct {
+	x     int
+	y     int
+	width int
+}
+
+type skylineBin struct {
+	width  int
+	height int
+	nodes  []skylineNode
+}
+
+func newSkylineBin(width, height int) Bin {
+	return &skylineBin{width: width, height: height, nodes: []skylineNode{{0, 0, width}}}
+}
+
+// fits returns the lowest y at which a rect starting at node i rests on the skyline.
+func (bin *skylineBin) fits(i, width, height int) (int, bool) {
+	if bin.nodes[i].x+width > bin.width {
+		return 0, false
+	}
+
+	y := 0
+	for remaining := width; remaining > 0; i++ {
+		if i >= len(bin.nodes) {
+			return 0, false
+		}
+		y = max(y, bin.nodes[i].y)
+		if y+height > bin.height {
+			return 0, false
+		}
+		remaining -= bin.nodes[i].width
+	}
+
+	return y, true
+}
+
+func (bin *skylineBin) Insert(width, height int) (image.Point, bool) {
+	best, bestY := -1, 0
+
+	for i := range bin.nodes {
+		y, ok := bin.fits(i, width, height)
+		if ok && (best < 0 || y < bestY) {
+			best, bestY = i, y
+		}
+	}
+
+	if best < 0 {
+		return image.Point{}, false
+	}
+
+	at := image.Point{bin.nodes[best].x, bestY}
+	bin.nodes = slices.Insert(bin.nodes, best, skylineNode{at.X, bestY + height, width})
+
+	for i := best + 1; i < len(bin.nodes); {
+		prevEnd := bin.nodes[i-1].x + bin.nodes[i-1].width
+		if bin.nodes[i].x >= prevEnd {
+			break
+		}
+
+		shrink := prevEnd - bin.nodes[i].x
+		bin.nodes[i].x += shrink
+		bin.nodes[i].width -= shrink
+		if bin.nodes[i].width > 0 {
+			break
+		}
+		bin.nodes = slices.Delete(bin.nodes, i, i+1)
+	}
+
+	for i := 0; i+1 < len(bin.nodes); {
+		if bin.nodes[i].y == bin.nodes[i+1].y {
+			bin.nodes[i].width += bin.nodes[i+1].width
+			bin.nodes = slices.Delete(bin.nodes, i+1, i+2)
+			continue
+		}
+		i++
+	}
+
+	return at, true
+}
+
+type guillotineBin struct {
+	free []image.Rectangle
+}
+
+func newGuillotineBin(width, height int) Bin {
+	return &guillotineBin{free: []image.Rectangle{image.Rect(0, 0, width, height)}}
+}
+
+// Insert picks the best area fit and splits along the shorter leftover axis.
+func (bin *guillotineBin) Insert(width, height int) (image.Point, bool) {
+	best, bestArea := -1, 0
+
+	for i, free := range bin.free {
+		if free.Dx() < width || free.Dy() < height {
+			continue
+		}
+
+		area := free.Dx()*free.Dy() - width*height
+		if best < 0 || area < bestArea {
+			best, bestArea = i, area
+		}
+	}
+
+	if best < 0 {
+		return image.Point{}, false
+	}
+
+	free := bin.free[best]
+	bin.free = slices.Delete(bin.free, best, best+1)
+
+	var right, bottom image.Rectangle
+	if free.Dx()-width <= free.Dy()-height {
+		right = image.Rect(free.Min.X+width, free.Min.Y, free.Max.X, free.Min.Y+height)
+		bottom = image.Rect(free.Min.X, free.Min.Y+height, free.Max.X, free.Max.Y)
+	} else {
+		right = image.Rect(free.Min.X+width, free.Min.Y, free.Max.X, free.Max.Y)
+		bottom = image.Rect(free.Min.X, free.Min.Y+height, free.Min.X+width, free.Max.Y)
+	}
+
+	for _, split := range []image.Rectangle{right, bottom} {
+		if !split.Empty() {
+			bin.free = append(bin.free, split)
+		}
+	}
+
+	return free.Min, true
+}
+
+// Reserve cuts region out of the free rects, keeping them disjoint.
+func (bin *guillotineBin) Reserve(region image.Rectangle) {
+	var next []image.Rectangle
+
+	for _, free := range bin.free {
+		cut := free.Intersect(region)
+		if cut.Empty() {
+			next = append(next, free)
+			continue
+		}
+
+		for _, split := range []image.Rectangle{
+			image.Rect(free.Min.X, free.Min.Y, cut.Min.X, free.Max.Y),
+			image.Rect(cut.Max.X, free.Min.Y, free.Max.X, free.Max.Y),
+			image.Rect(cut.Min.X, free.Min.Y, cut.Max.X, cut.Min.Y),
+			image.Rect(cut.Min.X, cut.Max.Y, cut.Max.X, free.Max.Y),
+		} {
+			if !split.Empty() {
+				next = append(next, split)
+			}
+		}
+	}
+
+	bin.free = next
+}
+
+var packGroupModes = []string{"none", "prefix"}
+
+// sortPackSprites orders sprites largest first, which packs far tighter.
+func sortPackSprites(sprites []PackSprite) []PackSprite {
+	ordered := slices.Clone(sprites)
+	slices.SortStableFunc(ordered, func(a, b PackSprite) int {
+		return cmp.Or(
+			cmp.Compare(max(b.Width, b.Height), max(a.Width, a.Height)),
+			cmp.Compare(b.Width*b.Height, a.Width*a.Height),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+	return ordered
+}
+
+// groupPackSprites splits sprites into groups that should share a page,
+// largest groups first. Without grouping every sprite is its own group.
+func groupPackSprites(sprites []PackSprite, groupBy string) ([][]PackSprite, error) {
+	ordered := sortPackSprites(sprites)
+
+	switch groupBy {
+	case "", "none":
+		groups := make([][]PackSprite, 0, len(ordered))
+		for _, sprite := range ordered {
+			groups = append(groups, []PackSprite{sprite})
+		}
+		return groups, nil
+	case "prefix":
+	default:
+		return nil, fmt.Errorf("invalid group mode %q, expected one of: %s", groupBy, strings.Join(packGroupModes, ", "))
+	}
+
+	var keys []string
+	byKey := make(map[string][]PackSprite)
+	for _, sprite := range ordered {
+		key := animationName(sprite.Name)
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], sprite)
+	}
+
+	area := func(group []PackSprite) int {
+		total := 0
+		for _, sprite := range group {
+			total += sprite.Width * sprite.Height
+		}
+		return total
+	}
+
+	groups := make([][]PackSprite, 0, len(keys))
+	for _, key := range keys {
+		groups = append(groups, byKey[key])
+	}
+	slices.SortStableFunc(groups, func(a, b []PackSprite) int {
+		return cmp.Compare(area(b), area(a))
+	})
+
+	return groups, nil
+}
+
+type pagePacker struct {
+	algorithm PackAlgorithm
+	width     int
+	height    int
+	padding   int
+	reserved  []ReservedRegion
+	layout    PackLayout
+	bins      []Bin
+	inserted  [][]image.Point
+}
+
+// size is the rect a sprite claims in a bin, and offset where the sprite sits
+// inside it. Forced padding surrounds the sprite, the default only trails it.
+func (packer *pagePacker) size(sprite PackSprite) (image.Point, image.Point) {
+	if sprite.Padding > 0 {
+		return image.Point{sprite.Width + 2*sprite.Padding, sprite.Height + 2*sprite.Padding}, image.Point{sprite.Padding, sprite.Padding}
+	}
+	return image.Point{sprite.Width + packer.padding, sprite.Height + packer.padding}, image.Point{}
+}
+
+func (packer *pagePacker) newBin(page int) Bin {
+	bin := packer.algorithm.New(packer.width, packer.height)
+	for _, region := range packer.reserved {
+		if region.Page == page {
+			bin.(Reserver).Reserve(region.Rect())
+		}
+	}
+	return bin
+}
+
+// openPages opens pages up to count, sizing each to cover its reserved regions.
+func (packer *pagePacker) openPages(count int) {
+	for len(packer.bins) < count {
+		page := PackPage{}
+		for _, region := range packer.reserved {
+			if region.Page == len(packer.bins) {
+				page.Width = max(page.Width, region.X+region.Width)
+				page.Height = max(page.Height, region.Y+region.Height)
+			}
+		}
+
+		packer.bins = append(packer.bins, packer.newBin(len(packer.bins)))
+		packer.inserted = append(packer.inserted, nil)
+		packer.layout.Pages = append(packer.layout.Pages, page)
+	}
+}
+
+func (packer *pagePacker) insert(page int, sprite PackSprite) bool {
+	size, offset := packer.size(sprite)
+	at, ok := packer.bins[page].Insert(size.X, size.Y)
+	if !ok {
+		return false
+	}
+
+	packer.inserted[page] = append(packer.inserted[page], size)
+	packer.layout.Pages[page].add(sprite, at.Add(offset))
+	return true
+}
+
+// place puts a single sprite on its pinned page, or else the first page with
+// room, opening a new one if needed.
+func (packer *pagePacker) place(sprite PackSprite) error {
+	if sprite.Page != nil {
+		packer.openPages(*sprite.Page + 1)
+		if !packer.insert(*sprite.Page, sprite) {
+			return fmt.Errorf("sprite %q does not fit on its pinned page %d", sprite.Name, *sprite.Page)
+		}
+		return nil
+	}
+
+	for i := range packer.bins {
+		if packer.insert(i, sprite) {
+			return nil
+		}
+	}
+
+	packer.openPages(len(packer.bins) + 1)
+	if !packer.insert(len(packer.bins)-1, sprite) {
+		return fmt.Errorf("sprite %q does not fit on an empty page", sprite.Name)
+	}
+
+	return nil
+}
+
+// placeGroup puts every sprite of group on one page, trying existing pages
+// before a new one. Bins cannot undo inserts, so each attempt replays the
+// page into a fresh bin and only keeps it if the whole group fits.
+func (packer *pagePacker) placeGroup(group []PackSprite) bool {
+	for i := 0; i <= len(packer.bins); i++ {
+		trial := packer.newBin(i)
+
+		var previous []image.Point
+		if i < len(packer.bins) {
+			previous = packer.inserted[i]
+		}
+		for _, size := range previous {
+			trial.Insert(size.X, size.Y)
+		}
+
+		positions := make([]image.Point, 0, len(group))
+		for _, sprite := range group {
+			size, offset := packer.size(sprite)
+			at, ok := trial.Insert(size.X, size.Y)
+			if !ok {
+				break
+			}
+			positions = append(positions, at.Add(offset))
+		}
+		if len(positions) < len(group) {
+			continue
+		}
+
+		packer.openPages(i + 1)
+		packer.bins[i] = trial
+		for j, sprite := range group {
+			size, _ := packer.size(sprite)
+			packer.inserted[i] = append(packer.inserted[i], size)
+			packer.layout.Pages[i].add(sprite, positions[j])
+		}
+		return true
+	}
+
+	return false
+}
+
+func packSprites(sprites []PackSprite, algorithm PackAlgorithm, options PackOptions) (PackLayout, error) {
+	maxSize := options.MaxSize
+	for _, sprite := range sprites {
+		if sprite.Width > maxSize.Width || sprite.Height > maxSize.Height {
+			return PackLayout{}, fmt.Errorf("sprite %q (%dx%d) does not fit in %dx%d", sprite.Name, sprite.Width, sprite.Height, maxSize.Width, maxSize.Height)
+		}
+	}
+
+	if len(options.Reserved) > 0 {
+		if _, ok := algorithm.New(1, 1).(Reserver); !ok {
+			return PackLayout{}, fmt.Errorf("packing algorithm %s does not support reserved regions", algorithm.Name)
+		}
+	}
+
+	// Every sprite reserves trailing padding, so the bin grows by the same
+	// amount to let sprites touch the far page edges.
+	packer := &pagePacker{
+		algorithm: algorithm,
+		width:     maxSize.Width + options.Padding,
+		height:    maxSize.Height + options.Padding,
+		padding:   options.Padding,
+		reserved:  options.Reserved,
+		layout:    PackLayout{Algorithm: algorithm.Name},
+	}
+
+	// Pinned sprites go first so loose ones cannot take their room.
+	var pinned, loose []PackSprite
+	for _, sprite := range sprites {
+		if sprite.Page != nil {
+			pinned = append(pinned, sprite)
+		} else {
+			loose = append(loose, sprite)
+		}
+	}
+
+	for _, sprite := range sortPackSprites(pinned) {
+		if err := packer.place(sprite); err != nil {
+			return PackLayout{}, err
+		}
+	}
+
+	groups, err := groupPackSprites(loose, options.GroupBy)
+	if err != nil {
+		return PackLayout{}, err
+	}
+
+	for _, group := range groups {
+		if len(group) > 1 && packer.placeGroup(group) {
+			continue
+		}
+
+		// Groups larger than a page are split across pages like loose sprites.
+		for _, sprite := range group {
+			if err := packer.place(sprite); err != nil {
+				return PackLayout{}, err
+			}
+		}
+	}
+
+	for i, page := range packer.layout.Pages {
+		if page.Width == 0 || page.Height == 0 {
+			return PackLayout{}, fmt.Errorf("page %d would be empty, check the page pins", i)
+		}
+	}
+
+	return packer.layout, nil
+}
+
+func (page *PackPage) add(sprite PackSprite, at image.Point) {
+	page.Sprites = append(page.Sprites, PackedSprite{PackSprite: sprite, X: at.X, Y: at.Y})
+	page.Width = max(page.Width, at.X+sprite.Width)
+	page.Height = max(page.Height, at.Y+sprite.Height)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math/rand"
+	"testing"
+)
+
+// TestPackNoOverlap packs random sprites with every algorithm, with and
+// without grouping, and checks that no two sprites on a page come closer
+// than the padding and every sprite is placed once inside the page.
+func TestPackNoOverlap(t *testing.T) {
+	for _, algorithm := range packAlgorithms {
+		for _, groupBy := range packGroupModes {
+			for seed := range 500 {
+				r := rand.New(rand.NewSource(int64(seed)))
+				padding := r.Intn(3)
+				groups := 1 + r.Intn(6)
+				sprites := make([]PackSprite, 20+r.Intn(60))
+				for i := range sprites {
+					width, height := 1+r.Intn(96), 1+r.Intn(64)
+					sprites[i] = PackSprite{
+						Name:       fmt.Sprintf("group%d/sprite%d", r.Intn(groups), i),
+						Width:      width,
+						Height:     height,
+						Bounds:     image.Rect(0, 0, width, height),
+						SourceSize: Size{Width: width, Height: height},
+					}
+				}
+
+				options := PackOptions{MaxSize: Size{Width: 128 << r.Intn(2), Height: 128 << r.Intn(2)}, Padding: padding, GroupBy: groupBy}
+				layout, err := packSprites(sprites, algorithm, options)
+				if err != nil {
+					t.Fatalf("%s, group by %s, seed %d: %v", algorithm.Name, groupBy, seed, err)
+				}
+
+				placed := 0
+				for p, page := range layout.Pages {
+					for i, a := range page.Sprites {
+						placed++
+						ra := image.Rect(a.X, a.Y, a.X+a.Width, a.Y+a.Height)
+						if !ra.In(image.Rect(0, 0, options.MaxSize.Width, options.MaxSize.Height)) {
+							t.Fatalf("%s, group by %s, seed %d: %s at %v leaves page %d", algorithm.Name, groupBy, seed, a.Name, ra, p)
+						}
+						for _, b := range page.Sprites[i+1:] {
+							rb := image.Rect(b.X, b.Y, b.X+b.Width, b.Y+b.Height)
+							if ra.Add(image.Pt(padding, padding)).Union(ra).Overlaps(rb) || rb.Add(image.Pt(padding, padding)).Union(rb).Overlaps(ra) {
+								t.Fatalf("%s, group by %s, seed %d: %s at %v and %s at %v overlap on page %d", algorithm.Name, groupBy, seed, a.Name, ra, b.Name, rb, p)
+							}
+						}
+					}
+				}
+				if placed != len(sprites) {
+					t.Fatalf("%s, group by %s, seed %d: placed %d of %d sprites", algorithm.Name, groupBy, seed, placed, len(sprites))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultPipelinePath = "txunpak.pipeline.yaml"
+
+// A PipelineStep runs one txunpak command. Run names the subcommand, with
+// unpack meaning the root command. Inputs and Outputs are optional; a step
+// that lists both is skipped while its inputs and definition are unchanged
+// since it last succeeded and its outputs still exist.
+type PipelineStep struct {
+	Name    string         `yaml:"name" json:"name"`
+	Run     string         `yaml:"run" json:"run"`
+	Args    []string       `yaml:"args" json:"args"`
+	Flags   map[string]any `yaml:"flags" json:"flags"`
+	Inputs  []string       `yaml:"inputs" json:"inputs"`
+	Outputs []string       `yaml:"outputs" json:"outputs"`
+}
+
+type Pipeline struct {
+	Steps []PipelineStep `yaml:"steps"`
+}
+
+// PipelineCache maps step names to the key of their last successful run.
+type PipelineCache struct {
+	Steps map[string]string `json:"steps"`
+}
+
+type StepResult struct {
+	Name    string
+	Status  string
+	Seconds float64
+}
+
+func loadPipeline(path string) (Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Pipeline{}, fmt.Errorf("failed to read pipeline: %w", err)
+	}
+
+	var pipeline Pipeline
+	if err := yaml.Unmarshal(data, &pipeline); err != nil {
+		return Pipeline{}, fmt.Errorf("invalid pipeline: %w", err)
+	}
+	if len(pipeline.Steps) == 0 {
+		return Pipeline{}, fmt.Errorf("invalid pipeline: no steps")
+	}
+
+	names := make(map[string]bool)
+	for i, step := range pipeline.Steps {
+		if step.Name == "" {
+			return Pipeline{}, fmt.Errorf("invalid pipeline: step %d has no name", i+1)
+		}
+		if names[step.Name] {
+			return Pipeline{}, fmt.Errorf("invalid pipeline: step %q is defined twice", step.Name)
+		}
+		names[step.Name] = true
+		if step.Run == "" || step.Run == "pipeline" {
+			return Pipeline{}, fmt.Errorf("invalid pipeline: step %q must run unpack or another command", step.Name)
+		}
+	}
+	return pipeline, nil
+}
+
+// commandLine turns the step into arguments for the root command, with
+// flags sorted by name so the line is the same every run. Lists repeat the
+// flag.
+func (step PipelineStep) commandLine() []string {
+	var line []string
+	if step.Run != "unpack" {
+		line = append(line, strings.Fields(step.Run)...)
+	}
+	line = append(line, step.Args...)
+
+	for _, name := range slices.Sorted(maps.Keys(step.Flags)) {
+		switch value := step.Flags[name].(type) {
+		case bool:
+			line = append(line, fmt.Sprintf("--%s=%t", name, value))
+		case []any:
+			for _, item := range value {
+				line = append(line, fmt.Sprintf("--%s=%v", name, item))
+			}
+		default:
+			line = append(line, fmt.Sprintf("--%s=%v", name, value))
+		}
+	}
+
+	// Steps print their own output, so only the pipeline reports progress.
+	if step.Run == "unpack" {
+		if _, ok := step.Flags["no-progress"]; !ok {
+			line = append(line, "--no-progress")
+		}
+	}
+	return line
+}
+
+// cacheKey hashes the step's definition with the size and modification
+// time of every file below its inputs, like make does, so unchanged inputs
+// are not read again. Steps without inputs and outputs are never cached.
+func (step PipelineStep) cacheKey() (string, bool, error) {
+	if len(step.Inputs) == 0 || len(step.Outputs) == 0 {
+		return "", false, nil
+	}
+	for _, output := range step.Outputs {
+		if _, err := os.Stat(output); err != nil {
+			return "", false, nil
+		}
+	}
+
+	hash := sha256.New()
+	definition, err := json.Marshal(step)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to encode step %q: %w", step.Name, err)
+	}
+	hash.Write(definition)
+
+	for _, input := range step.Inputs {
+		err := filepath.WalkDir(input, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hash, "%s\x00%d\x00%d\n", filepath.ToSlash(path), info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read input %s of step %q: %w", input, step.Name, err)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), true, nil
+}
+
+func loadPipelineCache(path string) PipelineCache {
+	cache := PipelineCache{Steps: make(map[string]string)}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &cache)
+	}
+	if cache.Steps == nil {
+		cache.Steps = make(map[string]string)
+	}
+	return cache
+}
+
+func (cache PipelineCache) save(path string) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pipeline cache: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write pipeline cache: %w", err)
+	}
+	return nil
+}
+
+// runPipeline runs the steps in order on fresh command trees, stopping at
+// the first failure. The cache is saved after every step, so a failed run
+// keeps what the steps before it did.
+func runPipeline(pipeline Pipeline, cachePath string, useCache bool) ([]StepResult, error) {
+	cache := loadPipelineCache(cachePath)
+	var results []StepResult
+
+	for i, step := range pipeline.Steps {
+		start := time.Now()
+		key, cacheable, err := step.cacheKey()
+		if err != nil {
+			return results, err
+		}
+
+		if useCache && cacheable && cache.Steps[step.Name] == key {
+			logf("[info] [%d/%d] %s: unchanged, skipping\n", i+1, len(pipeline.Steps), step.Name)
+			results = append(results, StepResult{Name: step.Name, Status: "cached"})
+			continue
+		}
+
+		logf("[info] [%d/%d] %s: txunpak %s\n", i+1, len(pipeline.Steps), step.Name, strings.Join(step.commandLine(), " "))
+		rootCmd := newRootCmd()
+		rootCmd.SetArgs(step.commandLine())
+		rootCmd.SilenceErrors = true
+		rootCmd.SilenceUsage = true
+		if err := rootCmd.Execute(); err != nil {
+			results = append(results, StepResult{Name: step.Name, Status: "failed", Seconds: time.Since(start).Seconds()})
+			return results, fmt.Errorf("step %q failed: %w", step.Name, err)
+		}
+		results = append(results, StepResult{Name: step.Name, Status: "ran", Seconds: time.Since(start).Seconds()})
+
+		// Outputs exist now, so the key can be taken for the next run.
+		if key, cacheable, err = step.cacheKey(); err != nil {
+			return results, err
+		}
+		if cacheable {
+			cache.Steps[step.Name] = key
+		} else {
+			delete(cache.Steps, step.Name)
+		}
+		if err := cache.save(cachePath); err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+func printPipelineResults(results []StepResult) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "STEP\tSTATUS\tTIME")
+	for _, result := range results {
+		fmt.Fprintf(writer, "%s\t%s\t%.2fs\n", result.Name, result.Status, result.Seconds)
+	}
+	writer.Flush()
+}
+
+func newPipelineCmd() *cobra.Command {
+	var noCache bool = false
+
+	var pipelineCmd = &cobra.Command{
+		Use:   "pipeline [file]",
+		Short: "Run the steps of a pipeline file, skipping those whose inputs are unchanged",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := defaultPipelinePath
+			if len(args) == 1 {
+				path = args[0]
+			}
+
+			pipeline, err := loadPipeline(path)
+			if err != nil {
+				return err
+			}
+
+			// Paths in the pipeline are relative to its file.
+			if err := os.Chdir(filepath.Dir(path)); err != nil {
+				return fmt.Errorf("failed to enter pipeline directory: %w", err)
+			}
+
+			results, err := runPipeline(pipeline, ".txunpak-cache.json", !noCache)
+			printPipelineResults(results)
+			return err
+		},
+	}
+
+	pipelineCmd.Flags().BoolVarP(&noCache, "no-cache", "", noCache, "Run every step even when its inputs are unchanged")
+
+	return pipelineCmd
+}
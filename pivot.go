@@ -0,0 +1,79 @@
+package main
+
+import (
+	"image"
+	"slices"
+)
+
+var autoPivotModes = []string{"none", "center", "bottom-center", "opaque-centroid"}
+
+// autoPivot places a pivot by a sprite's opaque pixels, as a fraction of its
+// source size: the center of their bounds, the middle of their bottom edge,
+// where characters stand, or their centroid weighted by alpha.
+func autoPivot(sprite image.Image, mode string) Pivot {
+	bounds := sprite.Bounds()
+	if bounds.Empty() {
+		return Pivot{X: 0.5, Y: 0.5}
+	}
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+
+	if mode == "opaque-centroid" {
+		var sumX, sumY, sumA float64
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				_, _, _, a := sprite.At(x, y).RGBA()
+				if a == 0 {
+					continue
+				}
+				weight := float64(a) / 0xffff
+				sumX += (float64(x-bounds.Min.X) + 0.5) * weight
+				sumY += (float64(y-bounds.Min.Y) + 0.5) * weight
+				sumA += weight
+			}
+		}
+		if sumA == 0 {
+			return Pivot{X: 0.5, Y: 0.5}
+		}
+		return Pivot{X: sumX / sumA / width, Y: sumY / sumA / height}
+	}
+
+	opaque := opaqueBounds(sprite).Sub(bounds.Min)
+	x := float64(opaque.Min.X+opaque.Max.X) / 2 / width
+	if mode == "bottom-center" {
+		return Pivot{X: x, Y: float64(opaque.Max.Y) / height}
+	}
+	return Pivot{X: x, Y: float64(opaque.Min.Y+opaque.Max.Y) / 2 / height}
+}
+
+// autoPivots gives every frame without a pivot one computed from its
+// pixels, decoding only the sheets holding such frames. Pivots the atlas
+// records are kept.
+func autoPivots(pack Pack, inputDir, mode string) (Pack, int, error) {
+	computed := 0
+	sheets := make([]Sheet, len(pack.Sheets))
+	for i, sh := range pack.Sheets {
+		sheets[i] = sh
+		if !slices.ContainsFunc(sh.Textures, func(tex Texture) bool { return tex.Pivot == nil }) {
+			continue
+		}
+
+		img, err := decodeSheet(inputDir, sh)
+		if err != nil {
+			return pack, 0, err
+		}
+
+		textures := slices.Clone(sh.Textures)
+		for j, tex := range textures {
+			if tex.Pivot != nil {
+				continue
+			}
+			pivot := autoPivot(extractTexture(tex, img), mode)
+			textures[j].Pivot = &pivot
+			computed++
+		}
+		sheets[i].Textures = textures
+	}
+
+	pack.Sheets = sheets
+	return pack, computed, nil
+}
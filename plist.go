@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func decodePlistValue(decoder *xml.Decoder, start xml.StartElement) (any, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]any)
+		var key string
+
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			switch t := token.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					var k string
+					if err := decoder.DecodeElement(&k, &t); err != nil {
+						return nil, err
+					}
+					key = k
+					continue
+				}
+
+				value, err := decodePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		var array []any
+
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			switch t := token.(type) {
+			case xml.StartElement:
+				value, err := decodePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	default:
+		var text string
+		if err := decoder.DecodeElement(&text, &start); err != nil {
+			return nil, err
+		}
+
+		switch start.Name.Local {
+		case "integer", "real":
+			return strconv.ParseFloat(strings.TrimSpace(text), 64)
+		default:
+			return text, nil
+		}
+	}
+}
+
+func decodePlist(data []byte) (any, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("empty plist")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return decodePlistValue(decoder, start)
+		}
+	}
+}
+
+// Cocos2d encodes geometry as strings like "{{x,y},{w,h}}" or "{w,h}".
+func parsePlistInts(value any) []int {
+	s, _ := value.(string)
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '{' || r == '}' || r == ',' || r == ' '
+	})
+	if len(fields) == 0 {
+		return nil
+	}
+
+	ints := make([]int, 0, len(fields))
+	for _, field := range fields {
+		n, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil
+		}
+		ints = append(ints, int(n))
+	}
+
+	return ints
+}
+
+func plistInt(dict map[string]any, key string) int {
+	n, _ := dict[key].(float64)
+	return int(n)
+}
+
+func plistFrameTexture(name string, dict map[string]any) Texture {
+	tex := Texture{FileName: frameName(name)}
+
+	if _, ok := dict["width"]; ok {
+		// Format 0 stores plain numeric fields rather than geometry strings.
+		tex.Frame = Frame{X: plistInt(dict, "x"), Y: plistInt(dict, "y"), Width: plistInt(dict, "width"), Height: plistInt(dict, "height")}
+		tex.SourceSize = Size{Width: plistInt(dict, "originalWidth"), Height: plistInt(dict, "originalHeight")}
+		tex.SpriteSourceSize = Frame{
+			X:      (tex.SourceSize.Width-tex.Frame.Width)/2 + plistInt(dict, "offsetX"),
+			Y:      (tex.SourceSize.Height-tex.Frame.Height)/2 - plistInt(dict, "offsetY"),
+			Width:  tex.Frame.Width,
+			Height: tex.Frame.Height,
+		}
+	} else {
+		rect := parsePlistInts(dict["frame"])
+		if rect == nil {
+			rect = parsePlistInts(dict["textureRect"])
+		}
+		if len(rect) == 4 {
+			tex.Frame = Frame{X: rect[0], Y: rect[1], Width: rect[2], Height: rect[3]}
+		}
+
+		tex.Rotated, _ = dict["rotated"].(bool)
+		if rotated, ok := dict["textureRotated"].(bool); ok {
+			tex.Rotated = rotated
+		}
+
+		source := parsePlistInts(dict["sourceSize"])
+		if source == nil {
+			source = parsePlistInts(dict["spriteSourceSize"])
+		}
+		if len(source) == 2 {
+			tex.SourceSize = Size{Width: source[0], Height: source[1]}
+		} else {
+			tex.SourceSize = Size{Width: tex.Frame.Width, Height: tex.Frame.Height}
+		}
+
+		if colorRect := parsePlistInts(dict["sourceColorRect"]); len(colorRect) == 4 {
+			tex.SpriteSourceSize = Frame{X: colorRect[0], Y: colorRect[1], Width: colorRect[2], Height: colorRect[3]}
+		} else {
+			// Offsets are from the center of the source to the center of the
+			// trimmed frame, with y pointing up.
+			offset := parsePlistInts(dict["offset"])
+			if offset == nil {
+				offset = parsePlistInts(dict["spriteOffset"])
+			}
+			if len(offset) != 2 {
+				offset = []int{0, 0}
+			}
+			tex.SpriteSourceSize = Frame{
+				X:      (tex.SourceSize.Width-tex.Frame.Width)/2 + offset[0],
+				Y:      (tex.SourceSize.Height-tex.Frame.Height)/2 - offset[1],
+				Width:  tex.Frame.Width,
+				Height: tex.Frame.Height,
+			}
+		}
+	}
+
+	tex.Trimmed = tex.SourceSize.Width != tex.Frame.Width || tex.SourceSize.Height != tex.Frame.Height
+
+	return tex
+}
+
+func parsePlistPack(data []byte, plistPath string) (Pack, error) {
+	root, err := decodePlist(data)
+	if err != nil {
+		return Pack{}, fmt.Errorf("invalid plist: %w", err)
+	}
+
+	dict, ok := root.(map[string]any)
+	if !ok {
+		return Pack{}, fmt.Errorf("invalid plist: root is not a dict")
+	}
+
+	frames, ok := dict["frames"].(map[string]any)
+	if !ok {
+		return Pack{}, fmt.Errorf("invalid plist: missing frames dict")
+	}
+
+	sheet := Sheet{Scale: 1}
+	if metadata, ok := dict["metadata"].(map[string]any); ok {
+		sheet.Image, _ = metadata["realTextureFileName"].(string)
+		if sheet.Image == "" {
+			sheet.Image, _ = metadata["textureFileName"].(string)
+		}
+		if size := parsePlistInts(metadata["size"]); len(size) == 2 {
+			sheet.Size = Size{Width: size[0], Height: size[1]}
+		}
+	}
+	if sheet.Image == "" {
+		base := filepath.Base(plistPath)
+		sheet.Image = strings.TrimSuffix(base, filepath.Ext(base)) + ".png"
+	}
+
+	names := make([]string, 0, len(frames))
+	for name := range frames {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		frame, ok := frames[name].(map[string]any)
+		if !ok {
+			return Pack{}, fmt.Errorf("invalid plist: frame %q is not a dict", name)
+		}
+		sheet.Textures = append(sheet.Textures, plistFrameTexture(name, frame))
+	}
+
+	return Pack{Sheets: []Sheet{sheet}}, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"image/draw"
+	"os"
+)
+
+// polygonal reports whether the frame was packed with polygon trimming,
+// meaning only its triangles are its own and the rest of its rect may hold
+// parts of neighbouring frames.
+func (tex Texture) polygonal() bool {
+	return len(tex.Triangles) > 0 && len(tex.VerticesUV) > 0
+}
+
+// insideTriangle tests p against a triangle by the side of each edge it lies
+// on, so either winding order works and edges count as inside.
+func insideTriangle(p, a, b, c [2]float64) bool {
+	side := func(p, a, b [2]float64) float64 {
+		return (p[0]-b[0])*(a[1]-b[1]) - (a[0]-b[0])*(p[1]-b[1])
+	}
+	d1, d2, d3 := side(p, a, b), side(p, b, c), side(p, c, a)
+	negative := d1 < 0 || d2 < 0 || d3 < 0
+	positive := d1 > 0 || d2 > 0 || d3 > 0
+	return !(negative && positive)
+}
+
+// insidePolygon tests a sheet pixel's center against the frame's triangles,
+// whose vertices are sheet positions.
+func (tex Texture) insidePolygon(x, y int) bool {
+	p := [2]float64{float64(x) + 0.5, float64(y) + 0.5}
+	for _, triangle := range tex.Triangles {
+		if triangle[0] >= len(tex.VerticesUV) || triangle[1] >= len(tex.VerticesUV) || triangle[2] >= len(tex.VerticesUV) {
+			continue
+		}
+		if insideTriangle(p, tex.VerticesUV[triangle[0]], tex.VerticesUV[triangle[1]], tex.VerticesUV[triangle[2]]) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskPolygon clears the sprite pixels whose sheet pixels fall outside the
+// frame's polygon.
+func maskPolygon(tex Texture, sprite draw.Image) {
+	dest := tex.SpriteSourceSize.Rect()
+	for y := dest.Min.Y; y < dest.Max.Y; y++ {
+		for x := dest.Min.X; x < dest.Max.X; x++ {
+			if p := tex.sheetPoint(x-dest.Min.X, y-dest.Min.Y); !tex.insidePolygon(p.X, p.Y) {
+				sprite.Set(x, y, color.Transparent)
+			}
+		}
+	}
+}
+
+// FrameMesh is a polygon-trimmed frame's mesh as the atlas gives it:
+// vertices in sprite space, their sheet positions, and triangles indexing
+// both.
+type FrameMesh struct {
+	Vertices   [][2]float64 `json:"vertices"`
+	VerticesUV [][2]float64 `json:"verticesUV"`
+	Triangles  [][3]int     `json:"triangles"`
+}
+
+func writeMeshes(pack Pack, path string) error {
+	meshes := make(map[string]FrameMesh)
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			if tex.polygonal() {
+				meshes[tex.FileName] = FrameMesh{tex.Vertices, tex.VerticesUV, tex.Triangles}
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(meshes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode meshes: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write meshes: %w", err)
+	}
+	return nil
+}
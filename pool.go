@@ -0,0 +1,71 @@
+package main
+
+import (
+	"image"
+	"math/bits"
+	"runtime"
+	"sync"
+	"weak"
+)
+
+// Frames are cropped into pixel buffers drawn from pools bucketed by
+// power-of-two size, so a pack of thousands of frames reuses a handful of
+// buffers instead of leaving one per frame to the garbage collector.
+var pixelPools [bits.UintSize]sync.Pool
+
+// pixelBucket is the pool of buffers with room for n bytes.
+func pixelBucket(n int) int {
+	return bits.Len(uint(n - 1))
+}
+
+// getPixels returns n zeroed bytes, reusing a released buffer when one fits.
+func getPixels(n int) []uint8 {
+	if n == 0 {
+		return nil
+	}
+	bucket := pixelBucket(n)
+	if pix, ok := pixelPools[bucket].Get().(*[]uint8); ok {
+		buffer := (*pix)[:n]
+		clear(buffer)
+		return buffer
+	}
+	return make([]uint8, n, 1<<bucket)
+}
+
+// putPixels hands a buffer from getPixels back for reuse. Nothing may use it
+// afterwards.
+func putPixels(pix []uint8) {
+	size := cap(pix)
+	if size == 0 || size&(size-1) != 0 {
+		return
+	}
+	pix = pix[:size]
+	pixelPools[pixelBucket(size)].Put(&pix)
+}
+
+// pooledSprites holds every sprite from newPooledNRGBA not yet released, so
+// releaseSprite never pools a buffer it did not hand out, nor one twice. It
+// holds them weakly, dropping those collected without a release.
+var pooledSprites sync.Map
+
+// newPooledNRGBA is image.NewNRGBA with pooled pixels, for sprites released
+// with releaseSprite once written. Those never released are simply collected.
+func newPooledNRGBA(rect image.Rectangle) *image.NRGBA {
+	sprite := &image.NRGBA{Pix: getPixels(4 * rect.Dx() * rect.Dy()), Stride: 4 * rect.Dx(), Rect: rect}
+	key := weak.Make(sprite)
+	pooledSprites.Store(key, struct{}{})
+	runtime.AddCleanup(sprite, func(key weak.Pointer[image.NRGBA]) { pooledSprites.Delete(key) }, key)
+	return sprite
+}
+
+// releaseSprite returns the pixels of a sprite from newPooledNRGBA to the
+// pool. Other sprites are left to the garbage collector.
+func releaseSprite(sprite image.Image) {
+	nrgba, ok := sprite.(*image.NRGBA)
+	if !ok {
+		return
+	}
+	if _, pooled := pooledSprites.LoadAndDelete(weak.Make(nrgba)); pooled {
+		putPixels(nrgba.Pix)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// benchmarkSheet is a 1024x1024 sheet cut into a grid of 128x128 frames, the
+// last column trimmed so its frames are padded on extraction.
+func benchmarkSheet() (image.Image, []Texture) {
+	sheet := image.NewNRGBA(image.Rect(0, 0, 1024, 1024))
+	for y := range 1024 {
+		for x := range 1024 {
+			sheet.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: uint8(x ^ y), A: 255})
+		}
+	}
+
+	var textures []Texture
+	for y := 0; y < 1024; y += 128 {
+		for x := 0; x < 1024; x += 128 {
+			tex := Texture{
+				Frame:            Frame{X: x, Y: y, Width: 128, Height: 128},
+				SourceSize:       Size{Width: 128, Height: 128},
+				SpriteSourceSize: Frame{Width: 128, Height: 128},
+			}
+			if x == 896 {
+				tex.Frame.Width, tex.SpriteSourceSize.X, tex.SpriteSourceSize.Width = 96, 32, 96
+			}
+			textures = append(textures, tex)
+		}
+	}
+	return sheet, textures
+}
+
+// BenchmarkExtractPooled releases every sprite once done with it, as
+// unpackTexture does.
+func BenchmarkExtractPooled(b *testing.B) {
+	sheet, textures := benchmarkSheet()
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, tex := range textures {
+			releaseSprite(extractTexture(tex, sheet))
+		}
+	}
+}
+
+// BenchmarkExtractUnpooled never releases a sprite, so every frame allocates
+// its own buffer and leaves it to the garbage collector.
+func BenchmarkExtractUnpooled(b *testing.B) {
+	sheet, textures := benchmarkSheet()
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, tex := range textures {
+			extractTexture(tex, sheet)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+const porcelainVersion = 1
+
+// porcelainOut receives --porcelain records, nil when the flag is off.
+var porcelainOut *PorcelainWriter
+
+// A PorcelainWriter writes output meant for other programs rather than
+// people. It starts with a version line, then one record per line: fields
+// separated by tabs, the first naming the record. Backslashes, tabs, and
+// newlines in fields are escaped, so every record stays on one line. Within
+// a version, records only ever gain fields at the end; anything else bumps
+// the version.
+type PorcelainWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+var porcelainEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func newPorcelainWriter(w io.Writer) *PorcelainWriter {
+	fmt.Fprintf(w, "txunpak-porcelain %d\n", porcelainVersion)
+	return &PorcelainWriter{w: w}
+}
+
+// startPorcelain keeps stdout for records and moves everything printed for
+// people, messages and progress alike, to stderr.
+func startPorcelain() {
+	if porcelainOut != nil {
+		return
+	}
+	porcelainOut = newPorcelainWriter(os.Stdout)
+	os.Stdout = os.Stderr
+}
+
+func (writer *PorcelainWriter) record(kind string, fields ...any) {
+	var line strings.Builder
+	line.WriteString(kind)
+	for _, field := range fields {
+		line.WriteByte('\t')
+		switch v := field.(type) {
+		case bool:
+			if v {
+				line.WriteString("1")
+			} else {
+				line.WriteString("0")
+			}
+		case float64:
+			fmt.Fprintf(&line, "%.3f", v)
+		default:
+			line.WriteString(porcelainEscaper.Replace(fmt.Sprint(v)))
+		}
+	}
+	line.WriteByte('\n')
+
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	io.WriteString(writer.w, line.String())
+}
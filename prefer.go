@@ -0,0 +1,54 @@
+package main
+
+import (
+	"image"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// sheetVariant reports whether name decodes as a sheet of the given size,
+// which rules out formats with no registered decoder, like KTX2.
+func sheetVariant(inputDir, name string, size Size) bool {
+	file, err := os.Open(filepath.Join(inputDir, filepath.FromSlash(name)))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return false
+	}
+	return size == Size{} || (config.Width == size.Width && config.Height == size.Height)
+}
+
+// preferSheetFormats swaps each sheet image for the first sibling in the
+// preferred formats that exists and decodes, the way Phaser picks the first
+// texture format a device supports. Sheets with no such sibling keep the
+// image named in the atlas.
+func preferSheetFormats(pack Pack, inputDir string, formats []string) Pack {
+	if len(formats) == 0 {
+		return pack
+	}
+
+	sheets := make([]Sheet, len(pack.Sheets))
+	for i, sh := range pack.Sheets {
+		base := strings.TrimSuffix(sh.Image, path.Ext(sh.Image))
+		for _, format := range formats {
+			candidate := base + "." + strings.ToLower(strings.TrimPrefix(format, "."))
+			if sheetVariant(inputDir, candidate, sh.Size) {
+				if candidate != sh.Image {
+					logf("[info] using %s for %s\n", candidate, sh.Image)
+					sh.Image = candidate
+				}
+				break
+			}
+		}
+		sheets[i] = sh
+	}
+
+	pack.Sheets = sheets
+	return pack
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+)
+
+// unpremultiply divides color by alpha in a sheet whose pixels were stored
+// premultiplied but decoded as straight alpha, which otherwise shows up as
+// dark fringes around every sprite. Image types Go already treats as
+// premultiplied, and grayscale without alpha, are left as they are.
+func unpremultiply(img image.Image) image.Image {
+	bounds := img.Bounds()
+
+	switch src := img.(type) {
+	case *image.RGBA, *image.RGBA64, *image.Gray, *image.Gray16:
+		return img
+	case *image.NRGBA64:
+		deep := image.NewNRGBA64(bounds)
+		copy(deep.Pix, src.Pix)
+		for i := 0; i < len(deep.Pix); i += 8 {
+			a := uint32(deep.Pix[i+6])<<8 | uint32(deep.Pix[i+7])
+			if a == 0 || a == 0xffff {
+				continue
+			}
+			for c := i; c < i+6; c += 2 {
+				v := uint32(deep.Pix[c])<<8 | uint32(deep.Pix[c+1])
+				v = min(0xffff, (v*0xffff+a/2)/a)
+				deep.Pix[c], deep.Pix[c+1] = uint8(v>>8), uint8(v)
+			}
+		}
+		return deep
+	}
+
+	nrgba := image.NewNRGBA(bounds)
+	draw.Draw(nrgba, bounds, img, bounds.Min, draw.Src)
+	for i := 0; i < len(nrgba.Pix); i += 4 {
+		a := uint32(nrgba.Pix[i+3])
+		if a == 0 || a == 0xff {
+			continue
+		}
+		for c := i; c < i+3; c++ {
+			nrgba.Pix[c] = uint8(min(0xff, (uint32(nrgba.Pix[c])*0xff+a/2)/a))
+		}
+	}
+	return nrgba
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// A Profiler writes Go profiles of a run, so a slow pack can be diagnosed
+// without patching the binary: a CPU profile and an execution trace while
+// the command runs, and a heap profile once it ends. Read them with go tool
+// pprof and go tool trace.
+type Profiler struct {
+	CPU       string
+	Mem       string
+	Trace     string
+	cpuFile   *os.File
+	traceFile *os.File
+}
+
+// profiler is set from the hidden --cpuprofile, --memprofile, and --trace
+// flags, and stopped by main however the command ends.
+var profiler Profiler
+
+func (profiler *Profiler) start() error {
+	if profiler.CPU != "" {
+		file, err := os.Create(profiler.CPU)
+		if err != nil {
+			return withExitCode(exitOutput, fmt.Errorf("failed to create CPU profile: %w", err))
+		}
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		profiler.cpuFile = file
+	}
+
+	if profiler.Trace != "" {
+		file, err := os.Create(profiler.Trace)
+		if err != nil {
+			return withExitCode(exitOutput, fmt.Errorf("failed to create trace: %w", err))
+		}
+		if err := trace.Start(file); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to start trace: %w", err)
+		}
+		profiler.traceFile = file
+	}
+	return nil
+}
+
+// stop finishes the profiles start began and writes the heap profile.
+func (profiler *Profiler) stop() error {
+	var errs []error
+	if profiler.cpuFile != nil {
+		pprof.StopCPUProfile()
+		errs = append(errs, profiler.cpuFile.Close())
+		profiler.cpuFile = nil
+	}
+	if profiler.traceFile != nil {
+		trace.Stop()
+		errs = append(errs, profiler.traceFile.Close())
+		profiler.traceFile = nil
+	}
+
+	if profiler.Mem != "" {
+		file, err := os.Create(profiler.Mem)
+		if err != nil {
+			return withExitCode(exitOutput, fmt.Errorf("failed to create memory profile: %w", err))
+		}
+		// Collecting first makes the profile show what is still live.
+		runtime.GC()
+		errs = append(errs, pprof.WriteHeapProfile(file), file.Close())
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return withExitCode(exitOutput, fmt.Errorf("failed to write profile: %w", err))
+	}
+	return nil
+}
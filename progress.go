@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/vbauerster/mpb/v8"
+	"github.com/vbauerster/mpb/v8/decor"
+)
+
+var progressModes = []string{"bar", "total", "json"}
+
+const progressInterval = 500 * time.Millisecond
+
+// barDecorators label a progress bar and show its counts, percentage,
+// elapsed time, ETA, and frames per second. Columns line up across bars.
+func barDecorators(name string) []mpb.BarOption {
+	return []mpb.BarOption{
+		mpb.PrependDecorators(
+			decor.Name(name+" ", decor.WCSyncWidth),
+			decor.CountersNoUnit("%d / %d", decor.WCSyncWidth),
+		),
+		mpb.AppendDecorators(
+			decor.Percentage(decor.WCSyncWidth),
+			decor.Name("  elapsed "),
+			decor.Elapsed(decor.ET_STYLE_GO, decor.WCSyncWidth),
+			decor.Name("  ETA "),
+			decor.OnComplete(decor.AverageETA(decor.ET_STYLE_GO, decor.WCSyncWidth), "done"),
+			decor.Name("  "),
+			decor.AverageSpeed(0, "%.0f frames/s", decor.WCSyncWidth),
+		),
+	}
+}
+
+type progressRecord struct {
+	Done  int64  `json:"done"`
+	Total int64  `json:"total"`
+	Sheet string `json:"sheet,omitempty"`
+}
+
+// A ProgressStream writes progress as JSON lines for wrappers drawing their
+// own progress UI, like a GUI or CI. Every progressInterval it writes a
+// record for each sheet that advanced and one without a sheet for the whole
+// run, and once more when the run ends. The first round covers every sheet,
+// so a wrapper knows the totals before any frame is done.
+type ProgressStream struct {
+	encoder  *json.Encoder
+	sheets   []string
+	totals   map[string]int64
+	done     map[string]*atomic.Int64
+	reported map[string]int64
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+func newProgressStream(w io.Writer, sheets []Sheet) *ProgressStream {
+	stream := &ProgressStream{
+		encoder:  json.NewEncoder(w),
+		totals:   make(map[string]int64),
+		done:     make(map[string]*atomic.Int64),
+		reported: map[string]int64{"": -1},
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	for _, sh := range sheets {
+		if _, ok := stream.done[sh.Image]; !ok {
+			stream.sheets = append(stream.sheets, sh.Image)
+			stream.done[sh.Image] = &atomic.Int64{}
+			stream.reported[sh.Image] = -1
+		}
+		stream.totals[sh.Image] += int64(len(sh.Textures))
+	}
+
+	go stream.run()
+	return stream
+}
+
+func (stream *ProgressStream) increment(sheet string) {
+	stream.done[sheet].Add(1)
+}
+
+func (stream *ProgressStream) run() {
+	defer close(stream.stopped)
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	stream.report()
+	for {
+		select {
+		case <-ticker.C:
+			stream.report()
+		case <-stream.stop:
+			stream.report()
+			return
+		}
+	}
+}
+
+// report writes the records that changed since the last round. Only run
+// calls it, so reported needs no lock.
+func (stream *ProgressStream) report() {
+	var done, total int64
+	for _, sheet := range stream.sheets {
+		n := stream.done[sheet].Load()
+		done += n
+		total += stream.totals[sheet]
+		if n != stream.reported[sheet] {
+			stream.encoder.Encode(progressRecord{Done: n, Total: stream.totals[sheet], Sheet: sheet})
+			stream.reported[sheet] = n
+		}
+	}
+	if done != stream.reported[""] {
+		stream.encoder.Encode(progressRecord{Done: done, Total: total})
+		stream.reported[""] = done
+	}
+}
+
+// close writes the final records and stops the stream.
+func (stream *ProgressStream) close() {
+	close(stream.stop)
+	<-stream.stopped
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"cmp"
+	"image"
+	"image/color"
+	"image/draw"
+	"slices"
+)
+
+type colorCount struct {
+	color color.NRGBA
+	count int
+}
+
+// colorBox is one median-cut box: a run of distinct colors weighted by how
+// often each occurs.
+type colorBox []colorCount
+
+func channel(c color.NRGBA, i int) uint8 {
+	return [4]uint8{c.R, c.G, c.B, c.A}[i]
+}
+
+// widest returns the channel the box spans furthest, and that span.
+func (box colorBox) widest() (int, int) {
+	best, span := 0, -1
+	for i := range 4 {
+		lo, hi := uint8(255), uint8(0)
+		for _, entry := range box {
+			v := channel(entry.color, i)
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		if int(hi)-int(lo) > span {
+			best, span = i, int(hi)-int(lo)
+		}
+	}
+	return best, span
+}
+
+// split sorts the box along its widest channel and cuts it where half the
+// pixels fall on either side.
+func (box colorBox) split() (colorBox, colorBox) {
+	ch, _ := box.widest()
+	slices.SortFunc(box, func(a, b colorCount) int {
+		return cmp.Compare(channel(a.color, ch), channel(b.color, ch))
+	})
+
+	total := 0
+	for _, entry := range box {
+		total += entry.count
+	}
+
+	seen := 0
+	for i, entry := range box[:len(box)-1] {
+		seen += entry.count
+		if seen*2 >= total {
+			return box[:i+1], box[i+1:]
+		}
+	}
+	return box[:len(box)-1], box[len(box)-1:]
+}
+
+func (box colorBox) average() color.NRGBA {
+	var sums [4]int
+	total := 0
+	for _, entry := range box {
+		for i := range 4 {
+			sums[i] += int(channel(entry.color, i)) * entry.count
+		}
+		total += entry.count
+	}
+	return color.NRGBA{uint8(sums[0] / total), uint8(sums[1] / total), uint8(sums[2] / total), uint8(sums[3] / total)}
+}
+
+// quantize reduces img to at most n colors by median cut, without dithering
+// so pixel art keeps its hard edges. Fully transparent pixels share one
+// palette entry, since their color is never seen.
+func quantize(img image.Image, n int) *image.Paletted {
+	bounds := img.Bounds()
+	nrgba := image.NewNRGBA(bounds)
+	draw.Draw(nrgba, bounds, img, bounds.Min, draw.Src)
+
+	counts := make(ColorHistogram)
+	counts.add(nrgba, bounds)
+	_, transparent := counts[color.NRGBA{}]
+	delete(counts, color.NRGBA{})
+
+	var palette color.Palette
+	if transparent {
+		palette = append(palette, color.NRGBA{})
+		n--
+	}
+
+	if len(counts) > 0 {
+		boxes := []colorBox{make(colorBox, 0, len(counts))}
+		for c, count := range counts {
+			boxes[0] = append(boxes[0], colorCount{c, count})
+		}
+
+		for len(boxes) < n {
+			widest, span := -1, 0
+			for i, box := range boxes {
+				if _, s := box.widest(); len(box) > 1 && s > span {
+					widest, span = i, s
+				}
+			}
+			if widest < 0 {
+				break
+			}
+			a, b := boxes[widest].split()
+			boxes[widest] = a
+			boxes = append(boxes, b)
+		}
+
+		for _, box := range boxes {
+			palette = append(palette, box.average())
+		}
+	}
+
+	paletted := image.NewPaletted(bounds, palette)
+	indices := make(map[color.NRGBA]uint8)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := nrgba.NRGBAAt(x, y)
+			if c.A == 0 {
+				c = color.NRGBA{}
+			}
+			index, ok := indices[c]
+			if !ok {
+				index = uint8(palette.Index(c))
+				indices[c] = index
+			}
+			paletted.SetColorIndex(x, y, index)
+		}
+	}
+
+	return paletted
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// findProcessed locates a frame an external tool rewrote, at the path the
+// extraction wrote it to or at the same name in another sprite format,
+// since tools often save as png whatever they were given.
+func findProcessed(dir, rel string) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	stem := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, format := range spriteFormats {
+		encoder, err := newSpriteEncoder(SpriteEncoding{Format: format, Speed: 6, PNGCompression: "default"})
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(stem + encoder.Extension()); err == nil {
+			return stem + encoder.Extension(), nil
+		}
+	}
+	return "", fmt.Errorf("missing processed frame %s in %s", rel, dir)
+}
+
+// processedFrames finds every frame of an extraction where an external tool
+// wrote it back under dir, as sprites to repack, and checks they were all
+// scaled by the same factor, which it returns. Aliases written once share a
+// file.
+func processedFrames(manifest Manifest, dir string) ([]PackSprite, float64, error) {
+	var sprites []PackSprite
+	var factor float64
+
+	for _, name := range slices.Sorted(maps.Keys(manifest.Frames)) {
+		frame := manifest.Frames[name]
+		path, err := findProcessed(dir, frame.Path)
+		if err != nil {
+			return nil, 0, err
+		}
+		img, err := decodeImageFile(path)
+		if err != nil {
+			return nil, 0, err
+		}
+		bounds := img.Bounds()
+
+		if frame.Width > 0 && frame.Height > 0 {
+			if factor == 0 {
+				factor = float64(bounds.Dx()) / float64(frame.Width)
+			}
+			expected := image.Pt(int(math.Round(float64(frame.Width)*factor)), int(math.Round(float64(frame.Height)*factor)))
+			if bounds.Size() != expected {
+				return nil, 0, fmt.Errorf("processed frame %s is %dx%d, expected %dx%d at %gx like the others", name, bounds.Dx(), bounds.Dy(), expected.X, expected.Y, factor)
+			}
+		}
+
+		size := bounds.Size()
+		sprites = append(sprites, PackSprite{
+			Name:       name,
+			Path:       path,
+			Width:      size.X,
+			Height:     size.Y,
+			Bounds:     image.Rectangle{Max: size},
+			SourceSize: Size{Width: size.X, Height: size.Y},
+		})
+	}
+
+	if len(sprites) == 0 {
+		return nil, 0, fmt.Errorf("manifest lists no frames")
+	}
+	return sprites, factor, nil
+}
+
+// reassembleFrames rebuilds the extraction's tree under outputDir from the
+// processed frames, keeping their format, with a manifest updated to their
+// new sizes and hashes.
+func reassembleFrames(manifest Manifest, sprites []PackSprite, outputDir string) error {
+	for _, sprite := range sprites {
+		frame := manifest.Frames[sprite.Name]
+		rel := strings.TrimSuffix(frame.Path, filepath.Ext(frame.Path)) + filepath.Ext(sprite.Path)
+		outputPath := filepath.Join(outputDir, filepath.FromSlash(rel))
+
+		if filepath.Clean(outputPath) != filepath.Clean(sprite.Path) {
+			if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			if _, err := copyFile(sprite.Path, outputPath); err != nil {
+				return err
+			}
+		}
+
+		sum, err := hashFile(outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to hash %s: %w", outputPath, err)
+		}
+		frame.Path, frame.Width, frame.Height, frame.SHA256 = rel, sprite.Width, sprite.Height, sum
+		manifest.Frames[sprite.Name] = frame
+	}
+
+	writer := &ManifestWriter{Path: filepath.Join(outputDir, "manifest.json"), manifest: manifest}
+	return writer.close(true)
+}
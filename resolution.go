@@ -0,0 +1,75 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// resolutionSuffix matches the @2x or @0.5x a multi-resolution export adds
+// before the extension.
+var resolutionSuffix = regexp.MustCompile(`@(\d+(?:\.\d+)?)x$`)
+
+type ResolutionVariant struct {
+	Path       string
+	Resolution float64
+}
+
+// suffix is the tag a variant's output folder gets, empty at 1x so
+// single-resolution packs extract where they always have.
+func (variant ResolutionVariant) suffix() string {
+	if variant.Resolution == 1 {
+		return ""
+	}
+	return "@" + strconv.FormatFloat(variant.Resolution, 'f', -1, 64) + "x"
+}
+
+func parseResolution(s string) (float64, error) {
+	resolution, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimPrefix(s, "@"), "x"), 64)
+	if err != nil || resolution <= 0 {
+		return 0, fmt.Errorf("invalid resolution %q, expected a factor like 2x or 0.5x", s)
+	}
+	return resolution, nil
+}
+
+// findResolutionVariants lists the atlas at path with its siblings at other
+// resolutions, like sheet.json, sheet@2x.json, and sheet@0.5x.json, from
+// lowest resolution to highest.
+func findResolutionVariants(path string) ([]ResolutionVariant, error) {
+	dir, ext := filepath.Dir(path), filepath.Ext(path)
+	stem := resolutionSuffix.ReplaceAllString(strings.TrimSuffix(filepath.Base(path), ext), "")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find resolution variants: %w", err)
+	}
+
+	var variants []ResolutionVariant
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ext)
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		resolution := 1.0
+		if match := resolutionSuffix.FindStringSubmatch(name); match != nil {
+			name = strings.TrimSuffix(name, match[0])
+			if resolution, err = strconv.ParseFloat(match[1], 64); err != nil || resolution <= 0 {
+				continue
+			}
+		}
+		if name == stem {
+			variants = append(variants, ResolutionVariant{Path: filepath.Join(dir, entry.Name()), Resolution: resolution})
+		}
+	}
+
+	slices.SortFunc(variants, func(a, b ResolutionVariant) int {
+		return cmp.Compare(a.Resolution, b.Resolution)
+	})
+	return variants, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const resumeStateFile = ".txunpak-resume.json"
+
+type resumeHeader struct {
+	Atlas  string `json:"atlas"`
+	SHA256 string `json:"sha256"`
+}
+
+type resumeEntry struct {
+	Frame string `json:"frame"`
+	Path  string `json:"path"`
+}
+
+// A ResumeState logs every frame a run finishes, one JSON line each, so a
+// cancelled or crashed run over a huge pack picks up where it stopped. Lines
+// are appended as frames finish, and the file is removed once the run
+// completes. The first line ties the log to the atlas it was made from.
+type ResumeState struct {
+	Path string
+	mu   sync.Mutex
+	file *os.File
+	done map[string]string
+}
+
+// openResumeState loads the log an earlier run of the same atlas left in dir,
+// or starts a new one when there is none or the atlas has changed since.
+func openResumeState(dir, atlasPath string) (*ResumeState, error) {
+	sum, err := hashFile(atlasPath)
+	if err != nil {
+		return nil, fmt.Errorf(tr("failed to hash %s: %w"), atlasPath, err)
+	}
+	header := resumeHeader{Atlas: filepath.ToSlash(atlasPath), SHA256: sum}
+	state := &ResumeState{Path: filepath.Join(dir, resumeStateFile), done: make(map[string]string)}
+
+	if file, err := os.Open(state.Path); err == nil {
+		scanner := bufio.NewScanner(file)
+		var previous resumeHeader
+		if scanner.Scan() && json.Unmarshal(scanner.Bytes(), &previous) == nil && previous == header {
+			// A crash can cut the last line short, so reading stops at the first bad one.
+			for scanner.Scan() {
+				var entry resumeEntry
+				if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+					break
+				}
+				state.done[entry.Frame] = entry.Path
+			}
+		} else {
+			logf(tr("[warn] %s is from another atlas or version, starting over\n"), state.Path)
+		}
+		file.Close()
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, withExitCode(exitOutput, fmt.Errorf(tr("failed to create output directory: %w"), err))
+	}
+
+	// The log is rewritten with only the entries that were read, dropping a
+	// torn last line.
+	file, err := os.Create(state.Path)
+	if err != nil {
+		return nil, withExitCode(exitOutput, fmt.Errorf(tr("failed to open resume state: %w"), err))
+	}
+	state.file = file
+	if err := state.append(header); err != nil {
+		return nil, err
+	}
+	for frame, path := range state.done {
+		if err := state.append(resumeEntry{Frame: frame, Path: path}); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(state.done) > 0 {
+		logf(tr("[info] resuming, %d frames already done\n"), len(state.done))
+	}
+	return state, nil
+}
+
+func (state *ResumeState) append(line any) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return fmt.Errorf(tr("failed to encode resume state: %w"), err)
+	}
+	if _, err := state.file.Write(append(data, '\n')); err != nil {
+		return withExitCode(exitOutput, fmt.Errorf(tr("failed to write resume state: %w"), err))
+	}
+	return nil
+}
+
+// completed reports whether an earlier run finished the frame at path, and
+// the file is still there.
+func (state *ResumeState) completed(name, path string) bool {
+	if state == nil {
+		return false
+	}
+	state.mu.Lock()
+	done, ok := state.done[name]
+	state.mu.Unlock()
+	if !ok || done != path {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (state *ResumeState) record(name, path string) error {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.done[name] = path
+	return state.append(resumeEntry{Frame: name, Path: path})
+}
+
+// close keeps the log for the next run unless the run completed, when there
+// is nothing left to resume.
+func (state *ResumeState) close(complete bool) error {
+	if err := state.file.Close(); err != nil {
+		return withExitCode(exitOutput, fmt.Errorf(tr("failed to write resume state: %w"), err))
+	}
+	if complete {
+		return os.Remove(state.Path)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"path/filepath"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+type RoundTripResult struct {
+	Frame  string
+	Diff   int
+	Reason string
+}
+
+// diffImages counts differing pixels, treating every fully transparent pixel
+// as equal since PNG encoders may drop their color.
+func diffImages(a, b image.Image) (int, string) {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return 0, fmt.Sprintf("size %v != %v", a.Bounds().Size(), b.Bounds().Size())
+	}
+
+	diff := 0
+	size := a.Bounds().Size()
+	for y := range size.Y {
+		for x := range size.X {
+			pa := color.NRGBAModel.Convert(a.At(a.Bounds().Min.X+x, a.Bounds().Min.Y+y)).(color.NRGBA)
+			pb := color.NRGBAModel.Convert(b.At(b.Bounds().Min.X+x, b.Bounds().Min.Y+y)).(color.NRGBA)
+			if pa != pb && (pa.A != 0 || pb.A != 0) {
+				diff++
+			}
+		}
+	}
+
+	if diff > 0 {
+		return diff, fmt.Sprintf("%d pixels differ", diff)
+	}
+	return 0, ""
+}
+
+// roundTripSettings mirrors the source pack: pages as large as its largest
+// sheet, trimmed when any frame was trimmed.
+func roundTripSettings(pack Pack) (Size, bool) {
+	maxSize := Size{Width: 1, Height: 1}
+	trim := false
+
+	for _, sh := range pack.Sheets {
+		maxSize.Width = max(maxSize.Width, sh.Size.Width)
+		maxSize.Height = max(maxSize.Height, sh.Size.Height)
+		for _, tex := range sh.Textures {
+			maxSize.Width = max(maxSize.Width, tex.SourceSize.Width)
+			maxSize.Height = max(maxSize.Height, tex.SourceSize.Height)
+			trim = trim || tex.Trimmed
+		}
+	}
+
+	return maxSize, trim
+}
+
+func roundTrip(pack Pack, inputDir string, ws *Workspace, algorithm PackAlgorithm, padding int) ([]RoundTripResult, error) {
+	framesDir := ws.path("frames")
+	repackedPath := ws.path(filepath.Join("repacked", "atlas.json"))
+	reunpackedDir := ws.path("reunpacked")
+
+	workers := runtime.NumCPU()
+	first := Unpacker{Pack: pack, InputDir: inputDir, OutputDir: framesDir, Workers: workers}
+	if err := first.unpack(true); err != nil {
+		return nil, err
+	}
+	if err := ws.checkSize(); err != nil {
+		return nil, err
+	}
+
+	maxSize, trim := roundTripSettings(pack)
+	sprites, err := collectPackSprites(framesDir, trim)
+	if err != nil {
+		return nil, err
+	}
+
+	layout, err := packSprites(sprites, algorithm, PackOptions{MaxSize: maxSize, Padding: padding})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := writePackedAtlas(layout, repackedPath); err != nil {
+		return nil, err
+	}
+	if err := ws.checkSize(); err != nil {
+		return nil, err
+	}
+
+	repacked, err := loadPack(repackedPath, "multiatlas")
+	if err != nil {
+		return nil, err
+	}
+
+	second := Unpacker{Pack: repacked, InputDir: filepath.Dir(repackedPath), OutputDir: reunpackedDir, Workers: workers}
+	if err := second.unpack(true); err != nil {
+		return nil, err
+	}
+
+	var results []RoundTripResult
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			result := RoundTripResult{Frame: tex.FileName}
+
+			before, err := decodeImageFile(first.outputPath(tex))
+			if err != nil {
+				return nil, err
+			}
+
+			after, err := decodeImageFile(second.outputPath(tex))
+			if err != nil {
+				result.Reason = "missing after repack"
+			} else {
+				result.Diff, result.Reason = diffImages(before, after)
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+func newRoundTripCmd() *cobra.Command {
+	var algorithmName string = packAlgorithms[0].Name
+	var padding int = 2
+	var keep bool = false
+
+	var roundTripCmd = &cobra.Command{
+		Use:   "roundtrip <path>",
+		Short: "Unpack, repack, and re-unpack an atlas, checking every frame survives pixel for pixel",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var path = args[0]
+			format, _ := cmd.Flags().GetString("format")
+
+			algorithm, err := findPackAlgorithm(algorithmName)
+			if err != nil {
+				return err
+			}
+
+			pack, err := loadPack(path, format)
+			if err != nil {
+				return err
+			}
+
+			ws, err := openWorkspace("roundtrip")
+			if err != nil {
+				return err
+			}
+			if keep {
+				ws.keep()
+				logf("[info] keeping work directory %s\n", ws.Dir)
+			}
+			defer ws.Close()
+
+			results, err := roundTrip(pack, filepath.Dir(path), ws, algorithm, padding)
+			if err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, result := range results {
+				if result.Reason != "" {
+					failed++
+					fmt.Printf("%s  %s: %s\n", paint(colorRed, "FAIL"), result.Frame, result.Reason)
+				}
+			}
+
+			logf("[info] %d of %d frames survived the round trip\n", len(results)-failed, len(results))
+			if failed > 0 {
+				return fmt.Errorf("round trip failed for %d frames", failed)
+			}
+
+			fmt.Println(paint(colorGreen, "PASS"))
+			return nil
+		},
+	}
+
+	roundTripCmd.Flags().StringVarP(&algorithmName, "algorithm", "a", algorithmName, "Packing heuristic to repack with")
+	roundTripCmd.Flags().IntVarP(&padding, "padding", "p", padding, "Pixels between repacked sprites")
+	roundTripCmd.Flags().BoolVarP(&keep, "keep", "", keep, "Keep the work directory for inspection")
+
+	return roundTripCmd
+}
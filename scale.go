@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"slices"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+var scaleFilters = map[string]draw.Interpolator{
+	"nearest":     draw.NearestNeighbor,
+	"bilinear":    draw.BiLinear,
+	"catmull-rom": draw.CatmullRom,
+}
+
+func checkScaleFilter(filter string) error {
+	if _, ok := scaleFilters[filter]; !ok {
+		names := make([]string, 0, len(scaleFilters))
+		for name := range scaleFilters {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return fmt.Errorf("invalid filter %q, expected %s", filter, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// newSpriteLike allocates a canvas in the same pixel format as sprite, so
+// scaling keeps the depth extraction preserved.
+func newSpriteLike(sprite image.Image, rect image.Rectangle) draw.Image {
+	switch sprite.(type) {
+	case *image.Gray:
+		return image.NewGray(rect)
+	case *image.Gray16:
+		return image.NewGray16(rect)
+	case *image.NRGBA64:
+		return image.NewNRGBA64(rect)
+	case *FloatImage:
+		return NewFloatImage(rect)
+	}
+	return newPooledNRGBA(rect)
+}
+
+type floatTap struct {
+	index  int
+	weight float32
+}
+
+// floatTaps weighs the source pixels behind each of n destination pixels
+// resampled from srcN, widening the kernel when shrinking as x/image does.
+func floatTaps(n, srcN int, kernel *draw.Kernel) [][]floatTap {
+	scale := float64(srcN) / float64(n)
+	width := max(scale, 1)
+	support := kernel.Support * width
+
+	taps := make([][]floatTap, n)
+	for i := range taps {
+		center := (float64(i)+0.5)*scale - 0.5
+		var sum float32
+		for s := int(math.Ceil(center - support)); s <= int(math.Floor(center+support)); s++ {
+			t := math.Abs(float64(s)-center) / width
+			if t >= kernel.Support {
+				continue
+			}
+			weight := float32(kernel.At(t))
+			taps[i] = append(taps[i], floatTap{min(max(s, 0), srcN-1), weight})
+			sum += weight
+		}
+		if sum != 0 {
+			for j := range taps[i] {
+				taps[i][j].weight /= sum
+			}
+		}
+	}
+	return taps
+}
+
+// scaleFloat resizes src onto dst in float, since the x/image scalers read
+// pixels through color.Color and would clamp values past 1.
+func scaleFloat(dst, src *FloatImage, filter string) {
+	sb, db := src.Bounds(), dst.Bounds()
+	kernel, ok := scaleFilters[filter].(*draw.Kernel)
+	if !ok {
+		for y := range db.Dy() {
+			sy := sb.Min.Y + (2*y+1)*sb.Dy()/(2*db.Dy())
+			for x := range db.Dx() {
+				sx := sb.Min.X + (2*x+1)*sb.Dx()/(2*db.Dx())
+				dst.SetFloat(db.Min.X+x, db.Min.Y+y, src.FloatAt(sx, sy))
+			}
+		}
+		return
+	}
+
+	// Resample rows first, then columns of the result.
+	columns, rows := floatTaps(db.Dx(), sb.Dx(), kernel), floatTaps(db.Dy(), sb.Dy(), kernel)
+	wide := NewFloatImage(image.Rect(0, 0, db.Dx(), sb.Dy()))
+	for y := range sb.Dy() {
+		for x, taps := range columns {
+			var c FloatColor
+			for _, tap := range taps {
+				s := src.FloatAt(sb.Min.X+tap.index, sb.Min.Y+y)
+				c.R, c.G, c.B, c.A = c.R+s.R*tap.weight, c.G+s.G*tap.weight, c.B+s.B*tap.weight, c.A+s.A*tap.weight
+			}
+			wide.SetFloat(x, y, c)
+		}
+	}
+	for y, taps := range rows {
+		for x := range db.Dx() {
+			var c FloatColor
+			for _, tap := range taps {
+				s := wide.FloatAt(x, tap.index)
+				c.R, c.G, c.B, c.A = c.R+s.R*tap.weight, c.G+s.G*tap.weight, c.B+s.B*tap.weight, c.A+s.A*tap.weight
+			}
+			dst.SetFloat(db.Min.X+x, db.Min.Y+y, c)
+		}
+	}
+}
+
+// scaleSprite resizes sprite by factor, rounding to whole pixels but never
+// below one.
+func scaleSprite(sprite image.Image, factor float64, filter string) image.Image {
+	bounds := sprite.Bounds()
+	width := max(1, int(math.Round(float64(bounds.Dx())*factor)))
+	height := max(1, int(math.Round(float64(bounds.Dy())*factor)))
+
+	scaled := newSpriteLike(sprite, image.Rect(0, 0, width, height))
+	if float, ok := scaled.(*FloatImage); ok {
+		scaleFloat(float, sprite.(*FloatImage), filter)
+		return scaled
+	}
+	scaleFilters[filter].Scale(scaled, scaled.Bounds(), sprite, bounds, draw.Src, nil)
+	return scaled
+}
+
+// spriteScale combines the requested scale with undoing the sheet's own, so
+// a sheet exported at 0.5 extracts at its original resolution.
+func (unpacker Unpacker) spriteScale(sheet Sheet) float64 {
+	factor := unpacker.Scale
+	if factor == 0 {
+		factor = 1
+	}
+	if sheet.Scale > 0 {
+		factor /= sheet.Scale
+	}
+	return factor
+}
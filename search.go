@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+type FrameQuery struct {
+	MinSize   Size
+	MaxSize   Size
+	Aspect    float64
+	AspectTol float64
+	Color     *color.NRGBA
+	ColorTol  float64
+}
+
+type SearchMatch struct {
+	Frame    string `json:"frame"`
+	Sheet    string `json:"sheet"`
+	Width    int    `json:"w"`
+	Height   int    `json:"h"`
+	Dominant string `json:"dominant,omitempty"`
+}
+
+// splitTolerance splits "value~tolerance", falling back when no tolerance is given.
+func splitTolerance(s string, fallback float64) (string, float64, error) {
+	value, tol, ok := strings.Cut(s, "~")
+	if !ok {
+		return value, fallback, nil
+	}
+	tolerance, err := strconv.ParseFloat(tol, 64)
+	if err != nil || tolerance < 0 {
+		return "", 0, fmt.Errorf("invalid tolerance %q in %q", tol, s)
+	}
+	return value, tolerance, nil
+}
+
+// parseAspect reads W:H with an optional percent tolerance, like 16:9~5.
+func parseAspect(s string) (float64, float64, error) {
+	value, tolerance, err := splitTolerance(s, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var w, h float64
+	if _, err := fmt.Sscanf(value, "%g:%g", &w, &h); err != nil || w <= 0 || h <= 0 {
+		return 0, 0, fmt.Errorf("invalid aspect %q, expected W:H", s)
+	}
+	return w / h, tolerance / 100, nil
+}
+
+func newFrameQuery(minSize, maxSize, aspect, dominant string) (FrameQuery, error) {
+	var query FrameQuery
+	var err error
+
+	if minSize != "" {
+		if query.MinSize, err = parseSize(minSize); err != nil {
+			return query, err
+		}
+	}
+	if maxSize != "" {
+		if query.MaxSize, err = parseSize(maxSize); err != nil {
+			return query, err
+		}
+	}
+	if aspect != "" {
+		if query.Aspect, query.AspectTol, err = parseAspect(aspect); err != nil {
+			return query, err
+		}
+	}
+	if dominant != "" {
+		value, tolerance, err := splitTolerance(dominant, 32)
+		if err != nil {
+			return query, err
+		}
+		c, err := parseColor(value)
+		if err != nil {
+			return query, err
+		}
+		query.Color, query.ColorTol = &c, tolerance
+	}
+
+	return query, nil
+}
+
+// matchSize checks the frame's logical size, the one it is extracted at.
+func (query FrameQuery) matchSize(size Size) bool {
+	if size.Width < query.MinSize.Width || size.Height < query.MinSize.Height {
+		return false
+	}
+	if query.MaxSize.Width > 0 && (size.Width > query.MaxSize.Width || size.Height > query.MaxSize.Height) {
+		return false
+	}
+	if query.Aspect > 0 {
+		ratio := float64(size.Width) / float64(size.Height)
+		if math.Abs(ratio-query.Aspect) > query.Aspect*query.AspectTol {
+			return false
+		}
+	}
+	return true
+}
+
+// dominantColor buckets the visible pixels by their top four bits per
+// channel and averages the fullest bucket, so antialiased edges and noise do
+// not pull the result toward gray. Frames with no visible pixels have none.
+func dominantColor(img *image.NRGBA) (color.NRGBA, bool) {
+	var counts [4096]int
+	var sums [4096][3]int
+
+	for i := 0; i < len(img.Pix); i += 4 {
+		px := img.Pix[i : i+4 : i+4]
+		if px[3] < 128 {
+			continue
+		}
+		bucket := int(px[0]>>4)<<8 | int(px[1]>>4)<<4 | int(px[2]>>4)
+		counts[bucket]++
+		sums[bucket][0] += int(px[0])
+		sums[bucket][1] += int(px[1])
+		sums[bucket][2] += int(px[2])
+	}
+
+	best := 0
+	for bucket, count := range counts {
+		if count > counts[best] {
+			best = bucket
+		}
+	}
+	if counts[best] == 0 {
+		return color.NRGBA{}, false
+	}
+
+	n := counts[best]
+	return color.NRGBA{uint8(sums[best][0] / n), uint8(sums[best][1] / n), uint8(sums[best][2] / n), 255}, true
+}
+
+func colorDistance(a, b color.NRGBA) float64 {
+	dr, dg, db := float64(a.R)-float64(b.R), float64(a.G)-float64(b.G), float64(a.B)-float64(b.B)
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
+
+func hexColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// searchFrames checks the cheap geometric predicates first, so sheets are
+// only decoded when a color is asked for.
+func searchFrames(pack Pack, inputDir string, query FrameQuery) ([]SearchMatch, error) {
+	composer := newComposer(pack, inputDir)
+	var matches []SearchMatch
+
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			if !query.matchSize(tex.SourceSize) {
+				continue
+			}
+
+			match := SearchMatch{Frame: tex.FileName, Sheet: sh.Image, Width: tex.SourceSize.Width, Height: tex.SourceSize.Height}
+
+			if query.Color != nil {
+				sprite, err := composer.sprite(tex.FileName)
+				if err != nil {
+					return nil, err
+				}
+				dominant, ok := dominantColor(sprite)
+				if !ok || colorDistance(dominant, *query.Color) > query.ColorTol {
+					continue
+				}
+				match.Dominant = hexColor(dominant)
+			}
+
+			matches = append(matches, match)
+		}
+	}
+
+	return matches, nil
+}
+
+func newSearchCmd() *cobra.Command {
+	var minSize, maxSize, aspect, dominant string
+	var extractDir string
+	var asJSON bool = false
+
+	var searchCmd = &cobra.Command{
+		Use:   "search <atlas>",
+		Short: "Find frames by size, aspect ratio, or dominant color",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var path = args[0]
+			format, _ := cmd.Flags().GetString("format")
+
+			query, err := newFrameQuery(minSize, maxSize, aspect, dominant)
+			if err != nil {
+				return err
+			}
+
+			pack, err := loadPack(path, format)
+			if err != nil {
+				return err
+			}
+
+			matches, err := searchFrames(pack, filepath.Dir(path), query)
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(matches); err != nil {
+					return err
+				}
+			} else {
+				writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(writer, "FRAME\tSHEET\tSIZE\tDOMINANT")
+				for _, match := range matches {
+					fmt.Fprintf(writer, "%s\t%s\t%dx%d\t%s\n", match.Frame, match.Sheet, match.Width, match.Height, match.Dominant)
+				}
+				writer.Flush()
+			}
+
+			if extractDir == "" || len(matches) == 0 {
+				return nil
+			}
+
+			names := make(map[string]bool, len(matches))
+			for _, match := range matches {
+				names[match.Frame] = true
+			}
+
+			unpacker := Unpacker{
+				Pack:      FrameFilter{Names: names}.apply(pack, LocaleIndex{}),
+				InputDir:  filepath.Dir(path),
+				OutputDir: extractDir,
+				Workers:   runtime.NumCPU(),
+				Summary:   "none",
+			}
+			return unpacker.unpack(true)
+		},
+	}
+
+	searchCmd.Flags().StringVarP(&minSize, "min-size", "", "", "Only frames at least this size (WxH)")
+	searchCmd.Flags().StringVarP(&maxSize, "max-size", "", "", "Only frames at most this size (WxH)")
+	searchCmd.Flags().StringVarP(&aspect, "aspect", "", "", "Only frames of this aspect ratio (W:H, optionally ~percent tolerance)")
+	searchCmd.Flags().StringVarP(&dominant, "dominant-color", "", "", "Only frames whose dominant color is near this one (#rrggbb, optionally ~distance)")
+	searchCmd.Flags().StringVarP(&extractDir, "extract", "", "", "Extract the matching frames to this directory")
+	searchCmd.Flags().BoolVarP(&asJSON, "json", "", asJSON, "Print matches as JSON")
+
+	return searchCmd
+}
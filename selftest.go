@@ -0,0 +1,180 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// selftestFixtures are small atlases over the same two pages, one per
+// supported format, covering trimmed, rotated, and multi-page frames.
+// expected.json holds the pixel hash of every frame they extract to.
+//
+//go:embed selftest
+var selftestFixtures embed.FS
+
+type selftestCase struct {
+	Fixture string
+	Format  string
+}
+
+// selftestCases extract every fixture as PNG, and the multi-page one once
+// more in each other lossless format that can be decoded back.
+var selftestCases = []selftestCase{
+	{"multiatlas.json", "png"},
+	{"hash.json", "png"},
+	{"array.json", "png"},
+	{"atlas.xml", "png"},
+	{"cocos.plist", "png"},
+	{"spine.atlas", "png"},
+	{"page0.png.meta", "png"},
+	{"multiatlas.json", "webp"},
+	{"multiatlas.json", "qoi"},
+}
+
+type SelftestResult struct {
+	selftestCase
+	Frames   int
+	Failures []string
+}
+
+// selftest extracts every case into the workspace and compares the decoded
+// frames with the expected hashes.
+func selftest(ws *Workspace) ([]SelftestResult, error) {
+	fixtures, err := fs.Sub(selftestFixtures, "selftest")
+	if err != nil {
+		return nil, err
+	}
+	data, err := fs.ReadFile(fixtures, "expected.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read expected hashes: %w", err)
+	}
+	var expected map[string]map[string]string
+	if err := json.Unmarshal(data, &expected); err != nil {
+		return nil, fmt.Errorf("invalid expected hashes JSON: %w", err)
+	}
+
+	inputDir := ws.path("fixtures")
+	if err := os.CopyFS(inputDir, fixtures); err != nil {
+		return nil, fmt.Errorf("failed to write fixtures: %w", err)
+	}
+
+	var results []SelftestResult
+	for _, test := range selftestCases {
+		result := SelftestResult{selftestCase: test}
+
+		pack, err := loadPack(filepath.Join(inputDir, test.Fixture), "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to load fixture %s: %w", test.Fixture, err)
+		}
+		encoder, err := newSpriteEncoder(SpriteEncoding{Format: test.Format, PNGCompression: "default"})
+		if err != nil {
+			return nil, err
+		}
+
+		unpacker := Unpacker{
+			Pack:      pack,
+			InputDir:  inputDir,
+			OutputDir: ws.path(filepath.Join("out", test.Fixture, test.Format)),
+			Workers:   runtime.NumCPU(),
+			Encoder:   encoder,
+			Summary:   "none",
+		}
+		if err := unpacker.unpack(true); err != nil {
+			return nil, fmt.Errorf("failed to extract fixture %s as %s: %w", test.Fixture, test.Format, err)
+		}
+
+		want := expected[test.Fixture]
+		seen := make(map[string]bool)
+		for _, sh := range pack.Sheets {
+			for _, tex := range sh.Textures {
+				result.Frames++
+				seen[tex.FileName] = true
+
+				img, err := decodeImageFile(unpacker.outputPath(tex))
+				if err != nil {
+					result.Failures = append(result.Failures, fmt.Sprintf("%s: %v", tex.FileName, err))
+					continue
+				}
+				if hash, ok := want[tex.FileName]; !ok {
+					result.Failures = append(result.Failures, fmt.Sprintf("%s: unexpected frame", tex.FileName))
+				} else if pixelHash(img) != hash {
+					result.Failures = append(result.Failures, fmt.Sprintf("%s: pixels differ", tex.FileName))
+				}
+			}
+		}
+		for name := range want {
+			if !seen[name] {
+				result.Failures = append(result.Failures, fmt.Sprintf("%s: missing", name))
+			}
+		}
+		slices.Sort(result.Failures)
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func newSelftestCmd() *cobra.Command {
+	var keep bool = false
+
+	var selftestCmd = &cobra.Command{
+		Use:   "selftest",
+		Short: "Extract embedded fixture atlases and check every frame, to confirm this build works on this platform",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := openWorkspace("selftest")
+			if err != nil {
+				return err
+			}
+			if keep {
+				ws.keep()
+				logf("[info] keeping work directory %s\n", ws.Dir)
+			}
+			defer ws.Close()
+
+			results, err := selftest(ws)
+			if err != nil {
+				return err
+			}
+
+			failed := 0
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "FIXTURE\tFORMAT\tFRAMES\tSTATUS")
+			for _, result := range results {
+				status := "ok"
+				if len(result.Failures) > 0 {
+					failed++
+					status = "FAIL"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", result.Fixture, result.Format, result.Frames, status)
+			}
+			w.Flush()
+
+			for _, result := range results {
+				for _, failure := range result.Failures {
+					fmt.Printf("%s  %s as %s, %s\n", paint(colorRed, "FAIL"), result.Fixture, result.Format, failure)
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("selftest failed for %d of %d fixtures", failed, len(results))
+			}
+			fmt.Println(paint(colorGreen, "PASS"))
+			return nil
+		},
+	}
+
+	selftestCmd.Flags().BoolVarP(&keep, "keep", "", keep, "Keep the work directory for inspection")
+
+	return selftestCmd
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// packExtrude reads the extrusion an exporter recorded in the atlas meta, if any.
+func packExtrude(pack Pack) int {
+	extrude, err := strconv.Atoi(pack.Meta["extrude"])
+	if err != nil || extrude < 0 {
+		return 0
+	}
+	return extrude
+}
+
+// shrinkPack contracts every frame by n pixels on each side, dropping the
+// edge pixels a packer duplicated into the border with extrusion. The
+// declared sizes include the bleed, so the canvas shrinks with the frame.
+func shrinkPack(pack Pack, n int) (Pack, error) {
+	sheets := make([]Sheet, len(pack.Sheets))
+	for i, sh := range pack.Sheets {
+		textures := make([]Texture, len(sh.Textures))
+		for j, tex := range sh.Textures {
+			if tex.Frame.Width <= 2*n || tex.Frame.Height <= 2*n {
+				return Pack{}, fmt.Errorf("frame %q (%dx%d) is too small to shrink by %d", tex.FileName, tex.Frame.Width, tex.Frame.Height, n)
+			}
+
+			tex.Frame = Frame{X: tex.Frame.X + n, Y: tex.Frame.Y + n, Width: tex.Frame.Width - 2*n, Height: tex.Frame.Height - 2*n}
+			tex.SpriteSourceSize.Width -= 2 * n
+			tex.SpriteSourceSize.Height -= 2 * n
+			tex.SourceSize = Size{Width: tex.SourceSize.Width - 2*n, Height: tex.SourceSize.Height - 2*n}
+			textures[j] = tex
+		}
+		sh.Textures = textures
+		sheets[i] = sh
+	}
+
+	pack.Sheets = sheets
+	return pack, nil
+}
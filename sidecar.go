@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var sidecarModes = []string{"none", "json"}
+
+// SpriteSidecar describes where a sprite came from, so tools can place it
+// again without parsing the atlas.
+type SpriteSidecar struct {
+	Frame            string `json:"frame"`
+	Sheet            string `json:"sheet"`
+	Rect             Frame  `json:"rect"`
+	Rotated          bool   `json:"rotated"`
+	Trimmed          bool   `json:"trimmed"`
+	SpriteSourceSize Frame  `json:"spriteSourceSize"`
+	SourceSize       Size   `json:"sourceSize"`
+	Pivot            *Pivot `json:"pivot,omitempty"`
+}
+
+// writeSidecar writes the frame's sidecar next to its sprite, named like it
+// with a .json extension, returning the bytes written.
+func (unpacker Unpacker) writeSidecar(sheet Sheet, tex Texture) (int64, error) {
+	outputPath := unpacker.outputPath(tex)
+	sidecarPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".json"
+
+	data, err := json.MarshalIndent(SpriteSidecar{
+		Frame:            tex.FileName,
+		Sheet:            sheet.Image,
+		Rect:             tex.Frame,
+		Rotated:          tex.Rotated,
+		Trimmed:          tex.Trimmed,
+		SpriteSourceSize: tex.SpriteSourceSize,
+		SourceSize:       tex.SourceSize,
+		Pivot:            tex.Pivot,
+	}, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode sidecar: %w", err)
+	}
+	data = append(data, '\n')
+
+	if err := unpacker.checkOutput(sidecarPath); err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(sidecarPath, data, 0o644); err != nil {
+		return 0, withExitCode(exitOutput, fmt.Errorf("failed to write sidecar: %w", err))
+	}
+	return int64(len(data)), nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type spineRegion struct {
+	Name    string
+	Index   int
+	Texture Texture
+}
+
+var spineIndexSuffix = regexp.MustCompile(`^(.+?)[_-]?(\d+)$`)
+
+func parseSpineInts(value string) ([]int, error) {
+	fields := strings.Split(value, ",")
+	ints := make([]int, 0, len(fields))
+
+	for _, field := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid atlas value %q", value)
+		}
+		ints = append(ints, n)
+	}
+
+	return ints, nil
+}
+
+func applySpineRegionField(region *spineRegion, key, value string) error {
+	tex := &region.Texture
+
+	switch key {
+	case "rotate":
+		tex.Rotated = value == "true" || value == "90" || value == "270"
+		return nil
+	case "index":
+		index, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid atlas index %q", value)
+		}
+		region.Index = index
+		return nil
+	case "xy", "size", "orig", "offset", "bounds", "offsets":
+	default:
+		return nil
+	}
+
+	ints, err := parseSpineInts(value)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case key == "xy" && len(ints) == 2:
+		tex.Frame.X, tex.Frame.Y = ints[0], ints[1]
+	case key == "size" && len(ints) == 2:
+		tex.Frame.Width, tex.Frame.Height = ints[0], ints[1]
+	case key == "orig" && len(ints) == 2:
+		tex.SourceSize = Size{Width: ints[0], Height: ints[1]}
+	case key == "offset" && len(ints) == 2:
+		tex.SpriteSourceSize.X, tex.SpriteSourceSize.Y = ints[0], ints[1]
+	case key == "bounds" && len(ints) == 4:
+		tex.Frame = Frame{X: ints[0], Y: ints[1], Width: ints[2], Height: ints[3]}
+	case key == "offsets" && len(ints) == 4:
+		tex.SpriteSourceSize.X, tex.SpriteSourceSize.Y = ints[0], ints[1]
+		tex.SourceSize = Size{Width: ints[2], Height: ints[3]}
+	default:
+		return fmt.Errorf("invalid atlas %s %q", key, value)
+	}
+
+	return nil
+}
+
+// Spine offsets are measured from the bottom-left of the original image,
+// while SpriteSourceSize is measured from the top-left.
+func (region spineRegion) texture() Texture {
+	tex := region.Texture
+	tex.FileName = region.Name
+
+	if tex.SourceSize.Width == 0 && tex.SourceSize.Height == 0 {
+		tex.SourceSize = Size{Width: tex.Frame.Width, Height: tex.Frame.Height}
+	}
+
+	tex.SpriteSourceSize.Y = tex.SourceSize.Height - tex.Frame.Height - tex.SpriteSourceSize.Y
+	tex.SpriteSourceSize.Width = tex.Frame.Width
+	tex.SpriteSourceSize.Height = tex.Frame.Height
+	tex.Trimmed = tex.SourceSize.Width != tex.Frame.Width || tex.SourceSize.Height != tex.Frame.Height
+
+	return tex
+}
+
+func parseSpineAtlas(data []byte) (Pack, error) {
+	var pack Pack
+	var sheet *Sheet
+	var region *spineRegion
+	var regions [][]spineRegion
+
+	inPage := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" {
+			sheet, region, inPage = nil, nil, false
+			continue
+		}
+
+		key, value, isField := strings.Cut(trimmed, ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch {
+		case !isField && sheet == nil:
+			pack.Sheets = append(pack.Sheets, Sheet{Image: trimmed, Scale: 1})
+			regions = append(regions, nil)
+			sheet = &pack.Sheets[len(pack.Sheets)-1]
+			region, inPage = nil, true
+		case !isField:
+			regions[len(regions)-1] = append(regions[len(regions)-1], spineRegion{Name: trimmed, Index: -1})
+			region = &regions[len(regions)-1][len(regions[len(regions)-1])-1]
+			inPage = false
+		case inPage:
+			switch key {
+			case "size":
+				ints, err := parseSpineInts(value)
+				if err != nil || len(ints) != 2 {
+					return Pack{}, fmt.Errorf("invalid atlas: line %d: bad page size %q", lineNum, value)
+				}
+				sheet.Size = Size{Width: ints[0], Height: ints[1]}
+			case "format":
+				sheet.Format = value
+			case "scale":
+				scale, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return Pack{}, fmt.Errorf("invalid atlas: line %d: bad page scale %q", lineNum, value)
+				}
+				sheet.Scale = scale
+			}
+		case region != nil:
+			if err := applySpineRegionField(region, key, value); err != nil {
+				return Pack{}, fmt.Errorf("invalid atlas: line %d: %w", lineNum, err)
+			}
+		default:
+			return Pack{}, fmt.Errorf("invalid atlas: line %d: field outside of a page or region", lineNum)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Pack{}, fmt.Errorf("failed to read atlas: %w", err)
+	}
+
+	if len(pack.Sheets) == 0 {
+		return Pack{}, fmt.Errorf("invalid atlas: no pages found")
+	}
+
+	animations := make(map[string]int)
+	for _, pageRegions := range regions {
+		for _, r := range pageRegions {
+			animations[spineAnimation(r)]++
+		}
+	}
+
+	for i, pageRegions := range regions {
+		pack.Sheets[i].Textures = make([]Texture, 0, len(pageRegions))
+
+		for _, r := range pageRegions {
+			tex := r.texture()
+
+			if r.Index >= 0 {
+				tex.FileName = fmt.Sprintf("%s/%s_%d", r.Name, r.Name, r.Index)
+			} else if anim := spineAnimation(r); anim != r.Name && animations[anim] > 1 {
+				tex.FileName = anim + "/" + r.Name
+			}
+
+			pack.Sheets[i].Textures = append(pack.Sheets[i].Textures, tex)
+		}
+	}
+
+	return pack, nil
+}
+
+// Regions either carry an explicit index or encode it in their name (walk_0),
+// both of which group into an animation named by the prefix.
+func spineAnimation(region spineRegion) string {
+	if region.Index >= 0 {
+		return region.Name
+	}
+	if match := spineIndexSuffix.FindStringSubmatch(region.Name); match != nil {
+		return match[1]
+	}
+	return region.Name
+}
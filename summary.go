@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type SheetSummary struct {
+	Sheet      string  `json:"sheet"`
+	Frames     int     `json:"frames"`
+	Skipped    int     `json:"skipped"`
+	Failed     int     `json:"failed"`
+	Collisions int     `json:"collisions"`
+	Bytes      int64   `json:"bytes"`
+	Seconds    float64 `json:"seconds"`
+}
+
+type RunSummary struct {
+	Sheets []SheetSummary `json:"sheets"`
+	Total  SheetSummary   `json:"total"`
+}
+
+var summaryModes = []string{"table", "json", "none"}
+
+// newRunSummary totals the sheets, taking the run's wall time rather than the
+// sum of sheets that ran side by side.
+func newRunSummary(sheets []SheetSummary, elapsed time.Duration) RunSummary {
+	total := SheetSummary{Sheet: "total", Seconds: elapsed.Seconds()}
+	for _, sheet := range sheets {
+		total.Frames += sheet.Frames
+		total.Skipped += sheet.Skipped
+		total.Failed += sheet.Failed
+		total.Collisions += sheet.Collisions
+		total.Bytes += sheet.Bytes
+	}
+	return RunSummary{Sheets: sheets, Total: total}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func (summary RunSummary) print(mode string) error {
+	switch mode {
+	case "none":
+		return nil
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summary)
+	}
+
+	var table strings.Builder
+	writer := tabwriter.NewWriter(&table, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(writer, "SHEET\tFRAMES\tSKIPPED\tFAILED\tCOLLISIONS\tBYTES\tTIME\t")
+	for _, sheet := range append(summary.Sheets, summary.Total) {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%d\t%s\t%.2fs\t\n",
+			sheet.Sheet, sheet.Frames, sheet.Skipped, sheet.Failed, sheet.Collisions, formatBytes(sheet.Bytes), sheet.Seconds)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	// The total row is colored once aligned, since tabwriter would count the
+	// color codes as text.
+	rows := strings.SplitAfter(strings.TrimSuffix(table.String(), "\n"), "\n")
+	color := colorGreen
+	if summary.Total.Failed > 0 {
+		color = colorRed
+	}
+	rows[len(rows)-1] = paint(color, rows[len(rows)-1]) + "\n"
+	_, err := io.WriteString(os.Stdout, strings.Join(rows, ""))
+	return err
+}
+
+// porcelain writes a sheet record per sheet and a total record:
+// sheet, frames, skipped, failed, collisions, bytes, and seconds.
+func (summary RunSummary) porcelain(writer *PorcelainWriter) {
+	for _, sheet := range summary.Sheets {
+		writer.record("sheet", sheet.Sheet, sheet.Frames, sheet.Skipped, sheet.Failed, sheet.Collisions, sheet.Bytes, sheet.Seconds)
+	}
+	total := summary.Total
+	writer.record("total", total.Frames, total.Skipped, total.Failed, total.Collisions, total.Bytes, total.Seconds)
+}
+
+type countingWriter struct {
+	io.Writer
+	n int64
+}
+
+func (writer *countingWriter) Write(p []byte) (int, error) {
+	n, err := writer.Writer.Write(p)
+	writer.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var symlinkModes = []string{"reject", "follow"}
+
+// checkSymlinks refuses a path that passes through a symlink anywhere below
+// root, so a link planted in an asset tree cannot redirect writes elsewhere.
+// Root itself is trusted, since it was named by the user. Windows reports
+// junctions as irregular rather than as symlinks, so those are refused too.
+func checkSymlinks(root, path string) error {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return nil
+	}
+
+	// Paths outside root, like ../shared/sheet.png, only have their target checked.
+	components := []string{rel}
+	current := root
+	if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		components = strings.Split(rel, string(filepath.Separator))
+	}
+
+	for _, component := range components {
+		current = filepath.Join(current, component)
+
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s: %w", current, err)
+		}
+
+		if info.Mode()&(os.ModeSymlink|os.ModeIrregular) != 0 {
+			return fmt.Errorf("refusing to follow symlink %s, pass --symlinks follow to allow it", current)
+		}
+	}
+
+	return nil
+}
+
+// insideDir reports whether path stays within root once cleaned.
+func insideDir(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+func (unpacker Unpacker) checkOutput(path string) error {
+	if !insideDir(unpacker.OutputDir, path) {
+		return fmt.Errorf("refusing to write %s outside the output directory %s", path, unpacker.OutputDir)
+	}
+	if unpacker.FollowSymlinks {
+		return nil
+	}
+	return checkSymlinks(unpacker.OutputDir, path)
+}
+
+func (unpacker Unpacker) checkInput(sheet Sheet) error {
+	if unpacker.FollowSymlinks {
+		return nil
+	}
+	return checkSymlinks(unpacker.InputDir, filepath.Join(unpacker.InputDir, sheet.Image))
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type placedGlyph struct {
+	Glyph
+	At image.Point
+}
+
+type TextRenderer struct {
+	BitmapFont
+	FontDir       string
+	Atlas         *Composer
+	LetterSpacing int
+	pages         map[int]image.Image
+}
+
+func loadBitmapFont(fontPath string) (BitmapFont, error) {
+	data, err := os.ReadFile(fontPath)
+	if err != nil {
+		return BitmapFont{}, fmt.Errorf("failed to read font: %w", err)
+	}
+
+	return parseBitmapFont(data)
+}
+
+func (renderer *TextRenderer) page(id int) (image.Image, error) {
+	if img, ok := renderer.pages[id]; ok {
+		return img, nil
+	}
+
+	file, ok := renderer.Pages[id]
+	if !ok {
+		return nil, fmt.Errorf("font has no page %d", id)
+	}
+
+	var img image.Image
+	if renderer.Atlas != nil {
+		base := path.Base(file)
+		for _, name := range []string{file, strings.TrimSuffix(base, path.Ext(base))} {
+			if _, ok := renderer.Atlas.frames[name]; ok {
+				sprite, err := renderer.Atlas.sprite(name)
+				if err != nil {
+					return nil, err
+				}
+				img = sprite
+				break
+			}
+		}
+	}
+
+	if img == nil {
+		pageFile, err := os.Open(filepath.Join(renderer.FontDir, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open font page: %w", err)
+		}
+		defer pageFile.Close()
+
+		if img, err = decodeImage(pageFile); err != nil {
+			return nil, fmt.Errorf("failed to decode font page: %w", err)
+		}
+	}
+
+	renderer.pages[id] = img
+	return img, nil
+}
+
+func (renderer *TextRenderer) layout(text string) ([]placedGlyph, image.Rectangle, []rune) {
+	var placed []placedGlyph
+	var missing []rune
+	bounds := image.Rectangle{}
+
+	for row, line := range strings.Split(text, "\n") {
+		x, y := 0, row*renderer.LineHeight
+		prev := rune(-1)
+
+		for _, r := range line {
+			glyph, ok := renderer.Glyphs[r]
+			if !ok {
+				missing = append(missing, r)
+				if glyph, ok = renderer.Glyphs['?']; !ok {
+					continue
+				}
+			}
+
+			x += renderer.Kernings[kerningPair{prev, glyph.ID}]
+
+			at := image.Point{x + glyph.XOffset, y + glyph.YOffset}
+			placed = append(placed, placedGlyph{Glyph: glyph, At: at})
+			bounds = bounds.Union(image.Rectangle{at, at.Add(image.Point{glyph.Width, glyph.Height})})
+
+			x += glyph.XAdvance + renderer.LetterSpacing
+			prev = glyph.ID
+		}
+
+		bounds = bounds.Union(image.Rect(0, y, x, y+renderer.LineHeight))
+	}
+
+	return placed, bounds, missing
+}
+
+func (renderer *TextRenderer) render(text string) (*image.RGBA, []rune, error) {
+	placed, bounds, missing := renderer.layout(text)
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for _, glyph := range placed {
+		img, err := renderer.page(glyph.Page)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		source := image.Point{glyph.X, glyph.Y}.Add(img.Bounds().Min)
+		target := image.Rectangle{glyph.At, glyph.At.Add(image.Point{glyph.Width, glyph.Height})}.Sub(bounds.Min)
+		draw.Draw(canvas, target, img, source, draw.Over)
+	}
+
+	return canvas, missing, nil
+}
+
+func tint(img *image.RGBA, c color.NRGBA) {
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		alpha := uint32(img.Pix[i+3]) * uint32(c.A) / 255
+		img.Pix[i+0] = uint8(uint32(img.Pix[i+0]) * uint32(c.R) / 255 * uint32(c.A) / 255)
+		img.Pix[i+1] = uint8(uint32(img.Pix[i+1]) * uint32(c.G) / 255 * uint32(c.A) / 255)
+		img.Pix[i+2] = uint8(uint32(img.Pix[i+2]) * uint32(c.B) / 255 * uint32(c.A) / 255)
+		img.Pix[i+3] = uint8(alpha)
+	}
+}
+
+func newTextCmd() *cobra.Command {
+	var outputPath string
+	var atlasPath string
+	var tintColor string
+	var background string
+	var letterSpacing int
+
+	var textCmd = &cobra.Command{
+		Use:   "text <font> <text>",
+		Short: "Render text with a bitmap font",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var fontPath, text = args[0], args[1]
+			format, _ := cmd.Flags().GetString("format")
+
+			font, err := loadBitmapFont(fontPath)
+			if err != nil {
+				return err
+			}
+
+			renderer := TextRenderer{
+				BitmapFont:    font,
+				FontDir:       filepath.Dir(fontPath),
+				LetterSpacing: letterSpacing,
+				pages:         make(map[int]image.Image),
+			}
+
+			if atlasPath != "" {
+				pack, err := loadPack(atlasPath, format)
+				if err != nil {
+					return err
+				}
+				renderer.Atlas = newComposer(pack, filepath.Dir(atlasPath))
+			}
+
+			canvas, missing, err := renderer.render(strings.ReplaceAll(text, `\n`, "\n"))
+			if err != nil {
+				return err
+			}
+
+			if tintColor != "" {
+				c, err := parseColor(tintColor)
+				if err != nil {
+					return err
+				}
+				tint(canvas, c)
+			}
+
+			if background != "" {
+				c, err := parseColor(background)
+				if err != nil {
+					return err
+				}
+				filled := image.NewRGBA(canvas.Bounds())
+				draw.Draw(filled, filled.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+				draw.Draw(filled, filled.Bounds(), canvas, image.Point{}, draw.Over)
+				canvas = filled
+			}
+
+			if outputPath == "" {
+				outputPath = "text.png"
+			}
+
+			if err := writePNG(outputPath, canvas); err != nil {
+				return err
+			}
+
+			if len(missing) > 0 {
+				logf("[warn] font has no glyphs for %q\n", string(missing))
+			}
+			logf("[info] rendered %dx%d text to %s\n", canvas.Bounds().Dx(), canvas.Bounds().Dy(), outputPath)
+
+			return nil
+		},
+	}
+
+	textCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output image")
+	textCmd.Flags().StringVarP(&atlasPath, "atlas", "a", "", "Atlas holding the font pages as frames")
+	textCmd.Flags().StringVarP(&tintColor, "tint", "", "", "Tint color as #rrggbb or #rrggbbaa")
+	textCmd.Flags().StringVarP(&background, "background", "", "", "Background color as #rrggbb or #rrggbbaa")
+	textCmd.Flags().IntVarP(&letterSpacing, "letter-spacing", "", 0, "Extra pixels between glyphs")
+
+	return textCmd
+}
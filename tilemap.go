@@ -0,0 +1,716 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	tileFlipHorizontal uint32 = 0x80000000
+	tileFlipVertical   uint32 = 0x40000000
+	tileFlipDiagonal   uint32 = 0x20000000
+	tileFlagMask       uint32 = 0xf0000000
+)
+
+type Tileset struct {
+	Name       string
+	FirstGID   uint32
+	Image      string
+	TileWidth  int
+	TileHeight int
+	Spacing    int
+	Margin     int
+	Columns    int
+	TileCount  int
+}
+
+type TileLayer struct {
+	Name    string
+	Width   int
+	Height  int
+	Data    []uint32
+	Visible bool
+	Opacity float64
+}
+
+type TileMap struct {
+	Width       int
+	Height      int
+	TileWidth   int
+	TileHeight  int
+	Orientation string
+	Tilesets    []Tileset
+	Layers      []TileLayer
+}
+
+type jsonTiledTileset struct {
+	Name       string `json:"name"`
+	FirstGID   uint32 `json:"firstgid"`
+	Source     string `json:"source"`
+	Image      string `json:"image"`
+	TileWidth  int    `json:"tilewidth"`
+	TileHeight int    `json:"tileheight"`
+	Spacing    int    `json:"spacing"`
+	Margin     int    `json:"margin"`
+	Columns    int    `json:"columns"`
+	TileCount  int    `json:"tilecount"`
+}
+
+type jsonTiledLayer struct {
+	Name        string           `json:"name"`
+	Type        string           `json:"type"`
+	Width       int              `json:"width"`
+	Height      int              `json:"height"`
+	Data        json.RawMessage  `json:"data"`
+	Encoding    string           `json:"encoding"`
+	Compression string           `json:"compression"`
+	Visible     *bool            `json:"visible"`
+	Opacity     *float64         `json:"opacity"`
+	Layers      []jsonTiledLayer `json:"layers"`
+}
+
+type jsonTiledMap struct {
+	Width       int                `json:"width"`
+	Height      int                `json:"height"`
+	TileWidth   int                `json:"tilewidth"`
+	TileHeight  int                `json:"tileheight"`
+	Orientation string             `json:"orientation"`
+	Infinite    bool               `json:"infinite"`
+	Tilesets    []jsonTiledTileset `json:"tilesets"`
+	Layers      []jsonTiledLayer   `json:"layers"`
+}
+
+type xmlTiledImage struct {
+	Source string `xml:"source,attr"`
+}
+
+type xmlTiledTileset struct {
+	Name       string        `xml:"name,attr"`
+	FirstGID   uint32        `xml:"firstgid,attr"`
+	Source     string        `xml:"source,attr"`
+	TileWidth  int           `xml:"tilewidth,attr"`
+	TileHeight int           `xml:"tileheight,attr"`
+	Spacing    int           `xml:"spacing,attr"`
+	Margin     int           `xml:"margin,attr"`
+	Columns    int           `xml:"columns,attr"`
+	TileCount  int           `xml:"tilecount,attr"`
+	Image      xmlTiledImage `xml:"image"`
+}
+
+type xmlTiledData struct {
+	Encoding    string `xml:"encoding,attr"`
+	Compression string `xml:"compression,attr"`
+	Text        string `xml:",chardata"`
+	Tiles       []struct {
+		GID uint32 `xml:"gid,attr"`
+	} `xml:"tile"`
+	Chunks []struct{} `xml:"chunk"`
+}
+
+type xmlTiledLayer struct {
+	XMLName xml.Name
+	Name    string          `xml:"name,attr"`
+	Width   int             `xml:"width,attr"`
+	Height  int             `xml:"height,attr"`
+	Visible *int            `xml:"visible,attr"`
+	Opacity *float64        `xml:"opacity,attr"`
+	Data    xmlTiledData    `xml:"data"`
+	Layers  []xmlTiledLayer `xml:",any"`
+}
+
+type xmlTiledMap struct {
+	Width       int               `xml:"width,attr"`
+	Height      int               `xml:"height,attr"`
+	TileWidth   int               `xml:"tilewidth,attr"`
+	TileHeight  int               `xml:"tileheight,attr"`
+	Orientation string            `xml:"orientation,attr"`
+	Infinite    int               `xml:"infinite,attr"`
+	Tilesets    []xmlTiledTileset `xml:"tileset"`
+	Layers      []xmlTiledLayer   `xml:",any"`
+}
+
+func decodeTileData(encoding, compression, text string, count int) ([]uint32, error) {
+	switch encoding {
+	case "csv":
+		fields := strings.FieldsFunc(text, func(r rune) bool {
+			return r == ',' || r == '\n' || r == '\r' || r == ' ' || r == '\t'
+		})
+		data := make([]uint32, 0, len(fields))
+		for _, field := range fields {
+			gid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tile gid %q", field)
+			}
+			data = append(data, uint32(gid))
+		}
+		return data, nil
+	case "base64":
+	default:
+		return nil, fmt.Errorf("unsupported tile encoding %q", encoding)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 tile data: %w", err)
+	}
+
+	var reader io.Reader = bytes.NewReader(raw)
+	switch compression {
+	case "":
+	case "zlib":
+		if reader, err = zlib.NewReader(reader); err != nil {
+			return nil, fmt.Errorf("invalid zlib tile data: %w", err)
+		}
+	case "gzip":
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return nil, fmt.Errorf("invalid gzip tile data: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported tile compression %q", compression)
+	}
+
+	data := make([]uint32, count)
+	if err := binary.Read(reader, binary.LittleEndian, data); err != nil {
+		return nil, fmt.Errorf("invalid tile data: %w", err)
+	}
+
+	return data, nil
+}
+
+func loadExternalTileset(mapDir, source string) (xmlTiledTileset, error) {
+	data, err := os.ReadFile(filepath.Join(mapDir, source))
+	if err != nil {
+		return xmlTiledTileset{}, fmt.Errorf("failed to read tileset: %w", err)
+	}
+
+	var tileset xmlTiledTileset
+	if err := xml.Unmarshal(data, &tileset); err != nil {
+		return xmlTiledTileset{}, fmt.Errorf("invalid tileset XML: %w", err)
+	}
+
+	// Images in external tilesets are relative to the tileset, not the map.
+	if tileset.Image.Source != "" {
+		tileset.Image.Source = path.Join(path.Dir(filepath.ToSlash(source)), tileset.Image.Source)
+	}
+
+	return tileset, nil
+}
+
+func (tileset xmlTiledTileset) tileset(firstGID uint32) Tileset {
+	return Tileset{
+		Name:       tileset.Name,
+		FirstGID:   firstGID,
+		Image:      tileset.Image.Source,
+		TileWidth:  tileset.TileWidth,
+		TileHeight: tileset.TileHeight,
+		Spacing:    tileset.Spacing,
+		Margin:     tileset.Margin,
+		Columns:    tileset.Columns,
+		TileCount:  tileset.TileCount,
+	}
+}
+
+// checkTileGrid refuses a grid of columns by rows tiles of tileWidth by
+// tileHeight pixels that is empty or larger than the decode limits allow,
+// before tile data or a canvas is sized from it.
+func checkTileGrid(columns, rows, tileWidth, tileHeight int) error {
+	if columns <= 0 || rows <= 0 || tileWidth <= 0 || tileHeight <= 0 {
+		return fmt.Errorf("size %dx%d of %dx%d tiles must be positive", columns, rows, tileWidth, tileHeight)
+	}
+	if err := decodeLimits.check(columns, rows); err != nil {
+		return err
+	}
+	if err := decodeLimits.check(tileWidth, tileHeight); err != nil {
+		return err
+	}
+	return decodeLimits.check(columns*tileWidth, rows*tileHeight)
+}
+
+// checkTiles validates the map and its tilesets before any layer is read.
+func (tileMap TileMap) checkTiles() error {
+	if err := checkTileGrid(tileMap.Width, tileMap.Height, tileMap.TileWidth, tileMap.TileHeight); err != nil {
+		return fmt.Errorf("invalid map: %w", err)
+	}
+	for _, ts := range tileMap.Tilesets {
+		if ts.TileWidth <= 0 || ts.TileHeight <= 0 || ts.Spacing < 0 || ts.Margin < 0 {
+			return fmt.Errorf("invalid tileset %q: tile size must be positive and spacing and margin not negative", ts.Name)
+		}
+		if err := decodeLimits.check(ts.TileWidth, ts.TileHeight); err != nil {
+			return fmt.Errorf("invalid tileset %q: %w", ts.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkLayerSize refuses a layer that is empty or larger than the map, whose
+// size would otherwise allocate its tile data unchecked.
+func checkLayerSize(name string, width, height int, mapSize image.Point) error {
+	if width <= 0 || height <= 0 || width > mapSize.X || height > mapSize.Y {
+		return fmt.Errorf("invalid layer %q: size %dx%d must be positive and fit the %dx%d map", name, width, height, mapSize.X, mapSize.Y)
+	}
+	return nil
+}
+
+func flattenJSONLayers(layers []jsonTiledLayer, prefix string, visible bool, opacity float64, mapSize image.Point) ([]TileLayer, error) {
+	var flat []TileLayer
+
+	for _, layer := range layers {
+		layerVisible := visible && (layer.Visible == nil || *layer.Visible)
+		layerOpacity := opacity
+		if layer.Opacity != nil {
+			layerOpacity *= *layer.Opacity
+		}
+
+		switch layer.Type {
+		case "group":
+			nested, err := flattenJSONLayers(layer.Layers, prefix+layer.Name+"/", layerVisible, layerOpacity, mapSize)
+			if err != nil {
+				return nil, err
+			}
+			flat = append(flat, nested...)
+		case "tilelayer":
+			if err := checkLayerSize(prefix+layer.Name, layer.Width, layer.Height, mapSize); err != nil {
+				return nil, err
+			}
+
+			var data []uint32
+			if layer.Encoding == "base64" {
+				var text string
+				if err := json.Unmarshal(layer.Data, &text); err != nil {
+					return nil, fmt.Errorf("invalid data in layer %q: %w", layer.Name, err)
+				}
+				decoded, err := decodeTileData("base64", layer.Compression, text, layer.Width*layer.Height)
+				if err != nil {
+					return nil, fmt.Errorf("layer %q: %w", layer.Name, err)
+				}
+				data = decoded
+			} else if err := json.Unmarshal(layer.Data, &data); err != nil {
+				return nil, fmt.Errorf("invalid data in layer %q: %w", layer.Name, err)
+			}
+
+			flat = append(flat, TileLayer{
+				Name:    prefix + layer.Name,
+				Width:   layer.Width,
+				Height:  layer.Height,
+				Data:    data,
+				Visible: layerVisible,
+				Opacity: layerOpacity,
+			})
+		}
+	}
+
+	return flat, nil
+}
+
+func flattenXMLLayers(layers []xmlTiledLayer, prefix string, visible bool, opacity float64, mapSize image.Point) ([]TileLayer, error) {
+	var flat []TileLayer
+
+	for _, layer := range layers {
+		layerVisible := visible && (layer.Visible == nil || *layer.Visible != 0)
+		layerOpacity := opacity
+		if layer.Opacity != nil {
+			layerOpacity *= *layer.Opacity
+		}
+
+		switch layer.XMLName.Local {
+		case "group":
+			nested, err := flattenXMLLayers(layer.Layers, prefix+layer.Name+"/", layerVisible, layerOpacity, mapSize)
+			if err != nil {
+				return nil, err
+			}
+			flat = append(flat, nested...)
+		case "layer":
+			if len(layer.Data.Chunks) > 0 {
+				return nil, fmt.Errorf("layer %q: infinite maps are not supported", layer.Name)
+			}
+			if err := checkLayerSize(prefix+layer.Name, layer.Width, layer.Height, mapSize); err != nil {
+				return nil, err
+			}
+
+			var data []uint32
+			if layer.Data.Encoding == "" {
+				for _, tile := range layer.Data.Tiles {
+					data = append(data, tile.GID)
+				}
+			} else {
+				decoded, err := decodeTileData(layer.Data.Encoding, layer.Data.Compression, layer.Data.Text, layer.Width*layer.Height)
+				if err != nil {
+					return nil, fmt.Errorf("layer %q: %w", layer.Name, err)
+				}
+				data = decoded
+			}
+
+			flat = append(flat, TileLayer{
+				Name:    prefix + layer.Name,
+				Width:   layer.Width,
+				Height:  layer.Height,
+				Data:    data,
+				Visible: layerVisible,
+				Opacity: layerOpacity,
+			})
+		}
+	}
+
+	return flat, nil
+}
+
+func loadTileMap(mapPath string) (TileMap, error) {
+	data, err := os.ReadFile(mapPath)
+	if err != nil {
+		return TileMap{}, fmt.Errorf("failed to read map: %w", err)
+	}
+
+	mapDir := filepath.Dir(mapPath)
+	var tileMap TileMap
+
+	switch filepath.Ext(mapPath) {
+	case ".json", ".tmj":
+		var raw jsonTiledMap
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return TileMap{}, fmt.Errorf("invalid map JSON: %w", err)
+		}
+		if raw.Infinite {
+			return TileMap{}, fmt.Errorf("infinite maps are not supported")
+		}
+
+		tileMap = TileMap{
+			Width:       raw.Width,
+			Height:      raw.Height,
+			TileWidth:   raw.TileWidth,
+			TileHeight:  raw.TileHeight,
+			Orientation: raw.Orientation,
+		}
+
+		for _, ts := range raw.Tilesets {
+			if ts.Source != "" {
+				external, err := loadExternalTileset(mapDir, ts.Source)
+				if err != nil {
+					return TileMap{}, err
+				}
+				tileMap.Tilesets = append(tileMap.Tilesets, external.tileset(ts.FirstGID))
+				continue
+			}
+
+			tileMap.Tilesets = append(tileMap.Tilesets, Tileset{
+				Name:       ts.Name,
+				FirstGID:   ts.FirstGID,
+				Image:      ts.Image,
+				TileWidth:  ts.TileWidth,
+				TileHeight: ts.TileHeight,
+				Spacing:    ts.Spacing,
+				Margin:     ts.Margin,
+				Columns:    ts.Columns,
+				TileCount:  ts.TileCount,
+			})
+		}
+
+		if err := tileMap.checkTiles(); err != nil {
+			return TileMap{}, err
+		}
+		if tileMap.Layers, err = flattenJSONLayers(raw.Layers, "", true, 1, image.Pt(tileMap.Width, tileMap.Height)); err != nil {
+			return TileMap{}, err
+		}
+	case ".tmx":
+		var raw xmlTiledMap
+		if err := xml.Unmarshal(data, &raw); err != nil {
+			return TileMap{}, fmt.Errorf("invalid map XML: %w", err)
+		}
+		if raw.Infinite != 0 {
+			return TileMap{}, fmt.Errorf("infinite maps are not supported")
+		}
+
+		tileMap = TileMap{
+			Width:       raw.Width,
+			Height:      raw.Height,
+			TileWidth:   raw.TileWidth,
+			TileHeight:  raw.TileHeight,
+			Orientation: raw.Orientation,
+		}
+
+		for _, ts := range raw.Tilesets {
+			firstGID := ts.FirstGID
+			if ts.Source != "" {
+				external, err := loadExternalTileset(mapDir, ts.Source)
+				if err != nil {
+					return TileMap{}, err
+				}
+				ts = external
+			}
+			tileMap.Tilesets = append(tileMap.Tilesets, ts.tileset(firstGID))
+		}
+
+		if err := tileMap.checkTiles(); err != nil {
+			return TileMap{}, err
+		}
+		if tileMap.Layers, err = flattenXMLLayers(raw.Layers, "", true, 1, image.Pt(tileMap.Width, tileMap.Height)); err != nil {
+			return TileMap{}, err
+		}
+	default:
+		return TileMap{}, fmt.Errorf("map file must be a .json, .tmj, or .tmx file")
+	}
+
+	if tileMap.Orientation != "" && tileMap.Orientation != "orthogonal" {
+		return TileMap{}, fmt.Errorf("unsupported map orientation %q", tileMap.Orientation)
+	}
+
+	return tileMap, nil
+}
+
+type TileRenderer struct {
+	TileMap
+	MapDir string
+	Atlas  *Composer
+	images map[string]image.Image
+}
+
+// Tileset images are looked up in the atlas first, by image or tileset name,
+// so tilesets that were packed into an atlas render without being extracted.
+func (renderer *TileRenderer) tilesetImage(tileset Tileset) (image.Image, error) {
+	if img, ok := renderer.images[tileset.Image]; ok {
+		return img, nil
+	}
+
+	var img image.Image
+	if renderer.Atlas != nil {
+		base := path.Base(tileset.Image)
+		for _, name := range []string{tileset.Image, strings.TrimSuffix(base, path.Ext(base)), tileset.Name} {
+			if _, ok := renderer.Atlas.frames[name]; ok {
+				sprite, err := renderer.Atlas.sprite(name)
+				if err != nil {
+					return nil, err
+				}
+				img = sprite
+				break
+			}
+		}
+	}
+
+	if img == nil {
+		if tileset.Image == "" {
+			return nil, fmt.Errorf("tileset %q has no image", tileset.Name)
+		}
+
+		imageFile, err := os.Open(filepath.Join(renderer.MapDir, filepath.FromSlash(tileset.Image)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open tileset image: %w", err)
+		}
+		defer imageFile.Close()
+
+		if img, err = decodeImage(imageFile); err != nil {
+			return nil, fmt.Errorf("failed to decode tileset image: %w", err)
+		}
+	}
+
+	renderer.images[tileset.Image] = img
+	return img, nil
+}
+
+func (renderer *TileRenderer) tileset(gid uint32) (Tileset, bool) {
+	var found Tileset
+	ok := false
+
+	for _, ts := range renderer.Tilesets {
+		if ts.FirstGID <= gid && (!ok || ts.FirstGID > found.FirstGID) {
+			found, ok = ts, true
+		}
+	}
+
+	return found, ok
+}
+
+func (renderer *TileRenderer) tile(gid uint32) (*image.RGBA, error) {
+	flags := gid & tileFlagMask
+	gid &^= tileFlagMask
+
+	tileset, ok := renderer.tileset(gid)
+	if !ok {
+		return nil, fmt.Errorf("no tileset contains gid %d", gid)
+	}
+
+	img, err := renderer.tilesetImage(tileset)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := tileset.Columns
+	if columns <= 0 {
+		columns = max(1, (img.Bounds().Dx()-2*tileset.Margin+tileset.Spacing)/(tileset.TileWidth+tileset.Spacing))
+	}
+
+	local := int(gid - tileset.FirstGID)
+	origin := img.Bounds().Min.Add(image.Point{
+		X: tileset.Margin + (local%columns)*(tileset.TileWidth+tileset.Spacing),
+		Y: tileset.Margin + (local/columns)*(tileset.TileHeight+tileset.Spacing),
+	})
+
+	width, height := tileset.TileWidth, tileset.TileHeight
+	if flags&tileFlipDiagonal != 0 {
+		width, height = height, width
+	}
+
+	tile := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			sx, sy := x, y
+			if flags&tileFlipHorizontal != 0 {
+				sx = width - 1 - sx
+			}
+			if flags&tileFlipVertical != 0 {
+				sy = height - 1 - sy
+			}
+			if flags&tileFlipDiagonal != 0 {
+				sx, sy = sy, sx
+			}
+			tile.Set(x, y, img.At(origin.X+sx, origin.Y+sy))
+		}
+	}
+
+	return tile, nil
+}
+
+func (renderer *TileRenderer) renderLayer(layer TileLayer) (*image.RGBA, error) {
+	canvas, err := newCanvas(renderer.Width*renderer.TileWidth, renderer.Height*renderer.TileHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, gid := range layer.Data {
+		if gid&^tileFlagMask == 0 {
+			continue
+		}
+
+		tile, err := renderer.tile(gid)
+		if err != nil {
+			return nil, fmt.Errorf("layer %q: %w", layer.Name, err)
+		}
+
+		// Tiles taller than the grid are anchored to the bottom of their cell.
+		x, y := i%layer.Width, i/layer.Width
+		at := image.Point{x * renderer.TileWidth, (y+1)*renderer.TileHeight - tile.Bounds().Dy()}
+		draw.Draw(canvas, tile.Bounds().Add(at), tile, image.Point{}, draw.Over)
+	}
+
+	return canvas, nil
+}
+
+func writePNG(outputPath string, img image.Image) error {
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+
+	if err = png.Encode(outputFile, img); err != nil {
+		outputFile.Close()
+		return fmt.Errorf("failed to encode image as png: %w", err)
+	}
+
+	if err = outputFile.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	return nil
+}
+
+func newTilemapCmd() *cobra.Command {
+	var outputDir string
+	var atlasPath string
+
+	var tilemapCmd = &cobra.Command{
+		Use:   "tilemap <map>",
+		Short: "Render a Tiled map to per-layer and composited images",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var mapPath = args[0]
+			format, _ := cmd.Flags().GetString("format")
+
+			tileMap, err := loadTileMap(mapPath)
+			if err != nil {
+				return err
+			}
+
+			renderer := TileRenderer{
+				TileMap: tileMap,
+				MapDir:  filepath.Dir(mapPath),
+				images:  make(map[string]image.Image),
+			}
+
+			if atlasPath != "" {
+				pack, err := loadPack(atlasPath, format)
+				if err != nil {
+					return err
+				}
+				renderer.Atlas = newComposer(pack, filepath.Dir(atlasPath))
+			}
+
+			if outputDir == "" {
+				outputDir = strings.TrimSuffix(mapPath, filepath.Ext(mapPath))
+			}
+
+			// Layers get their own directory so none can overwrite map.png,
+			// and same-named layers take the first free _2, _3, ... name.
+			layersDir := filepath.Join(outputDir, "layers")
+			if err := os.MkdirAll(layersDir, 0o755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			taken := make(map[string]bool)
+
+			composite, err := newCanvas(tileMap.Width*tileMap.TileWidth, tileMap.Height*tileMap.TileHeight)
+			if err != nil {
+				return err
+			}
+
+			for _, layer := range tileMap.Layers {
+				canvas, err := renderer.renderLayer(layer)
+				if err != nil {
+					return err
+				}
+
+				name := strings.ReplaceAll(layer.Name, "/", "_")
+				layerPath := filepath.Join(layersDir, name+".png")
+				for n := 2; taken[pathKey(layerPath)]; n++ {
+					layerPath = filepath.Join(layersDir, fmt.Sprintf("%s_%d.png", name, n))
+				}
+				taken[pathKey(layerPath)] = true
+				if err := writePNG(layerPath, canvas); err != nil {
+					return err
+				}
+
+				if layer.Visible {
+					opacity := image.NewUniform(color.Alpha{A: uint8(layer.Opacity*255 + 0.5)})
+					draw.DrawMask(composite, composite.Bounds(), canvas, image.Point{}, opacity, image.Point{}, draw.Over)
+				}
+			}
+
+			if err := writePNG(filepath.Join(outputDir, "map.png"), composite); err != nil {
+				return err
+			}
+
+			logf("[info] rendered %d layers to %s\n", len(tileMap.Layers), outputDir)
+
+			return nil
+		},
+	}
+
+	tilemapCmd.Flags().StringVarP(&outputDir, "output", "o", "", "Output directory")
+	tilemapCmd.Flags().StringVarP(&atlasPath, "atlas", "a", "", "Atlas containing the tileset images")
+
+	return tilemapCmd
+}
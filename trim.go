@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+var trimModes = []string{"full", "tight"}
+
+// TrimOffset places a tight sprite back on its original canvas.
+type TrimOffset struct {
+	X          int  `json:"x"`
+	Y          int  `json:"y"`
+	Width      int  `json:"w"`
+	Height     int  `json:"h"`
+	SourceSize Size `json:"sourceSize"`
+}
+
+// tightPack shrinks every frame's canvas to its trimmed pixels, so the
+// sprites are written without the transparent margin the packer removed.
+func tightPack(pack Pack) Pack {
+	sheets := make([]Sheet, len(pack.Sheets))
+	for i, sh := range pack.Sheets {
+		textures := make([]Texture, len(sh.Textures))
+		for j, tex := range sh.Textures {
+			trimmed := tex.SpriteSourceSize
+			tex.SourceSize = Size{Width: trimmed.Width, Height: trimmed.Height}
+			tex.SpriteSourceSize = Frame{Width: trimmed.Width, Height: trimmed.Height}
+			if tex.Scale9Borders != nil {
+				center := *tex.Scale9Borders
+				center.X, center.Y = center.X-trimmed.X, center.Y-trimmed.Y
+				tex.Scale9Borders = &center
+			}
+			textures[j] = tex
+		}
+		sh.Textures = textures
+		sheets[i] = sh
+	}
+
+	pack.Sheets = sheets
+	return pack
+}
+
+func writeTrimOffsets(pack Pack, path string) error {
+	offsets := make(map[string]TrimOffset)
+	for _, sh := range pack.Sheets {
+		for _, tex := range sh.Textures {
+			offsets[tex.FileName] = TrimOffset{
+				X:          tex.SpriteSourceSize.X,
+				Y:          tex.SpriteSourceSize.Y,
+				Width:      tex.SpriteSourceSize.Width,
+				Height:     tex.SpriteSourceSize.Height,
+				SourceSize: tex.SourceSize,
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(offsets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode trim offsets: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write trim offsets: %w", err)
+	}
+	return nil
+}
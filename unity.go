@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type unitySprite struct {
+	Name string `yaml:"name"`
+	Rect struct {
+		X      float64 `yaml:"x"`
+		Y      float64 `yaml:"y"`
+		Width  float64 `yaml:"width"`
+		Height float64 `yaml:"height"`
+	} `yaml:"rect"`
+	Pivot struct {
+		X float64 `yaml:"x"`
+		Y float64 `yaml:"y"`
+	} `yaml:"pivot"`
+}
+
+type unityMeta struct {
+	TextureImporter struct {
+		SpriteMode  int `yaml:"spriteMode"`
+		SpriteSheet struct {
+			Sprites []unitySprite `yaml:"sprites"`
+		} `yaml:"spriteSheet"`
+	} `yaml:"TextureImporter"`
+}
+
+// Unity rects are measured from the bottom-left of the texture, so the sheet
+// height is needed to flip them into image space.
+func parseUnityMeta(data []byte, metaPath string) (Pack, error) {
+	var meta unityMeta
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return Pack{}, fmt.Errorf("invalid Unity meta: %w", err)
+	}
+
+	sprites := meta.TextureImporter.SpriteSheet.Sprites
+	if len(sprites) == 0 {
+		return Pack{}, fmt.Errorf("invalid Unity meta: no spriteSheet sprites found")
+	}
+
+	imageName := strings.TrimSuffix(filepath.Base(metaPath), ".meta")
+
+	imageFile, err := os.Open(filepath.Join(filepath.Dir(metaPath), imageName))
+	if err != nil {
+		return Pack{}, fmt.Errorf("failed to open texture sheet: %w", err)
+	}
+	defer imageFile.Close()
+
+	config, _, err := image.DecodeConfig(imageFile)
+	if err != nil {
+		return Pack{}, fmt.Errorf("failed to decode texture sheet: %w", err)
+	}
+
+	sheet := Sheet{
+		Image:    imageName,
+		Scale:    1,
+		Size:     Size{Width: config.Width, Height: config.Height},
+		Textures: make([]Texture, 0, len(sprites)),
+	}
+
+	for _, sprite := range sprites {
+		frame := Frame{
+			X:      int(sprite.Rect.X),
+			Y:      config.Height - int(sprite.Rect.Y) - int(sprite.Rect.Height),
+			Width:  int(sprite.Rect.Width),
+			Height: int(sprite.Rect.Height),
+		}
+
+		sheet.Textures = append(sheet.Textures, Texture{
+			FileName:         sprite.Name,
+			Frame:            frame,
+			SourceSize:       Size{Width: frame.Width, Height: frame.Height},
+			SpriteSourceSize: Frame{Width: frame.Width, Height: frame.Height},
+			// Unity pivots are measured up from the bottom, like its rects.
+			Pivot: &Pivot{X: sprite.Pivot.X, Y: 1 - sprite.Pivot.Y},
+		})
+	}
+
+	return Pack{Sheets: []Sheet{sheet}}, nil
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type UpscaleTile struct {
+	Path   string `json:"path"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"w"`
+	Height int    `json:"h"`
+}
+
+type UpscaleImage struct {
+	Output string        `json:"output"`
+	Width  int           `json:"w"`
+	Height int           `json:"h"`
+	Tiles  []UpscaleTile `json:"tiles"`
+}
+
+// An UpscaleManifest records how pages or frames were cut into tiles, so
+// reassemble can put the tiles back together once an external upscaler has
+// enlarged them. Paths are relative to the manifest.
+type UpscaleManifest struct {
+	TileSize int            `json:"tileSize"`
+	Overlap  int            `json:"overlap"`
+	Images   []UpscaleImage `json:"images"`
+}
+
+// tileStarts spreads tiles of size along length, each overlapping the one
+// before by at least overlap, with the last one flush with the end.
+func tileStarts(length, size, overlap int) []int {
+	if length <= size {
+		return []int{0}
+	}
+
+	var starts []int
+	for start := 0; start+size < length; start += size - overlap {
+		starts = append(starts, start)
+	}
+	return append(starts, length-size)
+}
+
+// writeTiles cuts img into overlapping tiles under the output directory.
+func (unpacker Unpacker) writeTiles(img image.Image, output string) (UpscaleImage, error) {
+	bounds := img.Bounds()
+	tiled := UpscaleImage{Output: filepath.ToSlash(output), Width: bounds.Dx(), Height: bounds.Dy()}
+	stem := strings.TrimSuffix(output, filepath.Ext(output))
+
+	for row, y := range tileStarts(bounds.Dy(), unpacker.TileSize, unpacker.TileOverlap) {
+		for column, x := range tileStarts(bounds.Dx(), unpacker.TileSize, unpacker.TileOverlap) {
+			rect := image.Rect(x, y, x+unpacker.TileSize, y+unpacker.TileSize).Add(bounds.Min).Intersect(bounds)
+			tile := image.NewNRGBA(image.Rectangle{Max: rect.Size()})
+			for ty := range rect.Dy() {
+				for tx := range rect.Dx() {
+					tile.Set(tx, ty, img.At(rect.Min.X+tx, rect.Min.Y+ty))
+				}
+			}
+
+			path := filepath.Join("tiles", stem, fmt.Sprintf("%d_%d%s", row, column, unpacker.encoder().Extension()))
+			if _, err := unpacker.writeSprite(filepath.Join(unpacker.OutputDir, path), tile); err != nil {
+				return tiled, err
+			}
+			tiled.Tiles = append(tiled.Tiles, UpscaleTile{Path: filepath.ToSlash(path), X: x, Y: y, Width: rect.Dx(), Height: rect.Dy()})
+		}
+	}
+	return tiled, nil
+}
+
+// writeUpscaleTiles tiles every page, or every frame with TileFrames, in
+// place of extracting sprites, and writes tiles.json to reassemble them.
+func (unpacker Unpacker) writeUpscaleTiles() error {
+	manifest := UpscaleManifest{TileSize: unpacker.TileSize, Overlap: unpacker.TileOverlap}
+
+	for _, sheet := range unpacker.Sheets {
+		if err := unpacker.checkInput(sheet); err != nil {
+			return err
+		}
+		img, err := decodeSheet(unpacker.InputDir, sheet)
+		if err != nil {
+			return err
+		}
+
+		if !unpacker.TileFrames {
+			tiled, err := unpacker.writeTiles(img, sheet.Image)
+			if err != nil {
+				return err
+			}
+			manifest.Images = append(manifest.Images, tiled)
+			continue
+		}
+
+		for _, tex := range sheet.Textures {
+			output, _ := filepath.Rel(unpacker.OutputDir, unpacker.outputPath(tex))
+			tiled, err := unpacker.writeTiles(extractTexture(tex, img), output)
+			if err != nil {
+				return err
+			}
+			manifest.Images = append(manifest.Images, tiled)
+		}
+	}
+
+	tiles := 0
+	for _, tiled := range manifest.Images {
+		tiles += len(tiled.Tiles)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tile manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(unpacker.OutputDir, "tiles.json"), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write tile manifest: %w", err)
+	}
+
+	logf("[info] cut %d images into %d tiles of %dpx overlapping by %dpx\n", len(manifest.Images), tiles, unpacker.TileSize, unpacker.TileOverlap)
+	return nil
+}
+
+// tileWeight ramps a tile's pixels up from the edges it shares with other
+// tiles, so overlapping tiles blend across the seam instead of meeting at a
+// hard edge. Edges on the image border keep full weight.
+func tileWeight(pos, length, ramp float64, start, end bool) float64 {
+	weight := 1.0
+	if ramp <= 0 {
+		return weight
+	}
+	if !start {
+		weight = min(weight, (pos+0.5)/ramp)
+	}
+	if !end {
+		weight = min(weight, (length-pos-0.5)/ramp)
+	}
+	return max(weight, 1e-6)
+}
+
+// reassemble blends the upscaled tiles of one image back together. The
+// upscale factor is read off the first tile, and every tile has to agree.
+func reassemble(tiled UpscaleImage, dir string, overlap int) (*image.NRGBA, float64, error) {
+	if len(tiled.Tiles) == 0 {
+		return nil, 0, fmt.Errorf("invalid tile manifest: %s has no tiles", tiled.Output)
+	}
+
+	var factor float64
+	var canvas image.Rectangle
+	var sums []float64
+	for _, tile := range tiled.Tiles {
+		img, err := decodeImageFile(filepath.Join(dir, filepath.FromSlash(tile.Path)))
+		if err != nil {
+			return nil, 0, err
+		}
+		bounds := img.Bounds()
+
+		if sums == nil {
+			factor = float64(bounds.Dx()) / float64(tile.Width)
+			canvas = image.Rect(0, 0, int(math.Round(float64(tiled.Width)*factor)), int(math.Round(float64(tiled.Height)*factor)))
+			if err := decodeLimits.check(canvas.Dx(), canvas.Dy()); err != nil {
+				return nil, 0, err
+			}
+			sums = make([]float64, 5*canvas.Dx()*canvas.Dy())
+		}
+
+		origin := image.Pt(int(math.Round(float64(tile.X)*factor)), int(math.Round(float64(tile.Y)*factor)))
+		expected := image.Pt(int(math.Round(float64(tile.X+tile.Width)*factor)), int(math.Round(float64(tile.Y+tile.Height)*factor))).Sub(origin)
+		if bounds.Size() != expected {
+			return nil, 0, fmt.Errorf("tile %s is %dx%d, expected %dx%d at %gx", tile.Path, bounds.Dx(), bounds.Dy(), expected.X, expected.Y, factor)
+		}
+
+		ramp := float64(overlap) * factor
+		for y := range expected.Y {
+			wy := tileWeight(float64(y), float64(expected.Y), ramp, tile.Y == 0, tile.Y+tile.Height >= tiled.Height)
+			for x := range expected.X {
+				wx := tileWeight(float64(x), float64(expected.X), ramp, tile.X == 0, tile.X+tile.Width >= tiled.Width)
+				weight := wx * wy
+
+				r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				i := 5 * ((origin.Y+y)*canvas.Dx() + origin.X + x)
+				sums[i] += float64(r) * weight
+				sums[i+1] += float64(g) * weight
+				sums[i+2] += float64(b) * weight
+				sums[i+3] += float64(a) * weight
+				sums[i+4] += weight
+			}
+		}
+	}
+
+	out := image.NewNRGBA(canvas)
+	for y := range canvas.Dy() {
+		for x := range canvas.Dx() {
+			i := 5 * (y*canvas.Dx() + x)
+			if sums[i+4] == 0 {
+				continue
+			}
+			c := color.RGBA64{}
+			channel := func(v float64) uint16 { return uint16(min(max(math.Round(v/sums[i+4]), 0), 0xffff)) }
+			c.R, c.G, c.B, c.A = channel(sums[i]), channel(sums[i+1]), channel(sums[i+2]), channel(sums[i+3])
+			out.Set(x, y, c)
+		}
+	}
+	return out, factor, nil
+}
+
+// encoderForPath picks the sprite format writing files with path's extension.
+func encoderForPath(path string) (SpriteEncoder, error) {
+	for _, format := range spriteFormats {
+		encoder, err := newSpriteEncoder(SpriteEncoding{Format: format, Speed: 6, PNGCompression: "default"})
+		if err == nil && encoder.Extension() == strings.ToLower(filepath.Ext(path)) {
+			return encoder, nil
+		}
+	}
+	return nil, fmt.Errorf("no output format writes %s files", filepath.Ext(path))
+}
+
+func newReassembleCmd() *cobra.Command {
+	var outputDir string
+	var repackPath string
+	var maxSize string = "4096x4096"
+	var padding int = 2
+	var algorithmName string = packAlgorithms[0].Name
+
+	var reassembleCmd = &cobra.Command{
+		Use:   "reassemble <tiles.json|manifest.json> [processed-dir]",
+		Short: "Rebuild pages from upscaled tiles, or frames or an atlas from processed frames",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var path = args[0]
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read manifest: %w", err)
+			}
+
+			dir := filepath.Dir(path)
+			if len(args) > 1 {
+				dir = args[1]
+			}
+			if outputDir == "" {
+				outputDir = filepath.Dir(path)
+			}
+
+			var tiles UpscaleManifest
+			if err := json.Unmarshal(data, &tiles); err != nil {
+				return fmt.Errorf("invalid manifest JSON: %w", err)
+			}
+			if tiles.TileSize == 0 {
+				var manifest Manifest
+				if err := json.Unmarshal(data, &manifest); err != nil {
+					return fmt.Errorf("invalid manifest JSON: %w", err)
+				}
+
+				sprites, factor, err := processedFrames(manifest, dir)
+				if err != nil {
+					return err
+				}
+
+				if repackPath == "" {
+					if err := reassembleFrames(manifest, sprites, outputDir); err != nil {
+						return err
+					}
+					logf("[info] rebuilt %d frames at %gx in %s\n", len(sprites), factor, outputDir)
+					return nil
+				}
+
+				pageSize, err := parseSize(maxSize)
+				if err != nil {
+					return err
+				}
+				algorithm, err := findPackAlgorithm(algorithmName)
+				if err != nil {
+					return err
+				}
+				layout, err := packSprites(sprites, algorithm, PackOptions{MaxSize: pageSize, Padding: padding})
+				if err != nil {
+					return err
+				}
+				if _, err := writePackedAtlas(layout, repackPath); err != nil {
+					return err
+				}
+				logf("[info] repacked %d frames at %gx onto %d pages\n", len(sprites), factor, len(layout.Pages))
+				logf("[info] wrote %s\n", repackPath)
+				return nil
+			}
+
+			if err := os.MkdirAll(outputDir, 0o755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+
+			for _, tiled := range tiles.Images {
+				img, factor, err := reassemble(tiled, dir, tiles.Overlap)
+				if err != nil {
+					return err
+				}
+
+				outputPath := filepath.Join(outputDir, filepath.FromSlash(tiled.Output))
+				encoder, err := encoderForPath(outputPath)
+				if err != nil {
+					return err
+				}
+				unpacker := Unpacker{OutputDir: outputDir, Encoder: encoder}
+				if _, err := unpacker.writeSprite(outputPath, img); err != nil {
+					return err
+				}
+				logf("[info] wrote %s at %gx from %d tiles\n", outputPath, factor, len(tiled.Tiles))
+			}
+
+			return nil
+		},
+	}
+
+	reassembleCmd.Flags().StringVarP(&outputDir, "output", "o", "", "Output directory, the manifest's directory by default")
+	reassembleCmd.Flags().StringVarP(&repackPath, "repack", "", "", "Pack processed frames into this atlas instead of rebuilding the frame tree")
+	reassembleCmd.Flags().StringVarP(&maxSize, "max-size", "", maxSize, "Maximum page size as WxH when repacking")
+	reassembleCmd.Flags().IntVarP(&padding, "padding", "p", padding, "Pixels between repacked sprites")
+	reassembleCmd.Flags().StringVarP(&algorithmName, "algorithm", "a", algorithmName, "Packing heuristic to repack with")
+
+	return reassembleCmd
+}
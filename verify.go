@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// deriveHashes re-extracts the frames in memory and hashes them as they
+// would be encoded, keyed by frame name. Only frames in names are derived,
+// and each sheet is decoded once. Attributions the manifest records are
+// stamped in as the extraction did.
+func (unpacker Unpacker) deriveHashes(names map[string]bool, manifest *Manifest) (map[string]string, error) {
+	hashes := make(map[string]string)
+
+	for _, sheet := range unpacker.Sheets {
+		if !slices.ContainsFunc(sheet.Textures, func(tex Texture) bool { return names[tex.FileName] }) {
+			continue
+		}
+
+		img, err := decodeSheet(unpacker.InputDir, sheet)
+		if err != nil {
+			return nil, err
+		}
+		unpacker.profile, err = readColorProfile(filepath.Join(unpacker.InputDir, sheet.Image))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read color profile of %s: %w", sheet.Image, err)
+		}
+		factor := unpacker.spriteScale(sheet)
+
+		for _, tex := range sheet.Textures {
+			if !names[tex.FileName] {
+				continue
+			}
+			sprite := extractTexture(tex, img)
+			if factor != 1 {
+				sprite = scaleSprite(sprite, factor, unpacker.Filter)
+			}
+
+			stamped := unpacker
+			if manifest != nil {
+				stamped.profile = unpacker.profile.stamped(manifest.Frames[tex.FileName].Attribution)
+			}
+			hash := sha256.New()
+			if err := stamped.encodeSprite(hash, sprite); err != nil {
+				return nil, err
+			}
+			hashes[tex.FileName] = hex.EncodeToString(hash.Sum(nil))
+		}
+	}
+
+	return hashes, nil
+}
+
+// verifyExtraction compares the file hashes of an extraction with sprites
+// derived from its atlas. Every file is read, and a manifest hash that no
+// longer matches its file is reported as unrecorded.
+func (unpacker Unpacker) verifyExtraction() ([]FileCheck, error) {
+	dir := unpacker.OutputDir
+	paths, listed, err := expectedPaths(unpacker)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]Texture)
+	for _, sh := range unpacker.Sheets {
+		for _, tex := range sh.Textures {
+			known[tex.FileName] = tex
+		}
+	}
+
+	derive := make(map[string]bool)
+	for _, name := range paths {
+		_, derive[name] = known[name]
+	}
+	derived, err := unpacker.deriveHashes(derive, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []FileCheck
+	for _, outputPath := range slices.Sorted(maps.Keys(paths)) {
+		name := paths[outputPath]
+		check := FileCheck{Path: outputPath, Frame: name, Status: "ok"}
+
+		if _, ok := known[name]; !ok {
+			check.Status = "extra"
+			checks = append(checks, check)
+			continue
+		}
+		if _, err := os.Stat(outputPath); err != nil {
+			check.Status = "missing"
+			checks = append(checks, check)
+			continue
+		}
+
+		actual, err := hashFile(outputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", outputPath, err)
+		}
+		var recorded string
+		if manifest != nil {
+			recorded = manifest.Frames[name].SHA256
+		}
+		switch {
+		case actual != derived[name]:
+			check.Status = "changed"
+		case recorded != "" && recorded != actual:
+			check.Status = "unrecorded"
+		}
+		checks = append(checks, check)
+	}
+
+	for _, name := range frameNames(unpacker.Pack) {
+		if !listed[name] {
+			checks = append(checks, FileCheck{Path: unpacker.outputPath(known[name]), Frame: name, Status: "missing"})
+		}
+	}
+
+	extra, err := extraFiles(dir, paths)
+	if err != nil {
+		return nil, err
+	}
+	return append(checks, extra...), nil
+}
+
+func newVerifyCmd() *cobra.Command {
+	var outputDir string
+	var encoding = SpriteEncoding{Format: spriteFormats[0], Speed: 6, PNGCompression: "default"}
+	var asJSON bool = false
+
+	var verifyCmd = &cobra.Command{
+		Use:   "verify <atlas>",
+		Short: "Verify an earlier extraction by hash against sprites derived in memory, writing nothing",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var path = args[0]
+			format, _ := cmd.Flags().GetString("format")
+
+			pack, err := loadPack(path, format)
+			if err != nil {
+				return err
+			}
+
+			encoder, err := newSpriteEncoder(encoding)
+			if err != nil {
+				return err
+			}
+
+			unpacker := Unpacker{Pack: pack, InputDir: filepath.Dir(path), OutputDir: filepath.Clean(outputDir), Encoder: encoder}
+			checks, err := unpacker.verifyExtraction()
+			if err != nil {
+				return err
+			}
+
+			counts := make(map[string]int)
+			for _, check := range checks {
+				counts[check.Status]++
+			}
+
+			if asJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(checks); err != nil {
+					return err
+				}
+			} else {
+				if counts["ok"] < len(checks) {
+					writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					fmt.Fprintln(writer, "STATUS\tFRAME\tPATH")
+					for _, check := range checks {
+						if check.Status != "ok" {
+							fmt.Fprintf(writer, "%s\t%s\t%s\n", check.Status, check.Frame, check.Path)
+						}
+					}
+					writer.Flush()
+				}
+				logf("[info] %d ok, %d missing, %d changed, %d unrecorded, %d extra\n",
+					counts["ok"], counts["missing"], counts["changed"], counts["unrecorded"], counts["extra"])
+			}
+
+			if problems := len(checks) - counts["ok"]; problems > 0 {
+				return fmt.Errorf("%s does not match %s", outputDir, path)
+			}
+			return nil
+		},
+	}
+
+	verifyCmd.Flags().StringVarP(&outputDir, "output", "o", "", "Extracted directory to verify")
+	verifyCmd.Flags().StringVarP(&encoding.Format, "output-format", "", encoding.Format, "Sprite image format the extraction used: "+strings.Join(spriteFormats, ", "))
+	verifyCmd.Flags().IntVarP(&encoding.Quality, "quality", "", encoding.Quality, "Lossy quality the extraction used, 0 for the format default")
+	verifyCmd.Flags().StringVarP(&encoding.PNGCompression, "png-compression", "", encoding.PNGCompression, "PNG compression the extraction used: default, none, fast, or best")
+	verifyCmd.Flags().BoolVarP(&asJSON, "json", "", asJSON, "Print every file's status as JSON")
+	verifyCmd.MarkFlagRequired("output")
+
+	return verifyCmd
+}
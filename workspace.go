@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// WorkspaceLimits place every temporary workspace under one root and cap
+// how much each may hold, so a large repack cannot quietly fill the disk.
+type WorkspaceLimits struct {
+	Root     string
+	MaxBytes int64
+}
+
+var workspaceLimits = WorkspaceLimits{Root: filepath.Join(os.TempDir(), "txunpak"), MaxBytes: 4 << 30}
+
+// ownerFile records the pid of the run using a workspace, so a later run can
+// tell a crashed run's leftovers from a workspace still in use.
+const ownerFile = ".owner"
+
+// A Workspace is a directory of intermediate files private to one run.
+// Parallel runs each get their own, and Close removes it.
+type Workspace struct {
+	Dir      string
+	MaxBytes int64
+}
+
+// openWorkspace sweeps workspaces left by crashed runs, then creates a new
+// one named after its purpose.
+func openWorkspace(purpose string) (*Workspace, error) {
+	if err := os.MkdirAll(workspaceLimits.Root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create workspace root: %w", err)
+	}
+	if swept, err := sweepWorkspaces(workspaceLimits.Root); err != nil {
+		logf("[warn] failed to sweep stale workspaces: %v\n", err)
+	} else if swept > 0 {
+		logf("[info] removed %d workspaces left by crashed runs\n", swept)
+	}
+
+	dir, err := os.MkdirTemp(workspaceLimits.Root, purpose+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ownerFile), []byte(strconv.Itoa(os.Getpid())), 0o644); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	return &Workspace{Dir: dir, MaxBytes: workspaceLimits.MaxBytes}, nil
+}
+
+func (ws *Workspace) path(name string) string {
+	return filepath.Join(ws.Dir, name)
+}
+
+// checkSize fails once the workspace holds more than its cap. Steps call it
+// between stages rather than per file, so the cap may be overshot by one stage.
+func (ws *Workspace) checkSize() error {
+	var total int64
+	err := filepath.WalkDir(ws.Dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to measure workspace: %w", err)
+	}
+	if total > ws.MaxBytes {
+		return fmt.Errorf("workspace %s holds %s, over the %s limit, raise --temp-limit to allow it", ws.Dir, formatBytes(total), formatBytes(ws.MaxBytes))
+	}
+	return nil
+}
+
+// keep leaves the workspace for inspection. Without its owner file the
+// sweep no longer considers it.
+func (ws *Workspace) keep() {
+	os.Remove(filepath.Join(ws.Dir, ownerFile))
+}
+
+func (ws *Workspace) Close() error {
+	if _, err := os.Stat(filepath.Join(ws.Dir, ownerFile)); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.RemoveAll(ws.Dir); err != nil {
+		return fmt.Errorf("failed to remove workspace: %w", err)
+	}
+	return nil
+}
+
+// sweepWorkspaces removes the workspaces under root whose owning process
+// is gone. Workspaces of live runs and kept ones are left alone.
+func sweepWorkspaces(root string) (int, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return 0, err
+	}
+
+	swept := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, ownerFile))
+		if err != nil {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil || processAlive(pid) {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return swept, err
+		}
+		swept++
+	}
+	return swept, nil
+}
+
+// processAlive reports whether pid is running. When that cannot be told,
+// the process is assumed alive so a live run's workspace is never removed.
+func processAlive(pid int) bool {
+	if pid == os.Getpid() {
+		return true
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return !errors.Is(err, os.ErrProcessDone) && !errors.Is(err, syscall.ESRCH)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+type xmlSubTexture struct {
+	Name        string   `xml:"name,attr"`
+	X           int      `xml:"x,attr"`
+	Y           int      `xml:"y,attr"`
+	Width       int      `xml:"width,attr"`
+	Height      int      `xml:"height,attr"`
+	FrameX      *int     `xml:"frameX,attr"`
+	FrameY      *int     `xml:"frameY,attr"`
+	FrameWidth  *int     `xml:"frameWidth,attr"`
+	FrameHeight *int     `xml:"frameHeight,attr"`
+	Rotated     bool     `xml:"rotated,attr"`
+	PivotX      *float64 `xml:"pivotX,attr"`
+	PivotY      *float64 `xml:"pivotY,attr"`
+}
+
+type xmlTextureAtlas struct {
+	XMLName     xml.Name        `xml:"TextureAtlas"`
+	ImagePath   string          `xml:"imagePath,attr"`
+	Width       int             `xml:"width,attr"`
+	Height      int             `xml:"height,attr"`
+	Scale       float64         `xml:"scale,attr"`
+	SubTextures []xmlSubTexture `xml:"SubTexture"`
+}
+
+func (sub xmlSubTexture) texture() Texture {
+	// Starling stores rotated regions with their atlas dimensions, while
+	// Texture.Frame follows TexturePacker and holds the unrotated size.
+	frame := Frame{X: sub.X, Y: sub.Y, Width: sub.Width, Height: sub.Height}
+	if sub.Rotated {
+		frame.Width, frame.Height = frame.Height, frame.Width
+	}
+
+	texture := Texture{
+		FileName:         sub.Name,
+		Frame:            frame,
+		Rotated:          sub.Rotated,
+		SourceSize:       Size{Width: frame.Width, Height: frame.Height},
+		SpriteSourceSize: Frame{Width: frame.Width, Height: frame.Height},
+	}
+
+	if sub.FrameX != nil && sub.FrameY != nil {
+		texture.SpriteSourceSize.X = -*sub.FrameX
+		texture.SpriteSourceSize.Y = -*sub.FrameY
+	}
+	if sub.FrameWidth != nil && sub.FrameHeight != nil {
+		texture.SourceSize = Size{Width: *sub.FrameWidth, Height: *sub.FrameHeight}
+	}
+
+	// Starling pivots are in pixels of the untrimmed frame.
+	if sub.PivotX != nil && sub.PivotY != nil && texture.SourceSize.Width > 0 && texture.SourceSize.Height > 0 {
+		texture.Pivot = &Pivot{
+			X: *sub.PivotX / float64(texture.SourceSize.Width),
+			Y: *sub.PivotY / float64(texture.SourceSize.Height),
+		}
+	}
+
+	texture.Trimmed = texture.SourceSize.Width != frame.Width ||
+		texture.SourceSize.Height != frame.Height ||
+		texture.SpriteSourceSize.X != 0 ||
+		texture.SpriteSourceSize.Y != 0
+
+	return texture
+}
+
+func parseXMLPack(data []byte) (Pack, error) {
+	var atlas xmlTextureAtlas
+	if err := xml.Unmarshal(data, &atlas); err != nil {
+		return Pack{}, fmt.Errorf("invalid XML: %w", err)
+	}
+
+	if atlas.ImagePath == "" {
+		return Pack{}, fmt.Errorf("invalid XML: TextureAtlas is missing imagePath")
+	}
+
+	sheet := Sheet{
+		Image:    atlas.ImagePath,
+		Scale:    atlas.Scale,
+		Size:     Size{Width: atlas.Width, Height: atlas.Height},
+		Textures: make([]Texture, 0, len(atlas.SubTextures)),
+	}
+	if sheet.Scale == 0 {
+		sheet.Scale = 1
+	}
+
+	for _, sub := range atlas.SubTextures {
+		sheet.Textures = append(sheet.Textures, sub.texture())
+	}
+
+	return Pack{Sheets: []Sheet{sheet}}, nil
+}
+
+func encodeXMLPack(pack Pack) ([]byte, error) {
+	sheet := pack.Sheets[0]
+	atlas := xmlTextureAtlas{
+		ImagePath:   sheet.Image,
+		Width:       sheet.Size.Width,
+		Height:      sheet.Size.Height,
+		Scale:       sheet.Scale,
+		SubTextures: make([]xmlSubTexture, 0, len(sheet.Textures)),
+	}
+
+	for _, tex := range sheet.Textures {
+		sub := xmlSubTexture{
+			Name:    tex.FileName,
+			X:       tex.Frame.X,
+			Y:       tex.Frame.Y,
+			Width:   tex.Frame.Width,
+			Height:  tex.Frame.Height,
+			Rotated: tex.Rotated,
+		}
+		if tex.Rotated {
+			sub.Width, sub.Height = sub.Height, sub.Width
+		}
+		if tex.Trimmed {
+			frameX, frameY := -tex.SpriteSourceSize.X, -tex.SpriteSourceSize.Y
+			sub.FrameX, sub.FrameY = &frameX, &frameY
+			sub.FrameWidth, sub.FrameHeight = &tex.SourceSize.Width, &tex.SourceSize.Height
+		}
+		if tex.Pivot != nil {
+			pivotX, pivotY := tex.Pivot.X*float64(tex.SourceSize.Width), tex.Pivot.Y*float64(tex.SourceSize.Height)
+			sub.PivotX, sub.PivotY = &pivotX, &pivotY
+		}
+		atlas.SubTextures = append(atlas.SubTextures, sub)
+	}
+
+	data, err := xml.MarshalIndent(atlas, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode XML: %w", err)
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}